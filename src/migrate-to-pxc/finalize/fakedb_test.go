@@ -0,0 +1,130 @@
+package finalize_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDB answers queries from canned results, keyed by the query and its
+// arguments, so that verifiers can be tested without a server.
+type fakeDB struct {
+	mutex   sync.Mutex
+	results map[string]fakeResult
+}
+
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+}
+
+var (
+	fakeDBs      = map[string]*fakeDB{}
+	fakeDBsLock  sync.Mutex
+	registerFake sync.Once
+)
+
+// newFakeDB returns a fake and a *sql.DB that queries it.
+func newFakeDB() (*fakeDB, *sql.DB) {
+	registerFake.Do(func() { sql.Register("finalize-fake", fakeDriver{}) })
+
+	fake := &fakeDB{results: map[string]fakeResult{}}
+	fakeDBsLock.Lock()
+	name := fmt.Sprintf("fake-%d", len(fakeDBs))
+	fakeDBs[name] = fake
+	fakeDBsLock.Unlock()
+
+	db, err := sql.Open("finalize-fake", name)
+	if err != nil {
+		panic(err)
+	}
+	return fake, db
+}
+
+// Returns makes query with args return rows of columns. A nil value is
+// NULL.
+func (f *fakeDB) Returns(query string, args []interface{}, columns []string, rows ...[]driver.Value) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.results[fakeKey(query, args)] = fakeResult{columns: columns, rows: rows}
+}
+
+// Fails makes query with args return err.
+func (f *fakeDB) Fails(query string, args []interface{}, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.results[fakeKey(query, args)] = fakeResult{err: err}
+}
+
+func (f *fakeDB) query(query string, args []driver.Value) (driver.Rows, error) {
+	var converted []interface{}
+	for _, arg := range args {
+		converted = append(converted, arg)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	result, ok := f.results[fakeKey(query, converted)]
+	if !ok {
+		return nil, fmt.Errorf("unexpected query %q with %v", query, converted)
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	return &fakeRows{columns: result.columns, rows: result.rows}, nil
+}
+
+func fakeKey(query string, args []interface{}) string {
+	return strings.Join(strings.Fields(query), " ") + fmt.Sprint(args)
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsLock.Lock()
+	defer fakeDBsLock.Unlock()
+	return fakeConn{fakeDBs[name]}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{db: c.db, query: query}, nil
+}
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions are not supported") }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("statements are not supported")
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.db.query(s.query, args)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
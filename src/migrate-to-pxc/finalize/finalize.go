@@ -0,0 +1,81 @@
+package finalize
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"migrate-to-pxc/rollback"
+)
+
+const finalizedPrefix = "finalized at "
+
+//go:generate counterfeiter . ClusterStatus
+type ClusterStatus interface {
+	Healthy() error
+}
+
+//go:generate counterfeiter . ChecksumVerifier
+type ChecksumVerifier interface {
+	Verify() error
+}
+
+// Finalize deletes the cf-mysql-release datadir that the migration retained
+// as a backup, once the PXC cluster is healthy and, when a verifier is given,
+// its data still matches the backup. The finalization is recorded in the
+// migration marker so that it is clear why the backup is gone.
+func Finalize(storeDir string, cluster ClusterStatus, verifier ChecksumVerifier, now func() time.Time) error {
+	backupDir := filepath.Join(storeDir, rollback.BackupDirName)
+	marker := filepath.Join(storeDir, rollback.MigrationMarkerName)
+
+	markerContents, err := ioutil.ReadFile(marker)
+	if os.IsNotExist(err) {
+		return errors.New("Cannot finalize, no successful migration recorded at " + marker)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+		if strings.Contains(string(markerContents), finalizedPrefix) {
+			fmt.Println("migration already finalized")
+			return nil
+		}
+		return fmt.Errorf("Cannot finalize, no migration backup found at %s", backupDir)
+	}
+
+	fmt.Println("checking pxc cluster health...")
+	if err := cluster.Healthy(); err != nil {
+		return fmt.Errorf("Cannot finalize, pxc cluster is not healthy: %s", err)
+	}
+
+	if verifier != nil {
+		fmt.Println("verifying table checksums against the migration backup...")
+		if err := verifier.Verify(); err != nil {
+			return fmt.Errorf("Cannot finalize, checksum verification failed: %s", err)
+		}
+	}
+
+	fmt.Printf("removing migration backup %s...\n", backupDir)
+	if err := os.RemoveAll(backupDir); err != nil {
+		return fmt.Errorf("failed to remove migration backup: %s", err)
+	}
+
+	f, err := os.OpenFile(marker, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	separator := ""
+	if len(markerContents) > 0 && !strings.HasSuffix(string(markerContents), "\n") {
+		separator = "\n"
+	}
+
+	_, err = fmt.Fprintf(f, "%s%s%s; removed %s\n", separator, finalizedPrefix, now().UTC().Format(time.RFC3339), backupDir)
+	return err
+}
@@ -0,0 +1,103 @@
+package finalize_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"migrate-to-pxc/finalize"
+	"migrate-to-pxc/finalize/finalizefakes"
+)
+
+var _ = Describe("Finalize", func() {
+	var (
+		storeDir     string
+		backupDir    string
+		marker       string
+		fakeCluster  *finalizefakes.FakeClusterStatus
+		fakeVerifier *finalizefakes.FakeChecksumVerifier
+		now          func() time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		storeDir, err = ioutil.TempDir("", "finalize")
+		Expect(err).NotTo(HaveOccurred())
+
+		backupDir = filepath.Join(storeDir, "mysql-migration-backup")
+		marker = filepath.Join(storeDir, "migrated-successfully")
+
+		Expect(os.MkdirAll(filepath.Join(backupDir, "mysql"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(marker, []byte("DO NOT DELETE THIS FILE; YOU WILL LOSE DATA\n"), 0644)).To(Succeed())
+
+		fakeCluster = &finalizefakes.FakeClusterStatus{}
+		fakeVerifier = &finalizefakes.FakeChecksumVerifier{}
+		now = func() time.Time { return time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC) }
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(storeDir)).To(Succeed())
+	})
+
+	It("deletes the backup and records the finalization in the marker", func() {
+		Expect(finalize.Finalize(storeDir, fakeCluster, fakeVerifier, now)).To(Succeed())
+
+		Expect(fakeCluster.HealthyCallCount()).To(Equal(1))
+		Expect(fakeVerifier.VerifyCallCount()).To(Equal(1))
+		Expect(backupDir).NotTo(BeAnExistingFile())
+
+		contents, err := ioutil.ReadFile(marker)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("DO NOT DELETE THIS FILE; YOU WILL LOSE DATA\n" +
+			"finalized at 2019-01-02T03:04:05Z; removed " + backupDir + "\n"))
+	})
+
+	It("skips checksum verification when no verifier is given", func() {
+		Expect(finalize.Finalize(storeDir, fakeCluster, nil, now)).To(Succeed())
+		Expect(backupDir).NotTo(BeAnExistingFile())
+	})
+
+	It("is a no-op when the migration was already finalized", func() {
+		Expect(finalize.Finalize(storeDir, fakeCluster, nil, now)).To(Succeed())
+		Expect(finalize.Finalize(storeDir, fakeCluster, nil, now)).To(Succeed())
+
+		Expect(fakeCluster.HealthyCallCount()).To(Equal(1))
+	})
+
+	It("keeps the backup when the cluster is not healthy", func() {
+		fakeCluster.HealthyReturns(errors.New("wsrep_local_state_comment is Donor/Desynced"))
+
+		err := finalize.Finalize(storeDir, fakeCluster, fakeVerifier, now)
+		Expect(err).To(MatchError(ContainSubstring("pxc cluster is not healthy: wsrep_local_state_comment is Donor/Desynced")))
+		Expect(fakeVerifier.VerifyCallCount()).To(Equal(0))
+		Expect(backupDir).To(BeADirectory())
+	})
+
+	It("keeps the backup when the checksums do not match", func() {
+		fakeVerifier.VerifyReturns(errors.New("checksum mismatch for tables: `db`.`t`"))
+
+		err := finalize.Finalize(storeDir, fakeCluster, fakeVerifier, now)
+		Expect(err).To(MatchError(ContainSubstring("checksum verification failed")))
+		Expect(backupDir).To(BeADirectory())
+	})
+
+	It("returns an error when no migration was recorded", func() {
+		Expect(os.Remove(marker)).To(Succeed())
+
+		err := finalize.Finalize(storeDir, fakeCluster, fakeVerifier, now)
+		Expect(err).To(MatchError(ContainSubstring("no successful migration recorded")))
+		Expect(backupDir).To(BeADirectory())
+	})
+
+	It("returns an error when the backup is missing and the migration was not finalized", func() {
+		Expect(os.RemoveAll(backupDir)).To(Succeed())
+
+		err := finalize.Finalize(storeDir, fakeCluster, fakeVerifier, now)
+		Expect(err).To(MatchError(ContainSubstring("no migration backup found")))
+	})
+})
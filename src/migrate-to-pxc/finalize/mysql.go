@@ -0,0 +1,186 @@
+package finalize
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GaleraClusterStatus reports the PXC cluster healthy when the local node is
+// synced with a primary component.
+type GaleraClusterStatus struct {
+	DB *sql.DB
+}
+
+func (s GaleraClusterStatus) Healthy() error {
+	rows, err := s.DB.Query("SHOW GLOBAL STATUS LIKE 'wsrep_%'")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	status := map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		status[strings.ToLower(name)] = value
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	expected := []struct{ name, value string }{
+		{"wsrep_ready", "ON"},
+		{"wsrep_cluster_status", "Primary"},
+		{"wsrep_local_state_comment", "Synced"},
+	}
+	for _, e := range expected {
+		if status[e.name] != e.value {
+			return fmt.Errorf("expected %s to be %s, got %q", e.name, e.value, status[e.name])
+		}
+	}
+
+	return nil
+}
+
+// TableChecksumVerifier compares the row count and a hash of the rows of
+// every user table in the backup server with the same table in the PXC
+// server. CHECKSUM TABLE is not used because it depends on how each server
+// stores rows, so it differs between MariaDB and Percona Server for
+// identical data.
+type TableChecksumVerifier struct {
+	Backup *sql.DB
+	Pxc    *sql.DB
+}
+
+// tableChecksum is the row count of a table and the sum of a hash of each of
+// its rows, which does not depend on the order rows are read in.
+type tableChecksum struct {
+	Rows int64
+	Sum  string
+}
+
+func (v TableChecksumVerifier) Verify() error {
+	backupChecksums, err := tableChecksums(v.Backup)
+	if err != nil {
+		return fmt.Errorf("checksumming backup: %s", err)
+	}
+
+	pxcChecksums, err := tableChecksums(v.Pxc)
+	if err != nil {
+		return fmt.Errorf("checksumming pxc: %s", err)
+	}
+
+	var mismatches []string
+	for table, checksum := range backupChecksums {
+		pxcChecksum, found := pxcChecksums[table]
+		switch {
+		case !found:
+			mismatches = append(mismatches, table+" (missing in pxc)")
+		case pxcChecksum.Rows != checksum.Rows:
+			mismatches = append(mismatches, fmt.Sprintf("%s (%d rows in backup, %d in pxc)", table, checksum.Rows, pxcChecksum.Rows))
+		case pxcChecksum.Sum != checksum.Sum:
+			mismatches = append(mismatches, table)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("checksum mismatch for tables: %s", strings.Join(mismatches, ", "))
+	}
+
+	return nil
+}
+
+func tableChecksums(db *sql.DB) (map[string]tableChecksum, error) {
+	rows, err := db.Query(`SELECT table_schema, table_name FROM information_schema.tables
+		WHERE table_type = 'BASE TABLE'
+		AND table_schema NOT IN ('performance_schema', 'mysql', 'information_schema', 'sys')`)
+	if err != nil {
+		return nil, err
+	}
+
+	type table struct{ schema, name string }
+	var tables []table
+	for rows.Next() {
+		var t table
+		if err := rows.Scan(&t.schema, &t.name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	checksums := map[string]tableChecksum{}
+	for _, t := range tables {
+		name := fmt.Sprintf("`%s`.`%s`", escapeIdentifier(t.schema), escapeIdentifier(t.name))
+
+		query, err := checksumQuery(db, t.schema, t.name)
+		if err != nil {
+			return nil, err
+		}
+
+		var checksum tableChecksum
+		if err := db.QueryRow(query).Scan(&checksum.Rows, &checksum.Sum); err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		checksums[name] = checksum
+	}
+
+	return checksums, nil
+}
+
+// checksumQuery returns a query for the row count and row hash sum of a
+// table. Each row is hashed from the MD5 of each of its columns, so that
+// values cannot run together and NULL differs from any value. Columns whose
+// text form depends on the server or session are normalized first.
+func checksumQuery(db *sql.DB, schema, table string) (string, error) {
+	rows, err := db.Query(`SELECT column_name, data_type FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ?
+		ORDER BY ordinal_position`, schema, table)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return "", err
+		}
+		columns = append(columns, fmt.Sprintf("COALESCE(MD5(%s), 'NULL')", normalizedColumn(name, dataType)))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	rowHash := fmt.Sprintf("MD5(CONCAT_WS('#', %s))", strings.Join(columns, ", "))
+	return fmt.Sprintf("SELECT COUNT(*), COALESCE(SUM(CAST(CONV(SUBSTRING(%s, 1, 15), 16, 10) AS UNSIGNED)), 0) FROM `%s`.`%s`",
+		rowHash, escapeIdentifier(schema), escapeIdentifier(table)), nil
+}
+
+// normalizedColumn returns an expression for the column that both servers
+// render the same way: timestamps independently of the session time zone,
+// and floating point values with a fixed number of digits.
+func normalizedColumn(name, dataType string) string {
+	column := "`" + escapeIdentifier(name) + "`"
+	switch strings.ToLower(dataType) {
+	case "timestamp":
+		return fmt.Sprintf("UNIX_TIMESTAMP(%s)", column)
+	case "float", "double":
+		return fmt.Sprintf("CAST(%s AS DECIMAL(65, 30))", column)
+	}
+	return column
+}
+
+func escapeIdentifier(identifier string) string {
+	return strings.Replace(identifier, "`", "``", -1)
+}
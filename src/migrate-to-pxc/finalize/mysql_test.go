@@ -0,0 +1,103 @@
+package finalize_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"migrate-to-pxc/finalize"
+)
+
+const (
+	tablesQuery = `SELECT table_schema, table_name FROM information_schema.tables
+		WHERE table_type = 'BASE TABLE'
+		AND table_schema NOT IN ('performance_schema', 'mysql', 'information_schema', 'sys')`
+	columnsQuery = `SELECT column_name, data_type FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ?
+		ORDER BY ordinal_position`
+	idChecksumQuery = "SELECT COUNT(*), COALESCE(SUM(CAST(CONV(SUBSTRING(MD5(CONCAT_WS('#', COALESCE(MD5(`id`), 'NULL'))), 1, 15), 16, 10) AS UNSIGNED)), 0) FROM `db`.`t`"
+)
+
+var _ = Describe("TableChecksumVerifier", func() {
+	var (
+		backup, pxc     *fakeDB
+		backupDB, pxcDB *sql.DB
+		verifier        finalize.TableChecksumVerifier
+	)
+
+	BeforeEach(func() {
+		backup, backupDB = newFakeDB()
+		pxc, pxcDB = newFakeDB()
+		verifier = finalize.TableChecksumVerifier{Backup: backupDB, Pxc: pxcDB}
+	})
+
+	AfterEach(func() {
+		Expect(backupDB.Close()).To(Succeed())
+		Expect(pxcDB.Close()).To(Succeed())
+	})
+
+	hasTable := func(fake *fakeDB, rows int64, sum string) {
+		fake.Returns(tablesQuery, nil, []string{"table_schema", "table_name"}, []driver.Value{"db", "t"})
+		fake.Returns(columnsQuery, []interface{}{"db", "t"}, []string{"column_name", "data_type"}, []driver.Value{"id", "int"})
+		fake.Returns(idChecksumQuery, nil, []string{"COUNT(*)", "SUM"}, []driver.Value{rows, sum})
+	}
+
+	It("succeeds when every table has the same rows", func() {
+		hasTable(backup, 3, "1729382256910270464")
+		hasTable(pxc, 3, "1729382256910270464")
+
+		Expect(verifier.Verify()).To(Succeed())
+	})
+
+	It("reports tables whose row counts differ", func() {
+		hasTable(backup, 3, "1729382256910270464")
+		hasTable(pxc, 2, "1152921504606846976")
+
+		Expect(verifier.Verify()).To(MatchError("checksum mismatch for tables: `db`.`t` (3 rows in backup, 2 in pxc)"))
+	})
+
+	It("reports tables whose rows differ", func() {
+		hasTable(backup, 3, "1729382256910270464")
+		hasTable(pxc, 3, "1729382256910270465")
+
+		Expect(verifier.Verify()).To(MatchError("checksum mismatch for tables: `db`.`t`"))
+	})
+
+	It("reports tables missing in pxc", func() {
+		hasTable(backup, 3, "1729382256910270464")
+		pxc.Returns(tablesQuery, nil, []string{"table_schema", "table_name"})
+
+		Expect(verifier.Verify()).To(MatchError("checksum mismatch for tables: `db`.`t` (missing in pxc)"))
+	})
+
+	It("hashes timestamps and floating point values the same way on both servers", func() {
+		for _, fake := range []*fakeDB{backup, pxc} {
+			fake.Returns(tablesQuery, nil, []string{"table_schema", "table_name"}, []driver.Value{"db", "t`s"})
+			fake.Returns(columnsQuery, []interface{}{"db", "t`s"}, []string{"column_name", "data_type"},
+				[]driver.Value{"id", "int"},
+				[]driver.Value{"created_at", "timestamp"},
+				[]driver.Value{"price", "double"},
+				[]driver.Value{"ratio", "FLOAT"},
+			)
+			fake.Returns("SELECT COUNT(*), COALESCE(SUM(CAST(CONV(SUBSTRING(MD5(CONCAT_WS('#', "+
+				"COALESCE(MD5(`id`), 'NULL'), "+
+				"COALESCE(MD5(UNIX_TIMESTAMP(`created_at`)), 'NULL'), "+
+				"COALESCE(MD5(CAST(`price` AS DECIMAL(65, 30))), 'NULL'), "+
+				"COALESCE(MD5(CAST(`ratio` AS DECIMAL(65, 30))), 'NULL'))), 1, 15), 16, 10) AS UNSIGNED)), 0) FROM `db`.`t``s`",
+				nil, []string{"COUNT(*)", "SUM"}, []driver.Value{int64(1), "42"})
+		}
+
+		Expect(verifier.Verify()).To(Succeed())
+	})
+
+	It("returns an error naming the table when it cannot be read", func() {
+		backup.Returns(tablesQuery, nil, []string{"table_schema", "table_name"}, []driver.Value{"db", "t"})
+		backup.Returns(columnsQuery, []interface{}{"db", "t"}, []string{"column_name", "data_type"}, []driver.Value{"id", "int"})
+		backup.Fails(idChecksumQuery, nil, errors.New("table is marked as crashed"))
+
+		Expect(verifier.Verify()).To(MatchError("checksumming backup: `db`.`t`: table is marked as crashed"))
+	})
+})
@@ -2,9 +2,11 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"os/exec"
 	"time"
@@ -12,13 +14,108 @@ import (
 	"github.com/cloudfoundry/gosigar"
 	_ "github.com/go-sql-driver/mysql"
 	"migrate-to-pxc/disk"
+	"migrate-to-pxc/finalize"
+	"migrate-to-pxc/rollback"
 )
 
+const storeDir = "/var/vcap/store"
+
 var (
 	err error
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1])
+		return
+	}
+
+	migrate()
+}
+
+func runCommand(command string) {
+	switch command {
+	case "rollback":
+		fmt.Println("rolling back migration...")
+		if err := rollback.Rollback(storeDir, rollback.DefaultPxc); err != nil {
+			panic(err)
+		}
+		fmt.Println("rollback complete; cf-mysql-release may now be started with pxc_enabled: false")
+	case "finalize":
+		finalizeMigration(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q. Usage: migrate-to-pxc [rollback|finalize [-verify-checksums]]\n", command)
+		os.Exit(1)
+	}
+}
+
+func finalizeMigration(args []string) {
+	flags := flag.NewFlagSet("finalize", flag.ExitOnError)
+	verifyChecksums := flags.Bool("verify-checksums", false,
+		"Compare table checksums between the migration backup and pxc before deleting the backup")
+	flags.Parse(args)
+
+	mysqlAdminUsername := os.Getenv("MYSQL_USERNAME")
+	mysqlAdminPassword := os.Getenv("MYSQL_PASSWORD")
+
+	pxcConnectionString := fmt.Sprintf("%s:%s@unix(%s)/", mysqlAdminUsername, mysqlAdminPassword, "/var/vcap/sys/run/pxc-mysql/mysqld.sock")
+	pxcDatabaseConnection, err := sql.Open("mysql", pxcConnectionString)
+	if err != nil {
+		panic(err)
+	}
+	defer pxcDatabaseConnection.Close()
+
+	var verifier finalize.ChecksumVerifier
+	if *verifyChecksums {
+		verifier = backupChecksumVerifier{
+			mysqlAdminUsername: mysqlAdminUsername,
+			mysqlAdminPassword: mysqlAdminPassword,
+			pxc:                pxcDatabaseConnection,
+		}
+	}
+
+	fmt.Println("finalizing migration...")
+	err = finalize.Finalize(storeDir, finalize.GaleraClusterStatus{DB: pxcDatabaseConnection}, verifier, time.Now)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("finalize complete")
+}
+
+// backupChecksumVerifier runs MariaDB against the migration backup for the
+// duration of the verification, so the backup is not in use when it is
+// deleted.
+type backupChecksumVerifier struct {
+	mysqlAdminUsername string
+	mysqlAdminPassword string
+	pxc                *sql.DB
+}
+
+func (v backupChecksumVerifier) Verify() error {
+	fmt.Println("starting mariadb against the migration backup...")
+	err := startMariaDB("--datadir=" + filepath.Join(storeDir, rollback.BackupDirName))
+	if err != nil {
+		return err
+	}
+	defer shutdownMariaDB()
+
+	mariadbDatabaseConnection, err := connectToMariaDB(v.mysqlAdminUsername, v.mysqlAdminPassword)
+	if err != nil {
+		return err
+	}
+	defer mariadbDatabaseConnection.Close()
+
+	if _, err := listDBs(mariadbDatabaseConnection); err != nil {
+		return err
+	}
+
+	return finalize.TableChecksumVerifier{
+		Backup: mariadbDatabaseConnection,
+		Pxc:    v.pxc,
+	}.Verify()
+}
+
+func migrate() {
 	// Create a Sigar to gather system info
 	concreteSigar := sigar.ConcreteSigar{}
 	err = disk.RoomToMigrate(&concreteSigar)
@@ -159,12 +256,14 @@ func connectToMariaDB(mysqlAdminUsername, mysqlAdminPassword string) (*sql.DB, e
 	return mariadbDatabaseConnection, nil
 }
 
-func startMariaDB() error {
+func startMariaDB(extraArgs ...string) error {
 	_, err := os.Stat("/var/vcap/packages/mariadb/bin")
 	if os.IsNotExist(err) {
 		return fmt.Errorf("Missing mariadb packages. Unable to migrate from cf-mysql-release to pxc-release. In order to migrate from cf-mysql-release, both releases must be deployed on the same instance group.")
 	}
-	mariadbCmd := exec.Command("/var/vcap/packages/mariadb/bin/mysqld_safe", "--defaults-file=/var/vcap/jobs/mysql/config/my.cnf", "--wsrep-on=OFF", "--wsrep-desync=ON", "--wsrep-OSU-method=RSU", "--wsrep-provider='none'", "--skip-networking")
+	mariadbArgs := []string{"--defaults-file=/var/vcap/jobs/mysql/config/my.cnf", "--wsrep-on=OFF", "--wsrep-desync=ON", "--wsrep-OSU-method=RSU", "--wsrep-provider='none'", "--skip-networking"}
+	mariadbArgs = append(mariadbArgs, extraArgs...)
+	mariadbCmd := exec.Command("/var/vcap/packages/mariadb/bin/mysqld_safe", mariadbArgs...)
 	err = mariadbCmd.Start()
 	return err
 }
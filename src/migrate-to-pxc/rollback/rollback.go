@@ -0,0 +1,170 @@
+package rollback
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	MysqlDirName          = "mysql"
+	BackupDirName         = "mysql-migration-backup"
+	PxcDirName            = "pxc-mysql"
+	MigrationMarkerName   = "migrated-successfully"
+	mysqlSystemSchemaName = "mysql"
+)
+
+// Pxc is where a running pxc-mysql leaves evidence of itself.
+type Pxc struct {
+	// PidFiles are the pid files of galera-init and mysqld.
+	PidFiles []string
+	// Socket is mysqld's unix socket.
+	Socket string
+}
+
+// DefaultPxc is where the pxc-mysql job runs galera-init and mysqld.
+var DefaultPxc = Pxc{
+	PidFiles: []string{
+		"/var/vcap/sys/run/bpm/pxc-mysql/galera-init.pid",
+		"/var/vcap/store/pxc-mysql/mysql.pid",
+	},
+	Socket: "/var/vcap/sys/run/pxc-mysql/mysqld.sock",
+}
+
+// Rollback restores the cf-mysql-release datadir that pre-start set aside
+// during the migration and removes everything the migration left behind, so
+// that the mysql job from cf-mysql-release can be started again. It refuses
+// to run while pxc is running, since it deletes pxc's datadir.
+func Rollback(storeDir string, pxc Pxc) error {
+	backupDir := filepath.Join(storeDir, BackupDirName)
+	mysqlDir := filepath.Join(storeDir, MysqlDirName)
+	pxcDir := filepath.Join(storeDir, PxcDirName)
+	marker := filepath.Join(storeDir, MigrationMarkerName)
+
+	if err := pxc.checkStopped(); err != nil {
+		return err
+	}
+
+	if err := validateBackup(backupDir); err != nil {
+		return err
+	}
+
+	if err := removePlaceholder(mysqlDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("restoring %s to %s...\n", backupDir, mysqlDir)
+	if err := os.Rename(backupDir, mysqlDir); err != nil {
+		return fmt.Errorf("failed to restore migration backup: %s", err)
+	}
+
+	fmt.Printf("removing migration marker %s...\n", marker)
+	if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove migration marker: %s", err)
+	}
+
+	fmt.Printf("removing pxc datadir %s...\n", pxcDir)
+	if err := os.RemoveAll(pxcDir); err != nil {
+		return fmt.Errorf("failed to remove pxc datadir: %s", err)
+	}
+
+	return nil
+}
+
+func (p Pxc) checkStopped() error {
+	for _, pidFile := range p.PidFiles {
+		pid, err := readPid(pidFile)
+		if err != nil {
+			continue
+		}
+		if processRunning(pid) {
+			return fmt.Errorf("Cannot roll back, pxc is running: %s names live process %d. Stop it with 'monit stop galera-init' first", pidFile, pid)
+		}
+	}
+
+	if p.Socket != "" {
+		conn, err := net.DialTimeout("unix", p.Socket, time.Second)
+		if err == nil {
+			conn.Close()
+			return fmt.Errorf("Cannot roll back, pxc is running: %s accepts connections. Stop it with 'monit stop galera-init' first", p.Socket)
+		}
+	}
+
+	return nil
+}
+
+func readPid(pidFile string) (int, error) {
+	contents, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(contents)))
+}
+
+// processRunning reports whether pid exists. EPERM means it exists but
+// belongs to another user.
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+func validateBackup(backupDir string) error {
+	info, err := os.Stat(backupDir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("Cannot roll back, no migration backup found at %s", backupDir)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("Cannot roll back, %s is not a directory", backupDir)
+	}
+
+	systemSchema := filepath.Join(backupDir, mysqlSystemSchemaName)
+	info, err = os.Stat(systemSchema)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("Cannot roll back, %s does not look like a mysql datadir: missing %s", backupDir, systemSchema)
+	}
+
+	return nil
+}
+
+// removePlaceholder removes the empty, mode 000 directory that pre-start
+// creates to prevent cf-mysql-release from starting with an empty datadir.
+// Anything other than an empty directory is left alone.
+func removePlaceholder(mysqlDir string) error {
+	info, err := os.Stat(mysqlDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("Cannot roll back, %s exists and is not a directory", mysqlDir)
+	}
+
+	if err := os.Chmod(mysqlDir, 0700); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(mysqlDir)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		_ = os.Chmod(mysqlDir, info.Mode().Perm())
+		return fmt.Errorf("Cannot roll back, %s is not empty", mysqlDir)
+	}
+
+	return os.Remove(mysqlDir)
+}
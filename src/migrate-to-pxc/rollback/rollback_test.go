@@ -0,0 +1,154 @@
+package rollback_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"migrate-to-pxc/rollback"
+)
+
+var _ = Describe("Rollback", func() {
+	var (
+		storeDir  string
+		backupDir string
+		mysqlDir  string
+		pxcDir    string
+		marker    string
+		pxc       rollback.Pxc
+	)
+
+	BeforeEach(func() {
+		var err error
+		storeDir, err = ioutil.TempDir("", "rollback")
+		Expect(err).NotTo(HaveOccurred())
+
+		backupDir = filepath.Join(storeDir, "mysql-migration-backup")
+		mysqlDir = filepath.Join(storeDir, "mysql")
+		pxcDir = filepath.Join(storeDir, "pxc-mysql")
+		marker = filepath.Join(storeDir, "migrated-successfully")
+
+		Expect(os.MkdirAll(filepath.Join(backupDir, "mysql"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(backupDir, "ibdata1"), []byte("data"), 0600)).To(Succeed())
+		Expect(os.Mkdir(mysqlDir, 0000)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(pxcDir, "mysql"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(marker, []byte("DO NOT DELETE THIS FILE; YOU WILL LOSE DATA"), 0644)).To(Succeed())
+
+		runDir := filepath.Join(storeDir, "run")
+		Expect(os.Mkdir(runDir, 0700)).To(Succeed())
+		pxc = rollback.Pxc{
+			PidFiles: []string{filepath.Join(runDir, "galera-init.pid"), filepath.Join(runDir, "mysql.pid")},
+			Socket:   filepath.Join(runDir, "mysqld.sock"),
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.Chmod(mysqlDir, 0700)
+		Expect(os.RemoveAll(storeDir)).To(Succeed())
+	})
+
+	It("restores the backup and removes the migration artifacts", func() {
+		Expect(rollback.Rollback(storeDir, pxc)).To(Succeed())
+
+		Expect(filepath.Join(mysqlDir, "ibdata1")).To(BeARegularFile())
+		Expect(filepath.Join(mysqlDir, "mysql")).To(BeADirectory())
+		Expect(backupDir).NotTo(BeAnExistingFile())
+		Expect(pxcDir).NotTo(BeAnExistingFile())
+		Expect(marker).NotTo(BeAnExistingFile())
+	})
+
+	It("succeeds when the migration did not get as far as writing the marker", func() {
+		Expect(os.Remove(marker)).To(Succeed())
+
+		Expect(rollback.Rollback(storeDir, pxc)).To(Succeed())
+		Expect(filepath.Join(mysqlDir, "ibdata1")).To(BeARegularFile())
+	})
+
+	It("returns an error when there is no backup", func() {
+		Expect(os.RemoveAll(backupDir)).To(Succeed())
+
+		err := rollback.Rollback(storeDir, pxc)
+		Expect(err).To(MatchError(ContainSubstring("no migration backup found")))
+		Expect(marker).To(BeAnExistingFile())
+		Expect(pxcDir).To(BeADirectory())
+	})
+
+	It("returns an error when the backup is not a mysql datadir", func() {
+		Expect(os.RemoveAll(filepath.Join(backupDir, "mysql"))).To(Succeed())
+
+		err := rollback.Rollback(storeDir, pxc)
+		Expect(err).To(MatchError(ContainSubstring("does not look like a mysql datadir")))
+		Expect(backupDir).To(BeADirectory())
+		Expect(pxcDir).To(BeADirectory())
+	})
+
+	It("does not overwrite a mysql datadir that is not the migration placeholder", func() {
+		Expect(os.Chmod(mysqlDir, 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(mysqlDir, "ibdata1"), []byte("other"), 0600)).To(Succeed())
+
+		err := rollback.Rollback(storeDir, pxc)
+		Expect(err).To(MatchError(ContainSubstring("is not empty")))
+		Expect(backupDir).To(BeADirectory())
+		Expect(marker).To(BeAnExistingFile())
+	})
+
+	Context("when pxc is running", func() {
+		expectUntouched := func(err error) {
+			Expect(err).To(MatchError(ContainSubstring("Cannot roll back, pxc is running")))
+			Expect(backupDir).To(BeADirectory())
+			Expect(filepath.Join(pxcDir, "mysql")).To(BeADirectory())
+			Expect(marker).To(BeAnExistingFile())
+		}
+
+		It("refuses when a pid file names a live process", func() {
+			Expect(ioutil.WriteFile(pxc.PidFiles[1], []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)).To(Succeed())
+
+			err := rollback.Rollback(storeDir, pxc)
+			expectUntouched(err)
+			Expect(err).To(MatchError(ContainSubstring(pxc.PidFiles[1])))
+		})
+
+		It("refuses when mysqld's socket accepts connections", func() {
+			listener, err := net.Listen("unix", pxc.Socket)
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			err = rollback.Rollback(storeDir, pxc)
+			expectUntouched(err)
+			Expect(err).To(MatchError(ContainSubstring(pxc.Socket + " accepts connections")))
+		})
+
+		It("ignores stale pid files and sockets", func() {
+			exited := exec.Command("true")
+			Expect(exited.Run()).To(Succeed())
+			Expect(ioutil.WriteFile(pxc.PidFiles[0], []byte(fmt.Sprintf("%d\n", exited.Process.Pid)), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(pxc.PidFiles[1], []byte("not a pid"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(pxc.Socket, nil, 0644)).To(Succeed())
+
+			Expect(rollback.Rollback(storeDir, pxc)).To(Succeed())
+			Expect(pxcDir).NotTo(BeAnExistingFile())
+		})
+	})
+})
+
+var _ = Describe("DefaultPxc", func() {
+	It("checks the pid file and socket the pxc-mysql my.cnf template configures", func() {
+		template, err := ioutil.ReadFile("../../../jobs/pxc-mysql/templates/my.cnf.erb")
+		Expect(err).NotTo(HaveOccurred())
+
+		pidFile := regexp.MustCompile(`(?m)^pid-file\s*=\s*(\S+)$`).FindSubmatch(template)
+		Expect(pidFile).NotTo(BeNil())
+		Expect(rollback.DefaultPxc.PidFiles).To(ContainElement(string(pidFile[1])))
+
+		baseFolder := regexp.MustCompile(`base_folder = '([^']+)'`).FindSubmatch(template)
+		Expect(baseFolder).NotTo(BeNil())
+		Expect(rollback.DefaultPxc.Socket).To(Equal(string(baseFolder[1]) + "/mysqld.sock"))
+	})
+})
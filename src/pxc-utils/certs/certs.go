@@ -0,0 +1,256 @@
+// Package certs checks the TLS material a job renders before the process
+// that uses it starts. Problems with it otherwise only show up as failed
+// client connections or a node that cannot receive SST.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Severity is how bad a Finding is. Errors fail pre-start; warnings are
+// only reported.
+type Severity string
+
+const (
+	Error   Severity = "ERROR"
+	Warning Severity = "WARNING"
+)
+
+// Usage is a purpose a certificate must be valid for.
+type Usage string
+
+const (
+	ServerAuth Usage = "server"
+	ClientAuth Usage = "client"
+)
+
+// Pair is a certificate, its private key and the CA that must have issued
+// it, as rendered by a job.
+type Pair struct {
+	Name        string `yaml:"name"`
+	CA          string `yaml:"ca"`
+	Certificate string `yaml:"certificate"`
+	PrivateKey  string `yaml:"private_key"`
+	// Usages are what the certificate is used for. Galera and SST
+	// certificates are used on both ends of a connection.
+	Usages []Usage `yaml:"usages"`
+	// Hostnames must all be covered by the certificate's subject
+	// alternative names.
+	Hostnames []string `yaml:"hostnames"`
+}
+
+// Finding is a problem with a pair.
+type Finding struct {
+	Severity Severity
+	Pair     string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%-7s %s: %s", f.Severity, f.Pair, f.Message)
+}
+
+// Checker checks pairs at a point in time.
+type Checker struct {
+	Now func() time.Time
+	// WarnWithin is how long before a certificate expires it is reported.
+	WarnWithin time.Duration
+}
+
+// Report is the outcome of checking a pair.
+type Report struct {
+	Pair Pair
+	Leaf *x509.Certificate
+	// Intermediates are the certificates after the leaf in its file.
+	Intermediates []*x509.Certificate
+	CAs           []*x509.Certificate
+	Findings      []Finding
+}
+
+// OK is true when the pair has no errors.
+func (r Report) OK() bool {
+	for _, finding := range r.Findings {
+		if finding.Severity == Error {
+			return false
+		}
+	}
+	return true
+}
+
+// Check loads the pair's files and checks that the key matches the
+// certificate, that the certificate chains to the CA for each usage, that
+// nothing is expired or about to expire, and that the expected hostnames are
+// covered.
+func (c *Checker) Check(pair Pair) Report {
+	report := Report{Pair: pair}
+	add := func(severity Severity, format string, args ...interface{}) {
+		report.Findings = append(report.Findings, Finding{Severity: severity, Pair: pair.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	caPEM, err := ioutil.ReadFile(pair.CA)
+	if err == nil {
+		report.CAs, err = ParseCertificates(caPEM)
+	}
+	if err != nil {
+		add(Error, "loading CA %s: %s", pair.CA, err)
+	}
+
+	certPEM, err := ioutil.ReadFile(pair.Certificate)
+	var chain []*x509.Certificate
+	if err == nil {
+		chain, err = ParseCertificates(certPEM)
+	}
+	if err != nil {
+		add(Error, "loading certificate %s: %s", pair.Certificate, err)
+		return report
+	}
+	report.Leaf, report.Intermediates = chain[0], chain[1:]
+
+	keyPEM, err := ioutil.ReadFile(pair.PrivateKey)
+	if err != nil {
+		add(Error, "loading private key %s: %s", pair.PrivateKey, err)
+	} else if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		add(Error, "private key %s does not match certificate %s: %s; render both from the same credential", pair.PrivateKey, pair.Certificate, err)
+	}
+
+	now := c.Now()
+	for _, ca := range report.CAs {
+		c.checkValidity(add, "CA "+describe(ca), ca, now)
+	}
+	c.checkValidity(add, "certificate "+describe(report.Leaf), report.Leaf, now)
+
+	if len(report.CAs) > 0 {
+		checkChain(add, report, now)
+	}
+
+	checkNames(add, report.Leaf, pair.Hostnames)
+	return report
+}
+
+func (c *Checker) checkValidity(add func(Severity, string, ...interface{}), subject string, cert *x509.Certificate, now time.Time) {
+	switch {
+	case now.Before(cert.NotBefore):
+		add(Error, "%s is not valid until %s; check the clock or reissue it", subject, cert.NotBefore.UTC().Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		add(Error, "%s expired on %s; rotate it", subject, cert.NotAfter.UTC().Format(time.RFC3339))
+	case cert.NotAfter.Sub(now) < c.WarnWithin:
+		add(Warning, "%s expires in %d days, on %s; rotate it soon", subject,
+			int(cert.NotAfter.Sub(now).Hours()/24), cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+}
+
+func checkChain(add func(Severity, string, ...interface{}), report Report, now time.Time) {
+	roots := x509.NewCertPool()
+	for _, ca := range report.CAs {
+		roots.AddCert(ca)
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range report.Intermediates {
+		pool.AddCert(cert)
+	}
+
+	usages := report.Pair.Usages
+	if len(usages) == 0 {
+		usages = []Usage{ServerAuth}
+	}
+	for _, usage := range usages {
+		extUsage := x509.ExtKeyUsageServerAuth
+		if usage == ClientAuth {
+			extUsage = x509.ExtKeyUsageClientAuth
+		}
+
+		_, err := report.Leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: pool,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{extUsage},
+		})
+		if err == nil {
+			continue
+		}
+
+		switch err.(type) {
+		case x509.UnknownAuthorityError:
+			add(Error, "certificate %s was not issued by the CA in %s; it was issued by %q",
+				describe(report.Leaf), report.Pair.CA, report.Leaf.Issuer.String())
+			return
+		case x509.CertificateInvalidError:
+			switch err.(x509.CertificateInvalidError).Reason {
+			case x509.Expired:
+				// Reported by checkValidity.
+				return
+			case x509.IncompatibleUsage:
+				add(Error, "certificate %s cannot be used for %s authentication; reissue it with that extended key usage", describe(report.Leaf), usage)
+				continue
+			}
+		}
+		add(Error, "certificate %s does not verify against %s: %s", describe(report.Leaf), report.Pair.CA, err)
+		return
+	}
+}
+
+func checkNames(add func(Severity, string, ...interface{}), leaf *x509.Certificate, hostnames []string) {
+	if len(leaf.DNSNames) == 0 && len(leaf.IPAddresses) == 0 {
+		add(Warning, "certificate %s has no subject alternative names; clients that ignore the common name will reject it", describe(leaf))
+	}
+
+	var missing []string
+	for _, hostname := range hostnames {
+		if err := leaf.VerifyHostname(hostname); err != nil {
+			missing = append(missing, hostname)
+		}
+	}
+	if len(missing) > 0 {
+		add(Error, "certificate %s does not cover %s; its subject alternative names are %s",
+			describe(leaf), strings.Join(missing, ", "), strings.Join(names(leaf), ", "))
+	}
+}
+
+func names(cert *x509.Certificate) []string {
+	all := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		all = append(all, ip.String())
+	}
+	if len(all) == 0 {
+		return []string{"(none)"}
+	}
+	return all
+}
+
+func describe(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return fmt.Sprintf("%q", cert.Subject.CommonName)
+	}
+	return fmt.Sprintf("%q", cert.Subject.String())
+}
+
+// ParseCertificates returns every certificate in a PEM bundle, in order.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificates found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,161 @@
+package certs_test
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/certs"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		dir     string
+		ca      issued
+		leaf    issued
+		pair    certs.Pair
+		now     time.Time
+		checker *certs.Checker
+	)
+
+	write := func(caFile []byte, cert issued, key issued) {
+		pair.CA = writeFile(dir, "ca.pem", caFile)
+		pair.Certificate = writeFile(dir, "cert.pem", certPEM(cert))
+		pair.PrivateKey = writeFile(dir, "key.pem", keyPEM(key))
+	}
+
+	messages := func(report certs.Report) []string {
+		var m []string
+		for _, finding := range report.Findings {
+			m = append(m, finding.String())
+		}
+		return m
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "certs")
+		Expect(err).NotTo(HaveOccurred())
+
+		ca = newCA("galera-ca")
+		leaf = newLeaf(template{commonName: "galera", dnsNames: []string{"*.mysql.default.pxc.bosh"}, ips: []string{"10.0.0.1"}}, ca)
+		pair = certs.Pair{
+			Name:      "galera",
+			Usages:    []certs.Usage{certs.ServerAuth, certs.ClientAuth},
+			Hostnames: []string{"0.mysql.default.pxc.bosh", "10.0.0.1"},
+		}
+		write(certPEM(ca), leaf, leaf)
+
+		now = epoch.AddDate(0, 1, 0)
+		checker = &certs.Checker{Now: func() time.Time { return now }, WarnWithin: 30 * 24 * time.Hour}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("accepts a valid pair", func() {
+		report := checker.Check(pair)
+		Expect(report.Findings).To(BeEmpty())
+		Expect(report.OK()).To(BeTrue())
+		Expect(report.Leaf.Subject.CommonName).To(Equal("galera"))
+		Expect(report.CAs).To(HaveLen(1))
+	})
+
+	It("rejects a key that belongs to another certificate", func() {
+		write(certPEM(ca), leaf, newLeaf(template{commonName: "other"}, ca))
+
+		report := checker.Check(pair)
+		Expect(report.OK()).To(BeFalse())
+		Expect(messages(report)).To(ConsistOf(HavePrefix("ERROR   galera: private key " + pair.PrivateKey + " does not match certificate " + pair.Certificate)))
+	})
+
+	It("rejects a certificate issued by another CA", func() {
+		write(certPEM(newCA("server-ca")), leaf, leaf)
+
+		report := checker.Check(pair)
+		Expect(messages(report)).To(ConsistOf(
+			`ERROR   galera: certificate "galera" was not issued by the CA in ` + pair.CA + `; it was issued by "CN=galera-ca"`,
+		))
+	})
+
+	It("accepts a certificate issued by any CA in a bundle", func() {
+		write(certPEM(newCA("new-galera-ca"), ca), leaf, leaf)
+		Expect(checker.Check(pair).Findings).To(BeEmpty())
+	})
+
+	It("rejects a certificate that cannot be used on both ends of a connection", func() {
+		leaf = newLeaf(template{commonName: "galera", dnsNames: []string{"*.mysql.default.pxc.bosh"}, ips: []string{"10.0.0.1"},
+			usages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, ca)
+		write(certPEM(ca), leaf, leaf)
+
+		Expect(messages(checker.Check(pair))).To(ConsistOf(
+			`ERROR   galera: certificate "galera" cannot be used for client authentication; reissue it with that extended key usage`,
+		))
+	})
+
+	Describe("expiry", func() {
+		It("warns about a certificate that expires within the window", func() {
+			now = leaf.cert.NotAfter.Add(-10 * 24 * time.Hour)
+
+			report := checker.Check(pair)
+			Expect(report.OK()).To(BeTrue())
+			Expect(messages(report)).To(ConsistOf(
+				`WARNING galera: certificate "galera" expires in 10 days, on 2027-01-01T00:00:00Z; rotate it soon`,
+			))
+		})
+
+		It("rejects an expired certificate once", func() {
+			now = leaf.cert.NotAfter.Add(time.Hour)
+
+			Expect(messages(checker.Check(pair))).To(ConsistOf(
+				`ERROR   galera: certificate "galera" expired on 2027-01-01T00:00:00Z; rotate it`,
+			))
+		})
+
+		It("rejects a certificate that is not valid yet", func() {
+			now = epoch.Add(-time.Hour)
+
+			report := checker.Check(pair)
+			Expect(report.OK()).To(BeFalse())
+			Expect(messages(report)).To(ContainElement(
+				`ERROR   galera: CA "galera-ca" is not valid until 2026-01-01T00:00:00Z; check the clock or reissue it`,
+			))
+		})
+	})
+
+	Describe("names", func() {
+		It("rejects a certificate that does not cover the expected hostnames", func() {
+			pair.Hostnames = []string{"10.0.0.2", "0.mysql.other.bosh"}
+
+			Expect(messages(checker.Check(pair))).To(ConsistOf(
+				`ERROR   galera: certificate "galera" does not cover 10.0.0.2, 0.mysql.other.bosh; its subject alternative names are *.mysql.default.pxc.bosh, 10.0.0.1`,
+			))
+		})
+
+		It("warns about a certificate with only a common name", func() {
+			pair.Hostnames = nil
+			leaf = newLeaf(template{commonName: "galera"}, ca)
+			write(certPEM(ca), leaf, leaf)
+
+			Expect(messages(checker.Check(pair))).To(ConsistOf(
+				`WARNING galera: certificate "galera" has no subject alternative names; clients that ignore the common name will reject it`,
+			))
+		})
+	})
+
+	It("reports files that cannot be loaded", func() {
+		pair.CA = writeFile(dir, "ca.pem", []byte("not a certificate"))
+		pair.PrivateKey = "/does/not/exist"
+
+		report := checker.Check(pair)
+		Expect(messages(report)).To(ConsistOf(
+			"ERROR   galera: loading CA "+pair.CA+": no PEM certificates found",
+			HavePrefix("ERROR   galera: loading private key /does/not/exist: "),
+		))
+	})
+})
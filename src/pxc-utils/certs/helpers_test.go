@@ -0,0 +1,105 @@
+package certs_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// issued is a certificate with its key, as the job renders them.
+type issued struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+}
+
+type template struct {
+	commonName string
+	notBefore  time.Time
+	notAfter   time.Time
+	dnsNames   []string
+	ips        []string
+	usages     []x509.ExtKeyUsage
+}
+
+func newCA(commonName string) issued {
+	return issue(template{commonName: commonName, notBefore: epoch, notAfter: epoch.AddDate(5, 0, 0)}, nil, true)
+}
+
+func newLeaf(t template, ca issued) issued {
+	if t.notBefore.IsZero() {
+		t.notBefore = epoch
+	}
+	if t.notAfter.IsZero() {
+		t.notAfter = epoch.AddDate(1, 0, 0)
+	}
+	if t.usages == nil {
+		t.usages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	}
+	return issue(t, &ca, false)
+}
+
+var serial int64
+
+func issue(t template, parent *issued, isCA bool) issued {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	Expect(err).NotTo(HaveOccurred())
+
+	serial++
+	cert := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: t.commonName},
+		NotBefore:             t.notBefore,
+		NotAfter:              t.notAfter,
+		DNSNames:              t.dnsNames,
+		ExtKeyUsage:           t.usages,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	for _, ip := range t.ips {
+		cert.IPAddresses = append(cert.IPAddresses, net.ParseIP(ip))
+	}
+	if isCA {
+		cert.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signer := issued{cert: cert, key: key}
+	if parent != nil {
+		signer = *parent
+	}
+	der, err := x509.CreateCertificate(rand.Reader, cert, signer.cert, &key.PublicKey, signer.key)
+	Expect(err).NotTo(HaveOccurred())
+
+	parsed, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+	return issued{cert: parsed, key: key}
+}
+
+func certPEM(certs ...issued) []byte {
+	var data []byte
+	for _, c := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})...)
+	}
+	return data
+}
+
+func keyPEM(c issued) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(c.key)})
+}
+
+func writeFile(dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	Expect(ioutil.WriteFile(path, data, 0600)).To(Succeed())
+	return path
+}
@@ -0,0 +1,94 @@
+package certs
+
+import (
+	"crypto/x509"
+	"errors"
+	"sort"
+)
+
+// Phase is where a pair is in a CA rotation. A CA is rotated without
+// downtime in three deploys: the new CA is added to every node's CA bundle,
+// then every leaf certificate is reissued by the new CA while the bundle
+// still holds the old one, and finally the old CA is removed. Each deploy
+// may only start once every node has reached the phase the previous one
+// leads to.
+type Phase string
+
+const (
+	// SingleCA is a bundle of one CA that issued the leaf, before a
+	// rotation starts or after it ends.
+	SingleCA Phase = "single-ca"
+	// TrustingNewCA is a bundle that holds a CA newer than the one that
+	// issued the leaf.
+	TrustingNewCA Phase = "trusting-new-ca"
+	// UsingNewCA is a leaf issued by the newest CA of a bundle that still
+	// holds older ones.
+	UsingNewCA Phase = "using-new-ca"
+	// Untrusted is a leaf that no CA in the bundle issued.
+	Untrusted Phase = "untrusted"
+)
+
+// Rotation describes a pair's rotation phase.
+type Rotation struct {
+	Pair  string `json:"pair"`
+	Phase Phase  `json:"phase"`
+	// Issuer is the common name of the CA that issued the leaf.
+	Issuer string `json:"issuer,omitempty"`
+	// TrustedCAs are the common names of the CAs in the bundle, oldest
+	// first.
+	TrustedCAs []string `json:"trusted_cas"`
+}
+
+// DetectRotation returns the rotation phase of a checked pair. CAs are
+// ordered by when they became valid.
+func DetectRotation(report Report) (Rotation, error) {
+	if report.Leaf == nil || len(report.CAs) == 0 {
+		return Rotation{}, errors.New("the certificate and CA must load to detect a rotation")
+	}
+
+	rotation := Rotation{Pair: report.Pair.Name, Phase: Untrusted}
+	var issuer *x509.Certificate
+	for _, ca := range report.CAs {
+		if issuer == nil && issued(report, ca) {
+			issuer = ca
+			rotation.Issuer = ca.Subject.CommonName
+		}
+	}
+
+	for _, ca := range sortedByNotBefore(report.CAs) {
+		rotation.TrustedCAs = append(rotation.TrustedCAs, ca.Subject.CommonName)
+	}
+
+	if issuer == nil {
+		return rotation, nil
+	}
+
+	rotation.Phase = UsingNewCA
+	if len(report.CAs) == 1 {
+		rotation.Phase = SingleCA
+	}
+	for _, ca := range report.CAs {
+		if ca.NotBefore.After(issuer.NotBefore) {
+			rotation.Phase = TrustingNewCA
+		}
+	}
+	return rotation, nil
+}
+
+// issued reports whether ca signed the top of the pair's chain, so that
+// the phase does not depend on the current time.
+func issued(report Report, ca *x509.Certificate) bool {
+	top := report.Leaf
+	if len(report.Intermediates) > 0 {
+		top = report.Intermediates[len(report.Intermediates)-1]
+	}
+	return top.CheckSignatureFrom(ca) == nil
+}
+
+func sortedByNotBefore(cas []*x509.Certificate) []*x509.Certificate {
+	sorted := append([]*x509.Certificate(nil), cas...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].NotBefore.Before(sorted[j].NotBefore)
+	})
+	return sorted
+}
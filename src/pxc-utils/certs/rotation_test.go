@@ -0,0 +1,79 @@
+package certs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/certs"
+)
+
+var _ = Describe("DetectRotation", func() {
+	var (
+		dir      string
+		firstCA  issued
+		secondCA issued
+		checker  *certs.Checker
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "rotation")
+		Expect(err).NotTo(HaveOccurred())
+
+		firstCA = newCA("galera-ca")
+		secondCA = issue(template{commonName: "galera-ca-2", notBefore: epoch.AddDate(0, 6, 0), notAfter: epoch.AddDate(6, 0, 0)}, nil, true)
+		checker = &certs.Checker{Now: func() time.Time { return epoch.AddDate(0, 7, 0) }}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	detect := func(bundle []byte, leaf issued) certs.Rotation {
+		pair := certs.Pair{
+			Name:        "galera",
+			CA:          writeFile(dir, "ca.pem", bundle),
+			Certificate: writeFile(dir, "cert.pem", certPEM(leaf)),
+			PrivateKey:  writeFile(dir, "key.pem", keyPEM(leaf)),
+		}
+		rotation, err := certs.DetectRotation(checker.Check(pair))
+		Expect(err).NotTo(HaveOccurred())
+		return rotation
+	}
+
+	It("follows a rotation through its phases", func() {
+		firstLeaf := newLeaf(template{commonName: "galera"}, firstCA)
+		secondLeaf := newLeaf(template{commonName: "galera", notBefore: epoch.AddDate(0, 6, 0)}, secondCA)
+
+		Expect(detect(certPEM(firstCA), firstLeaf)).To(Equal(certs.Rotation{
+			Pair: "galera", Phase: certs.SingleCA, Issuer: "galera-ca", TrustedCAs: []string{"galera-ca"},
+		}))
+
+		Expect(detect(certPEM(secondCA, firstCA), firstLeaf)).To(Equal(certs.Rotation{
+			Pair: "galera", Phase: certs.TrustingNewCA, Issuer: "galera-ca", TrustedCAs: []string{"galera-ca", "galera-ca-2"},
+		}))
+
+		Expect(detect(certPEM(firstCA, secondCA), secondLeaf)).To(Equal(certs.Rotation{
+			Pair: "galera", Phase: certs.UsingNewCA, Issuer: "galera-ca-2", TrustedCAs: []string{"galera-ca", "galera-ca-2"},
+		}))
+
+		Expect(detect(certPEM(secondCA), secondLeaf)).To(Equal(certs.Rotation{
+			Pair: "galera", Phase: certs.SingleCA, Issuer: "galera-ca-2", TrustedCAs: []string{"galera-ca-2"},
+		}))
+	})
+
+	It("reports a leaf that no CA in the bundle issued", func() {
+		rotation := detect(certPEM(firstCA), newLeaf(template{commonName: "galera"}, secondCA))
+		Expect(rotation.Phase).To(Equal(certs.Untrusted))
+		Expect(rotation.Issuer).To(BeEmpty())
+	})
+
+	It("needs the certificate and CA", func() {
+		_, err := certs.DetectRotation(certs.Report{})
+		Expect(err).To(HaveOccurred())
+	})
+})
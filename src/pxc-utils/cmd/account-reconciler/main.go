@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	yaml "gopkg.in/yaml.v2"
+
+	"pxc-utils/dbinit"
+	"pxc-utils/reconciler"
+)
+
+type config struct {
+	// AccountsFile is the account spec pxc-db-init reads.
+	AccountsFile    string                      `yaml:"accounts_file"`
+	Accounts        dbinit.Spec                 `yaml:"-"`
+	SeededDatabases []reconciler.SeededDatabase `yaml:"seeded_databases"`
+	Socket          string                      `yaml:"socket"`
+	IntervalSeconds int                         `yaml:"interval_seconds"`
+	Repair          bool                        `yaml:"repair"`
+}
+
+// account-reconciler keeps the accounts and seeded databases of the
+// pxc-mysql job in place while mysqld runs, logging every drift it finds to
+// stderr. Errors are logged and retried on the next interval, since mysqld
+// may be restarting.
+func main() {
+	configPath := flag.String("config", "/var/vcap/jobs/pxc-mysql/config/account-reconciler.yml", "Path to the reconciler config")
+	once := flag.Bool("once", false, "Reconcile once and exit")
+	flag.Parse()
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	mysqlConfig, err := reconciler.ConnectionConfig(cfg.Accounts, cfg.Socket)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
+	if err != nil {
+		logger.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+
+	r := &reconciler.Reconciler{
+		Store:     &reconciler.SQLStore{DB: db},
+		Accounts:  cfg.Accounts,
+		Databases: cfg.SeededDatabases,
+		Repair:    cfg.Repair,
+		Logger:    logger,
+	}
+
+	for {
+		_, err := r.Reconcile()
+		if err != nil {
+			logger.Printf("reconciling accounts: %s", err)
+		}
+		if *once {
+			if err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+		time.Sleep(time.Duration(cfg.IntervalSeconds) * time.Second)
+	}
+}
+
+func loadConfig(path string) (config, error) {
+	cfg := config{
+		AccountsFile:    "/var/vcap/jobs/pxc-mysql/config/db_init.yml",
+		Socket:          "/var/vcap/sys/run/pxc-mysql/mysqld.sock",
+		IntervalSeconds: 60,
+		Repair:          true,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	err = yaml.UnmarshalStrict(data, &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	data, err = ioutil.ReadFile(cfg.AccountsFile)
+	if err != nil {
+		return cfg, err
+	}
+
+	err = yaml.UnmarshalStrict(data, &cfg.Accounts)
+	if err != nil {
+		return cfg, fmt.Errorf("parsing %s: %s", cfg.AccountsFile, err)
+	}
+
+	if cfg.IntervalSeconds <= 0 {
+		return cfg, fmt.Errorf("interval_seconds must be positive, got %d", cfg.IntervalSeconds)
+	}
+	return cfg, cfg.Accounts.Validate()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"pxc-utils/process"
+)
+
+// kill-and-wait stops the process in a pid file, like kill_and_wait in
+// pid_utils.sh, and prints the result as JSON. It exits 1 if the process
+// could not be stopped.
+func main() {
+	pidFile := flag.String("pidfile", "", "Pid file of the process to stop")
+	executable := flag.String("executable", "", "Base name of the program the pid file is for; other programs are never signalled")
+	stages := flag.String("stages", "TERM:25s,KILL:5s", "Comma separated SIGNAL:TIMEOUT stages to escalate through")
+	flag.Parse()
+
+	if *pidFile == "" {
+		fmt.Fprintln(os.Stderr, "-pidfile is required")
+		os.Exit(2)
+	}
+
+	parsed, err := process.ParseStages(*stages)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	stopper := &process.Stopper{
+		Table:        process.ProcTable{},
+		Stages:       parsed,
+		Executable:   *executable,
+		PollInterval: 100 * time.Millisecond,
+	}
+
+	result := stopper.Stop(*pidFile)
+	err = json.NewEncoder(os.Stdout).Encode(result)
+	if err != nil {
+		panic(err)
+	}
+
+	if !result.Succeeded() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"pxc-utils/certs"
+)
+
+type config struct {
+	WarningDays int          `yaml:"warning_days"`
+	Pairs       []certs.Pair `yaml:"pairs"`
+}
+
+// pxc-check-certs checks the certificates a job rendered before the process
+// that uses them starts. It prints a report and exits 1 when any pair has
+// an error. With -phase it prints the CA rotation phase of each pair as JSON
+// instead.
+func main() {
+	configPath := flag.String("config", "/var/vcap/jobs/pxc-mysql/config/certificates.yml", "Path to the list of certificates to check")
+	phase := flag.Bool("phase", false, "Print the CA rotation phase of each pair as JSON")
+	flag.Parse()
+
+	data, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		fail(err)
+	}
+
+	var cfg config
+	err = yaml.UnmarshalStrict(data, &cfg)
+	if err != nil {
+		fail(fmt.Errorf("parsing %s: %s", *configPath, err))
+	}
+
+	checker := &certs.Checker{
+		Now:        time.Now,
+		WarnWithin: time.Duration(cfg.WarningDays) * 24 * time.Hour,
+	}
+
+	if *phase {
+		printRotations(checker, cfg.Pairs)
+		return
+	}
+
+	ok := true
+	for _, pair := range cfg.Pairs {
+		report := checker.Check(pair)
+		if report.Leaf != nil && report.OK() {
+			fmt.Printf("%-7s %s: %q issued by %q, valid until %s\n", "OK", pair.Name,
+				report.Leaf.Subject.CommonName, report.Leaf.Issuer.CommonName, report.Leaf.NotAfter.UTC().Format(time.RFC3339))
+		}
+		for _, finding := range report.Findings {
+			fmt.Println(finding)
+		}
+		ok = ok && report.OK()
+	}
+
+	if !ok {
+		fail(fmt.Errorf("the certificates in %s are not usable", *configPath))
+	}
+}
+
+func printRotations(checker *certs.Checker, pairs []certs.Pair) {
+	rotations := []certs.Rotation{}
+	for _, pair := range pairs {
+		rotation, err := certs.DetectRotation(checker.Check(pair))
+		if err != nil {
+			fail(fmt.Errorf("%s: %s", pair.Name, err))
+		}
+		rotations = append(rotations, rotation)
+	}
+
+	err := json.NewEncoder(os.Stdout).Encode(rotations)
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"pxc-utils/dbinit"
+)
+
+// pxc-db-init writes the init_file that mysqld runs at startup from the
+// account spec rendered by the pxc-mysql job.
+func main() {
+	configPath := flag.String("config", "/var/vcap/jobs/pxc-mysql/config/db_init.yml", "Path to the account spec")
+	outputPath := flag.String("output", "/var/vcap/data/pxc-mysql/db_init", "Path to write the init_file to")
+	flag.Parse()
+
+	data, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		fail(err)
+	}
+
+	var spec dbinit.Spec
+	err = yaml.UnmarshalStrict(data, &spec)
+	if err != nil {
+		fail(fmt.Errorf("parsing %s: %s", *configPath, err))
+	}
+
+	sql, err := dbinit.Generate(spec)
+	if err != nil {
+		fail(err)
+	}
+
+	err = writeFile(*outputPath, []byte(sql))
+	if err != nil {
+		fail(err)
+	}
+}
+
+// writeFile replaces path atomically, so that mysqld never reads half of
+// it.
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"pxc-utils/drain"
+)
+
+type config struct {
+	Self        string   `yaml:"self"`
+	ClusterIPs  []string `yaml:"cluster_ips"`
+	GaleraAgent struct {
+		Port     int    `yaml:"port"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"galera_agent"`
+	PollIntervalSeconds   int    `yaml:"poll_interval_seconds"`
+	TimeoutSeconds        int    `yaml:"timeout_seconds"`
+	RequestTimeoutSeconds int    `yaml:"request_timeout_seconds"`
+	StateFile             string `yaml:"state_file"`
+}
+
+// pxc-drain is called from the pxc-mysql drain script with the script's
+// arguments. It prints the value BOSH expects on stdout and logs to stderr.
+func main() {
+	configPath := flag.String("config", "/var/vcap/jobs/pxc-mysql/config/drain.yml", "Path to the drain configuration")
+	flag.Parse()
+
+	logger := log.New(os.Stderr, "pxc-drain: ", log.LstdFlags)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	change, err := drain.ParseChange(os.Getenv("BOSH_JOB_STATE"), os.Getenv("BOSH_JOB_NEXT_STATE"))
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	drainer := &drain.Drainer{
+		Self:  cfg.Self,
+		Peers: cfg.ClusterIPs,
+		Fetcher: drain.NewAgentStatusFetcher(cfg.GaleraAgent.Port, cfg.GaleraAgent.Username, cfg.GaleraAgent.Password,
+			time.Duration(cfg.RequestTimeoutSeconds)*time.Second),
+		Change:       change,
+		PollInterval: time.Duration(cfg.PollIntervalSeconds) * time.Second,
+		Timeout:      time.Duration(cfg.TimeoutSeconds) * time.Second,
+		StateFile:    cfg.StateFile,
+		Logger:       logger,
+	}
+
+	checkStatus := flag.Arg(0) == "job_check_status"
+	logger.Printf("draining (%s, deleting: %t)", flag.Args(), change.Deleting())
+
+	value, err := drainer.Drain(checkStatus)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Println(value)
+}
+
+func loadConfig(path string) (config, error) {
+	cfg := config{
+		PollIntervalSeconds:   10,
+		TimeoutSeconds:        600,
+		RequestTimeoutSeconds: 5,
+		StateFile:             "/var/vcap/data/pxc-mysql/drain-started",
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config{}, err
+	}
+
+	err = yaml.Unmarshal(data, &cfg)
+	if err != nil {
+		return config{}, fmt.Errorf("parsing %s: %s", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+
+	"github.com/cloudfoundry/gosigar"
+
+	"pxc-utils/prestart"
+)
+
+const usage = `Usage: pxc-prestart datadir
+       pxc-prestart migrate -node-count N
+
+datadir  checks the persistent disk and initializes the data directory
+migrate  migrates cf-mysql-release data; reads MYSQL_USERNAME and MYSQL_PASSWORD
+`
+
+type execRunner struct{}
+
+func (execRunner) Run(env []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	config := prestart.DefaultConfig()
+	err := lookupVcap(&config)
+	if err != nil {
+		panic(err)
+	}
+
+	fs := prestart.OSFileSystem{}
+	var steps []prestart.Step
+
+	switch os.Args[1] {
+	case "datadir":
+		steps = prestart.DatadirSteps(config, fs, &sigar.ConcreteSigar{}, execRunner{})
+	case "migrate":
+		flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+		flags.IntVar(&config.NodeCount, "node-count", 0, "Number of mysql instances in the deployment")
+		flags.Parse(os.Args[2:])
+
+		config.AdminUsername = os.Getenv("MYSQL_USERNAME")
+		config.AdminPassword = os.Getenv("MYSQL_PASSWORD")
+		steps = prestart.MigrationSteps(config, fs, execRunner{})
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q.\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	outcomes, err := prestart.Run(steps)
+	prestart.PrintSummary(os.Stdout, outcomes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func lookupVcap(config *prestart.Config) error {
+	vcap, err := user.Lookup("vcap")
+	if err != nil {
+		return err
+	}
+
+	config.UID, err = strconv.Atoi(vcap.Uid)
+	if err != nil {
+		return err
+	}
+	config.GID, err = strconv.Atoi(vcap.Gid)
+	return err
+}
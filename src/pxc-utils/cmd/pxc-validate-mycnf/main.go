@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry/gosigar"
+
+	"pxc-utils/mycnf"
+)
+
+// pxc-validate-mycnf checks the rendered my.cnf, with the files it includes,
+// before mysqld starts. It prints one line per problem and exits 1 when any
+// of them is an error.
+func main() {
+	configPath := flag.String("config", "/var/vcap/jobs/pxc-mysql/config/my.cnf", "Path to the option file mysqld reads")
+	flag.Parse()
+
+	options, err := mycnf.ParseOptionFile(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	mem := sigar.Mem{}
+	err = mem.Get()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	problems := mycnf.Check(options.Merge(mycnf.MysqldSections...), mycnf.Rules(mem.Total))
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if mycnf.HasErrors(problems) {
+		fmt.Fprintf(os.Stderr, "%s is invalid; mysqld will not be started\n", *configPath)
+		os.Exit(1)
+	}
+	fmt.Printf("%s is valid\n", *configPath)
+}
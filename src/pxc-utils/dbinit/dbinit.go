@@ -0,0 +1,297 @@
+// Package dbinit generates the init_file that mysqld runs at startup to
+// manage the accounts pxc-mysql owns. The SQL targets MySQL 5.7 with its
+// default sql_mode, in which backslashes escape characters in strings.
+//
+// mysqld reads an init_file one line per statement, so every statement is
+// written on a single line, and running it again leaves the accounts
+// unchanged.
+package dbinit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LocalHosts are the hosts an account may connect from when remote access
+// is disabled.
+var LocalHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// RemoteHosts are the hosts an account may connect from when remote access
+// is enabled.
+var RemoteHosts = []string{"%"}
+
+const roadminUsername = "roadmin"
+
+// Spec declares the accounts pxc-mysql manages.
+type Spec struct {
+	AdminUsername string `yaml:"admin_username"`
+	AdminPassword string `yaml:"admin_password"`
+	// PreviousAdminUsername is an old admin account to remove after
+	// admin_username changed.
+	PreviousAdminUsername string `yaml:"previous_admin_username"`
+	// RemoteAdminAccess lets admin and roadmin connect from any host
+	// instead of only from the node itself.
+	RemoteAdminAccess bool `yaml:"remote_admin_access"`
+
+	Roadmin    Roadmin    `yaml:"roadmin"`
+	Backup     Backup     `yaml:"backup"`
+	Reconciler Reconciler `yaml:"reconciler"`
+}
+
+// Roadmin is the optional read-only admin account.
+type Roadmin struct {
+	Enabled  bool   `yaml:"enabled"`
+	Password string `yaml:"password"`
+}
+
+// Backup is the optional account that backup tools use. It is created when
+// it has a password.
+type Backup struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Reconciler is the optional account account-reconciler logs in with. It is
+// identified by auth_socket, so only the OS user of the same name can use it
+// over the socket, and it does not depend on the admin password the
+// reconciler repairs. That OS user must be dedicated to the reconciler.
+type Reconciler struct {
+	Username string `yaml:"username"`
+}
+
+// Account is a user at a host.
+type Account struct {
+	Username string
+	Host     string
+}
+
+func (a Account) String() string {
+	return QuoteString(a.Username) + "@" + QuoteString(a.Host)
+}
+
+// Validate reports specs that cannot produce working accounts.
+func (s Spec) Validate() error {
+	if s.AdminUsername == "" {
+		return errors.New("admin_username is required")
+	}
+	if s.AdminPassword == "" {
+		return errors.New("admin_password is required")
+	}
+	if s.PreviousAdminUsername != "" && s.PreviousAdminUsername == s.AdminUsername {
+		return errors.New("admin_username must not equal previous_admin_username")
+	}
+	if s.Roadmin.Enabled && s.Roadmin.Password == "" {
+		return errors.New("roadmin_password is required when roadmin_enabled is true")
+	}
+	if s.Roadmin.Enabled && s.AdminUsername == roadminUsername {
+		return errors.New("admin_username must not be roadmin when roadmin_enabled is true")
+	}
+	if s.Backup.Password != "" && s.Backup.Username == "" {
+		return errors.New("mysql_backup_username is required when mysql_backup_password is set")
+	}
+	if username := s.Reconciler.Username; username != "" {
+		switch username {
+		case "root", roadminUsername, s.AdminUsername, s.PreviousAdminUsername, s.Backup.Username:
+			return fmt.Errorf("the reconciler account must not be %s, which is managed as another account", username)
+		case "vcap":
+			return errors.New("the reconciler account must not be vcap, which every job process runs as")
+		}
+	}
+	return nil
+}
+
+// Hosts returns the hosts admin and roadmin may connect from.
+func (s Spec) Hosts() []string {
+	if s.RemoteAdminAccess {
+		return RemoteHosts
+	}
+	return LocalHosts
+}
+
+// OldHosts returns the hosts admin and roadmin were allowed from when
+// remote access was configured the other way.
+func (s Spec) OldHosts() []string {
+	if s.RemoteAdminAccess {
+		return LocalHosts
+	}
+	return RemoteHosts
+}
+
+// User is an account pxc-mysql creates, with the privileges it grants on
+// *.*.
+type User struct {
+	Account  Account
+	Password string
+	// Plugin identifies the account with an authentication plugin such as
+	// auth_socket instead of Password.
+	Plugin      string
+	Privileges  []string
+	GrantOption bool
+}
+
+// Plan is the account changes a Spec asks for, in the order they are
+// applied.
+type Plan struct {
+	// Drop are removed before Users are created; they include accounts
+	// that must not exist, such as root when another admin is configured.
+	Drop  []Account
+	Users []User
+	// DropAfter are removed once Users exist: the admin and roadmin
+	// accounts at hosts they are no longer allowed from.
+	DropAfter []Account
+	// Internal are created with Users but are not reconciled: the account
+	// the reconciler itself logs in with.
+	Internal []User
+}
+
+// PlanFor returns the changes that bring the accounts in line with spec.
+func PlanFor(spec Spec) (Plan, error) {
+	err := spec.Validate()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	hosts := spec.Hosts()
+	allHosts := append(append([]string(nil), hosts...), spec.OldHosts()...)
+
+	var plan Plan
+	if spec.PreviousAdminUsername != "" {
+		for _, host := range allHosts {
+			plan.Drop = append(plan.Drop, Account{spec.PreviousAdminUsername, host})
+		}
+	}
+
+	if spec.AdminUsername != "root" {
+		for _, host := range allHosts {
+			plan.Drop = append(plan.Drop, Account{"root", host})
+		}
+	}
+
+	if spec.Backup.Password != "" {
+		plan.Users = append(plan.Users, User{
+			Account:    Account{spec.Backup.Username, "localhost"},
+			Password:   spec.Backup.Password,
+			Privileges: []string{"RELOAD", "LOCK TABLES", "REPLICATION CLIENT", "PROCESS"},
+		})
+	}
+
+	for _, host := range hosts {
+		plan.Users = append(plan.Users, User{
+			Account:     Account{spec.AdminUsername, host},
+			Password:    spec.AdminPassword,
+			Privileges:  []string{AllPrivileges},
+			GrantOption: true,
+		})
+
+		if spec.Roadmin.Enabled {
+			plan.Users = append(plan.Users, User{
+				Account:    Account{roadminUsername, host},
+				Password:   spec.Roadmin.Password,
+				Privileges: []string{"SELECT", "PROCESS", "REPLICATION CLIENT"},
+			})
+		}
+	}
+
+	for _, host := range spec.OldHosts() {
+		plan.DropAfter = append(plan.DropAfter, Account{spec.AdminUsername, host}, Account{roadminUsername, host})
+	}
+
+	if !spec.Roadmin.Enabled {
+		for _, host := range hosts {
+			plan.DropAfter = append(plan.DropAfter, Account{roadminUsername, host})
+		}
+	}
+
+	if spec.Reconciler.Username != "" {
+		plan.Internal = append(plan.Internal, User{
+			Account:     Account{spec.Reconciler.Username, "localhost"},
+			Plugin:      "auth_socket",
+			Privileges:  []string{AllPrivileges},
+			GrantOption: true,
+		})
+	}
+
+	return plan, nil
+}
+
+// Generate returns the init_file for spec.
+func Generate(spec Spec) (string, error) {
+	plan, err := PlanFor(spec)
+	if err != nil {
+		return "", err
+	}
+
+	statements := []string{"DELETE FROM mysql.user WHERE User=''"}
+	for _, account := range plan.Drop {
+		statements = append(statements, DropUser(account))
+	}
+	for _, user := range append(plan.Users, plan.Internal...) {
+		statements = append(statements, CreateUser(user)...)
+		statements = append(statements, Grant(user))
+	}
+	for _, account := range plan.DropAfter {
+		statements = append(statements, DropUser(account))
+	}
+	statements = append(statements, "FLUSH PRIVILEGES")
+
+	return strings.Join(statements, ";\n") + ";\n", nil
+}
+
+// AllPrivileges grants every privilege but GRANT OPTION.
+const AllPrivileges = "ALL PRIVILEGES"
+
+// CreateUser returns the statements that create user, or reset its
+// password when it exists.
+func CreateUser(user User) []string {
+	if user.Plugin != "" {
+		return []string{
+			fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED WITH %s", user.Account, user.Plugin),
+			fmt.Sprintf("ALTER USER %s IDENTIFIED WITH %s", user.Account, user.Plugin),
+		}
+	}
+	return []string{
+		fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY %s", user.Account, QuoteString(user.Password)),
+		SetPassword(user.Account, user.Password),
+	}
+}
+
+// SetPassword returns the statement that sets account's password.
+func SetPassword(account Account, password string) string {
+	return fmt.Sprintf("ALTER USER %s IDENTIFIED BY %s", account, QuoteString(password))
+}
+
+// Grant returns the statement that grants user its privileges.
+func Grant(user User) string {
+	statement := fmt.Sprintf("GRANT %s ON *.* TO %s", strings.Join(user.Privileges, ", "), user.Account)
+	if user.GrantOption {
+		statement += " WITH GRANT OPTION"
+	}
+	return statement
+}
+
+// DropUser returns the statement that removes account if it exists.
+func DropUser(account Account) string {
+	return fmt.Sprintf("DROP USER IF EXISTS %s", account)
+}
+
+var stringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	"\x00", `\0`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x1a", `\Z`,
+)
+
+// QuoteString returns s as a single-quoted SQL string literal that stays on
+// one line.
+func QuoteString(s string) string {
+	return "'" + stringEscaper.Replace(s) + "'"
+}
+
+// QuoteIdentifier returns s as a backquoted identifier, such as a database
+// name.
+func QuoteIdentifier(s string) string {
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
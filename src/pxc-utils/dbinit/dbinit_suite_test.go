@@ -0,0 +1,16 @@
+package dbinit_test
+
+import (
+	"flag"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestDbinit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dbinit Suite")
+}
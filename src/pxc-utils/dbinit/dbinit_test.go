@@ -0,0 +1,180 @@
+package dbinit_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/dbinit"
+)
+
+var _ = Describe("Generate", func() {
+	baseSpec := func() dbinit.Spec {
+		return dbinit.Spec{
+			AdminUsername: "admin",
+			AdminPassword: "admin-password",
+		}
+	}
+
+	Describe("golden files", func() {
+		type combination struct {
+			remote, roadmin, backup, previous bool
+		}
+
+		var entries []TableEntry
+		for _, remote := range []bool{false, true} {
+			for _, roadmin := range []bool{false, true} {
+				for _, backup := range []bool{false, true} {
+					for _, previous := range []bool{false, true} {
+						c := combination{remote, roadmin, backup, previous}
+
+						name := []string{"local"}
+						if remote {
+							name[0] = "remote"
+						}
+						if roadmin {
+							name = append(name, "roadmin")
+						}
+						if backup {
+							name = append(name, "backup")
+						}
+						if previous {
+							name = append(name, "previous-admin")
+						}
+						entries = append(entries, Entry(strings.Join(name, "-"), strings.Join(name, "-"), c))
+					}
+				}
+			}
+		}
+
+		DescribeTable("matches testdata",
+			func(name string, c combination) {
+				spec := baseSpec()
+				spec.RemoteAdminAccess = c.remote
+				if c.roadmin {
+					spec.Roadmin = dbinit.Roadmin{Enabled: true, Password: "roadmin-password"}
+				}
+				if c.backup {
+					spec.Backup = dbinit.Backup{Username: "mysql-backup", Password: "backup-password"}
+				}
+				if c.previous {
+					spec.PreviousAdminUsername = "old-admin"
+				}
+
+				sql, err := dbinit.Generate(spec)
+				Expect(err).NotTo(HaveOccurred())
+				expectGolden(name, sql)
+			},
+			entries...,
+		)
+
+		It("keeps root when it is the admin", func() {
+			spec := baseSpec()
+			spec.AdminUsername = "root"
+
+			sql, err := dbinit.Generate(spec)
+			Expect(err).NotTo(HaveOccurred())
+			expectGolden("root-admin", sql)
+		})
+	})
+
+	It("writes every statement on its own line", func() {
+		spec := baseSpec()
+		spec.AdminPassword = "multi\nline\r\npassword"
+
+		sql, err := dbinit.Generate(spec)
+		Expect(err).NotTo(HaveOccurred())
+		for _, line := range strings.Split(strings.TrimSuffix(sql, "\n"), "\n") {
+			Expect(line).To(HaveSuffix(";"))
+		}
+		Expect(sql).To(ContainSubstring(`IDENTIFIED BY 'multi\nline\r\npassword';`))
+	})
+
+	It("escapes quotes and backslashes", func() {
+		spec := baseSpec()
+		spec.AdminUsername = "o'admin"
+		spec.AdminPassword = `pa'ss\' OR 1=1; --`
+
+		sql, err := dbinit.Generate(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sql).To(ContainSubstring(`ALTER USER 'o\'admin'@'localhost' IDENTIFIED BY 'pa\'ss\\\' OR 1=1; --';`))
+	})
+
+	It("creates the reconciler's auth_socket account after the managed accounts", func() {
+		spec := baseSpec()
+		spec.Reconciler = dbinit.Reconciler{Username: "pxc-reconciler"}
+
+		sql, err := dbinit.Generate(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sql).To(ContainSubstring("CREATE USER IF NOT EXISTS 'admin'@'::1' IDENTIFIED BY 'admin-password';\n"))
+		Expect(sql).To(ContainSubstring("GRANT ALL PRIVILEGES ON *.* TO 'admin'@'::1' WITH GRANT OPTION;\n" +
+			"CREATE USER IF NOT EXISTS 'pxc-reconciler'@'localhost' IDENTIFIED WITH auth_socket;\n" +
+			"ALTER USER 'pxc-reconciler'@'localhost' IDENTIFIED WITH auth_socket;\n" +
+			"GRANT ALL PRIVILEGES ON *.* TO 'pxc-reconciler'@'localhost' WITH GRANT OPTION;\n"))
+
+		plan, err := dbinit.PlanFor(spec)
+		Expect(err).NotTo(HaveOccurred())
+		for _, user := range plan.Users {
+			Expect(user.Account.Username).NotTo(Equal("pxc-reconciler"))
+		}
+	})
+
+	It("does not use the deprecated PASSWORD function", func() {
+		spec := baseSpec()
+		spec.Roadmin = dbinit.Roadmin{Enabled: true, Password: "roadmin-password"}
+
+		sql, err := dbinit.Generate(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sql).NotTo(ContainSubstring("PASSWORD("))
+		Expect(sql).NotTo(ContainSubstring("SET PASSWORD"))
+	})
+
+	DescribeTable("rejects invalid specs",
+		func(modify func(*dbinit.Spec), message string) {
+			spec := baseSpec()
+			modify(&spec)
+			_, err := dbinit.Generate(spec)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("without an admin username", func(s *dbinit.Spec) { s.AdminUsername = "" }, "admin_username is required"),
+		Entry("without an admin password", func(s *dbinit.Spec) { s.AdminPassword = "" }, "admin_password is required"),
+		Entry("removing the current admin", func(s *dbinit.Spec) { s.PreviousAdminUsername = "admin" },
+			"admin_username must not equal previous_admin_username"),
+		Entry("roadmin without a password", func(s *dbinit.Spec) { s.Roadmin.Enabled = true },
+			"roadmin_password is required when roadmin_enabled is true"),
+		Entry("roadmin as the admin", func(s *dbinit.Spec) {
+			s.AdminUsername = "roadmin"
+			s.Roadmin = dbinit.Roadmin{Enabled: true, Password: "p"}
+		}, "admin_username must not be roadmin when roadmin_enabled is true"),
+		Entry("a backup password without a username", func(s *dbinit.Spec) { s.Backup.Password = "p" },
+			"mysql_backup_username is required when mysql_backup_password is set"),
+		Entry("the admin as the reconciler", func(s *dbinit.Spec) { s.Reconciler.Username = "admin" },
+			"the reconciler account must not be admin, which is managed as another account"),
+		Entry("root as the reconciler", func(s *dbinit.Spec) { s.Reconciler.Username = "root" },
+			"the reconciler account must not be root, which is managed as another account"),
+		Entry("vcap as the reconciler", func(s *dbinit.Spec) { s.Reconciler.Username = "vcap" },
+			"the reconciler account must not be vcap, which every job process runs as"),
+	)
+})
+
+var _ = Describe("QuoteString", func() {
+	It("escapes characters that would end the literal or the line", func() {
+		Expect(dbinit.QuoteString("a'b\\c\x00d\ne\rf\x1ag")).To(Equal(`'a\'b\\c\0d\ne\rf\Zg'`))
+	})
+})
+
+func expectGolden(name, actual string) {
+	path := filepath.Join("testdata", name+".sql")
+	if *update {
+		Expect(ioutil.WriteFile(path, []byte(actual), 0644)).To(Succeed())
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("run the tests with -update to create %s", path))
+	Expect(actual).To(Equal(string(expected)))
+}
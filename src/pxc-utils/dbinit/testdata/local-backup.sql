@@ -0,0 +1,23 @@
+DELETE FROM mysql.user WHERE User='';
+DROP USER IF EXISTS 'root'@'localhost';
+DROP USER IF EXISTS 'root'@'127.0.0.1';
+DROP USER IF EXISTS 'root'@'::1';
+DROP USER IF EXISTS 'root'@'%';
+CREATE USER IF NOT EXISTS 'mysql-backup'@'localhost' IDENTIFIED BY 'backup-password';
+ALTER USER 'mysql-backup'@'localhost' IDENTIFIED BY 'backup-password';
+GRANT RELOAD, LOCK TABLES, REPLICATION CLIENT, PROCESS ON *.* TO 'mysql-backup'@'localhost';
+CREATE USER IF NOT EXISTS 'admin'@'localhost' IDENTIFIED BY 'admin-password';
+ALTER USER 'admin'@'localhost' IDENTIFIED BY 'admin-password';
+GRANT ALL PRIVILEGES ON *.* TO 'admin'@'localhost' WITH GRANT OPTION;
+CREATE USER IF NOT EXISTS 'admin'@'127.0.0.1' IDENTIFIED BY 'admin-password';
+ALTER USER 'admin'@'127.0.0.1' IDENTIFIED BY 'admin-password';
+GRANT ALL PRIVILEGES ON *.* TO 'admin'@'127.0.0.1' WITH GRANT OPTION;
+CREATE USER IF NOT EXISTS 'admin'@'::1' IDENTIFIED BY 'admin-password';
+ALTER USER 'admin'@'::1' IDENTIFIED BY 'admin-password';
+GRANT ALL PRIVILEGES ON *.* TO 'admin'@'::1' WITH GRANT OPTION;
+DROP USER IF EXISTS 'admin'@'%';
+DROP USER IF EXISTS 'roadmin'@'%';
+DROP USER IF EXISTS 'roadmin'@'localhost';
+DROP USER IF EXISTS 'roadmin'@'127.0.0.1';
+DROP USER IF EXISTS 'roadmin'@'::1';
+FLUSH PRIVILEGES;
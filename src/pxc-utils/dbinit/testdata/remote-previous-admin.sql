@@ -0,0 +1,20 @@
+DELETE FROM mysql.user WHERE User='';
+DROP USER IF EXISTS 'old-admin'@'%';
+DROP USER IF EXISTS 'old-admin'@'localhost';
+DROP USER IF EXISTS 'old-admin'@'127.0.0.1';
+DROP USER IF EXISTS 'old-admin'@'::1';
+DROP USER IF EXISTS 'root'@'%';
+DROP USER IF EXISTS 'root'@'localhost';
+DROP USER IF EXISTS 'root'@'127.0.0.1';
+DROP USER IF EXISTS 'root'@'::1';
+CREATE USER IF NOT EXISTS 'admin'@'%' IDENTIFIED BY 'admin-password';
+ALTER USER 'admin'@'%' IDENTIFIED BY 'admin-password';
+GRANT ALL PRIVILEGES ON *.* TO 'admin'@'%' WITH GRANT OPTION;
+DROP USER IF EXISTS 'admin'@'localhost';
+DROP USER IF EXISTS 'roadmin'@'localhost';
+DROP USER IF EXISTS 'admin'@'127.0.0.1';
+DROP USER IF EXISTS 'roadmin'@'127.0.0.1';
+DROP USER IF EXISTS 'admin'@'::1';
+DROP USER IF EXISTS 'roadmin'@'::1';
+DROP USER IF EXISTS 'roadmin'@'%';
+FLUSH PRIVILEGES;
@@ -0,0 +1,16 @@
+DELETE FROM mysql.user WHERE User='';
+CREATE USER IF NOT EXISTS 'root'@'localhost' IDENTIFIED BY 'admin-password';
+ALTER USER 'root'@'localhost' IDENTIFIED BY 'admin-password';
+GRANT ALL PRIVILEGES ON *.* TO 'root'@'localhost' WITH GRANT OPTION;
+CREATE USER IF NOT EXISTS 'root'@'127.0.0.1' IDENTIFIED BY 'admin-password';
+ALTER USER 'root'@'127.0.0.1' IDENTIFIED BY 'admin-password';
+GRANT ALL PRIVILEGES ON *.* TO 'root'@'127.0.0.1' WITH GRANT OPTION;
+CREATE USER IF NOT EXISTS 'root'@'::1' IDENTIFIED BY 'admin-password';
+ALTER USER 'root'@'::1' IDENTIFIED BY 'admin-password';
+GRANT ALL PRIVILEGES ON *.* TO 'root'@'::1' WITH GRANT OPTION;
+DROP USER IF EXISTS 'root'@'%';
+DROP USER IF EXISTS 'roadmin'@'%';
+DROP USER IF EXISTS 'roadmin'@'localhost';
+DROP USER IF EXISTS 'roadmin'@'127.0.0.1';
+DROP USER IF EXISTS 'roadmin'@'::1';
+FLUSH PRIVILEGES;
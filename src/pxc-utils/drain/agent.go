@@ -0,0 +1,82 @@
+package drain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// synced is the wsrep_local_state of a node that is part of the primary
+// component and up to date.
+const synced = 4
+
+// StatusFetcher asks a peer's galera-agent for the node's state.
+type StatusFetcher interface {
+	Status(address string) PeerStatus
+}
+
+// AgentStatusFetcher reads api/v1/status from galera-agent.
+type AgentStatusFetcher struct {
+	Port       int
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+// NewAgentStatusFetcher returns a fetcher whose requests time out after
+// timeout, so that a peer that has gone away cannot stall the drain.
+func NewAgentStatusFetcher(port int, username, password string, timeout time.Duration) *AgentStatusFetcher {
+	return &AgentStatusFetcher{
+		Port:       port,
+		Username:   username,
+		Password:   password,
+		HTTPClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type agentStatus struct {
+	WsrepLocalState        int    `json:"wsrep_local_state"`
+	WsrepLocalStateComment string `json:"wsrep_local_state_comment"`
+}
+
+// Status reports a node as synced only when its wsrep_local_state is
+// Synced; a donor that galera-agent considers healthy is still busy.
+func (f *AgentStatusFetcher) Status(address string) PeerStatus {
+	url := fmt.Sprintf("http://%s:%d/api/v1/status", address, f.Port)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return PeerStatus{Address: address, State: NotSynced, Detail: err.Error()}
+	}
+	if f.Username != "" {
+		req.SetBasicAuth(f.Username, f.Password)
+	}
+
+	resp, err := f.HTTPClient.Do(req)
+	if err != nil {
+		return PeerStatus{Address: address, State: Unreachable, Detail: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return PeerStatus{Address: address, State: Unreachable, Detail: err.Error()}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return PeerStatus{Address: address, State: NotSynced,
+			Detail: fmt.Sprintf("galera-agent returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))}
+	}
+
+	var status agentStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return PeerStatus{Address: address, State: NotSynced, Detail: fmt.Sprintf("invalid galera-agent status: %s", err)}
+	}
+
+	if status.WsrepLocalState != synced {
+		return PeerStatus{Address: address, State: NotSynced, Detail: status.WsrepLocalStateComment}
+	}
+	return PeerStatus{Address: address, State: Synced, Detail: status.WsrepLocalStateComment}
+}
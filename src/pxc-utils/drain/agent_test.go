@@ -0,0 +1,79 @@
+package drain_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/drain"
+)
+
+var _ = Describe("AgentStatusFetcher", func() {
+	var (
+		server     *httptest.Server
+		statusCode int
+		body       string
+		host       string
+		fetcher    *drain.AgentStatusFetcher
+	)
+
+	BeforeEach(func() {
+		statusCode = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.URL.Path).To(Equal("/api/v1/status"))
+			username, password, _ := r.BasicAuth()
+			Expect(username).To(Equal("galera-agent"))
+			Expect(password).To(Equal("secret"))
+			w.WriteHeader(statusCode)
+			w.Write([]byte(body))
+		}))
+
+		u, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		var portString string
+		host, portString = u.Hostname(), u.Port()
+		port, err := strconv.Atoi(portString)
+		Expect(err).NotTo(HaveOccurred())
+
+		fetcher = drain.NewAgentStatusFetcher(port, "galera-agent", "secret", time.Second)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("reports a synced node", func() {
+		body = `{"wsrep_local_state":4,"wsrep_local_state_comment":"Synced","wsrep_local_index":0,"healthy":true}`
+		Expect(fetcher.Status(host)).To(Equal(drain.PeerStatus{Address: host, State: drain.Synced, Detail: "Synced"}))
+	})
+
+	It("does not treat a healthy donor as synced", func() {
+		body = `{"wsrep_local_state":2,"wsrep_local_state_comment":"Donor/Desynced","wsrep_local_index":0,"healthy":true}`
+		Expect(fetcher.Status(host)).To(Equal(drain.PeerStatus{Address: host, State: drain.NotSynced, Detail: "Donor/Desynced"}))
+	})
+
+	It("reports unexpected responses as not synced", func() {
+		statusCode = http.StatusInternalServerError
+		body = "mysql is down"
+		Expect(fetcher.Status(host)).To(Equal(drain.PeerStatus{Address: host, State: drain.NotSynced,
+			Detail: "galera-agent returned HTTP 500: mysql is down"}))
+	})
+
+	It("reports invalid JSON as not synced", func() {
+		body = "not json"
+		status := fetcher.Status(host)
+		Expect(status.State).To(Equal(drain.NotSynced))
+		Expect(status.Detail).To(ContainSubstring("invalid galera-agent status"))
+	})
+
+	It("reports a node it cannot connect to as unreachable", func() {
+		server.Close()
+		Expect(fetcher.Status(host).State).To(Equal(drain.Unreachable))
+	})
+})
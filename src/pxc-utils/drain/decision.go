@@ -0,0 +1,131 @@
+// Package drain decides when a pxc-mysql node may be stopped by BOSH
+// without the rest of the cluster losing quorum.
+package drain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// NodeState is what a node's galera-agent reports about it.
+type NodeState string
+
+const (
+	Synced      NodeState = "synced"
+	NotSynced   NodeState = "not-synced"
+	Unreachable NodeState = "unreachable"
+)
+
+// PeerStatus is the state of one of the other nodes in the cluster.
+type PeerStatus struct {
+	Address string
+	State   NodeState
+	Detail  string
+}
+
+// JobState is the subset of BOSH_JOB_STATE and BOSH_JOB_NEXT_STATE that the
+// drain needs. PersistentDisk is nil when the state does not set it.
+type JobState struct {
+	PersistentDisk *int `json:"persistent_disk"`
+}
+
+// Change describes what BOSH is doing to this node.
+type Change struct {
+	Current JobState
+	Next    JobState
+}
+
+// ParseChange reads the JSON that BOSH passes to drain scripts in
+// BOSH_JOB_STATE and BOSH_JOB_NEXT_STATE. Empty values are treated as {}.
+func ParseChange(current, next string) (Change, error) {
+	var change Change
+	if err := parseJobState(current, &change.Current); err != nil {
+		return Change{}, fmt.Errorf("parsing BOSH_JOB_STATE: %s", err)
+	}
+	if err := parseJobState(next, &change.Next); err != nil {
+		return Change{}, fmt.Errorf("parsing BOSH_JOB_NEXT_STATE: %s", err)
+	}
+	return change, nil
+}
+
+func parseJobState(s string, state *JobState) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(s), state)
+}
+
+// Deleting is true when BOSH is removing this node from the deployment,
+// which it signals by taking away its persistent disk. BOSH passes an empty
+// BOSH_JOB_NEXT_STATE when it only stops the node, as for bosh stop, so the
+// next state must set persistent_disk to 0 explicitly.
+func (c Change) Deleting() bool {
+	current, next := c.Current.PersistentDisk, c.Next.PersistentDisk
+	return current != nil && *current > 0 && next != nil && *next == 0
+}
+
+// Decision is the outcome of Decide.
+type Decision struct {
+	Safe   bool
+	Reason string
+}
+
+// Decide reports whether this node can stop now.
+//
+// Every reachable peer must be synced, so that no peer is relying on this
+// node to finish a state transfer. The synced peers must also be a majority
+// of the peers that stay in the cluster. When this node is being deleted,
+// unreachable peers are taken to have been deleted earlier in the same
+// scale-down and do not count; otherwise an unreachable peer counts against
+// quorum, since it may only be cut off by the network.
+func Decide(peers []PeerStatus, change Change) Decision {
+	var (
+		synced      int
+		staying     int
+		notSynced   []string
+		unreachable []string
+	)
+
+	for _, peer := range peers {
+		switch peer.State {
+		case Synced:
+			synced++
+			staying++
+		case Unreachable:
+			unreachable = append(unreachable, peer.Address)
+			if !change.Deleting() {
+				staying++
+			}
+		default:
+			notSynced = append(notSynced, describe(peer))
+			staying++
+		}
+	}
+
+	if len(notSynced) > 0 {
+		return Decision{Reason: fmt.Sprintf("waiting for peers to sync: %s", strings.Join(notSynced, ", "))}
+	}
+
+	if staying > 0 && synced*2 <= staying {
+		return Decision{Reason: fmt.Sprintf("stopping would leave %d of %d peers synced, which is not a quorum; unreachable: %s",
+			synced, staying, strings.Join(unreachable, ", "))}
+	}
+
+	if len(unreachable) > 0 && change.Deleting() {
+		return Decision{Safe: true, Reason: fmt.Sprintf("%d of %d peers synced; assuming unreachable peers were deleted: %s",
+			synced, staying, strings.Join(unreachable, ", "))}
+	}
+	if len(unreachable) > 0 {
+		return Decision{Safe: true, Reason: fmt.Sprintf("%d of %d peers synced; unreachable: %s",
+			synced, staying, strings.Join(unreachable, ", "))}
+	}
+	return Decision{Safe: true, Reason: fmt.Sprintf("%d of %d peers synced", synced, staying)}
+}
+
+func describe(peer PeerStatus) string {
+	if peer.Detail == "" {
+		return peer.Address
+	}
+	return fmt.Sprintf("%s (%s)", peer.Address, peer.Detail)
+}
@@ -0,0 +1,103 @@
+package drain_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/drain"
+)
+
+func diskSize(mb int) *int {
+	return &mb
+}
+
+var _ = Describe("Decide", func() {
+	var (
+		restarting = drain.Change{Current: drain.JobState{PersistentDisk: diskSize(10240)}, Next: drain.JobState{PersistentDisk: diskSize(10240)}}
+		stopping   = drain.Change{Current: drain.JobState{PersistentDisk: diskSize(10240)}}
+		deleting   = drain.Change{Current: drain.JobState{PersistentDisk: diskSize(10240)}, Next: drain.JobState{PersistentDisk: diskSize(0)}}
+	)
+
+	peer := func(address string, state drain.NodeState) drain.PeerStatus {
+		return drain.PeerStatus{Address: address, State: state}
+	}
+
+	DescribeTable("whether it is safe to stop",
+		func(change drain.Change, peers []drain.PeerStatus, safe bool) {
+			Expect(drain.Decide(peers, change).Safe).To(Equal(safe))
+		},
+		Entry("a single node cluster", restarting, nil, true),
+		Entry("all peers synced", restarting,
+			[]drain.PeerStatus{peer("10.0.0.2", drain.Synced), peer("10.0.0.3", drain.Synced)}, true),
+		Entry("a two node cluster with its peer synced", restarting,
+			[]drain.PeerStatus{peer("10.0.0.2", drain.Synced)}, true),
+		Entry("a peer donating or joining", restarting,
+			[]drain.PeerStatus{peer("10.0.0.2", drain.Synced), peer("10.0.0.3", drain.NotSynced)}, false),
+		Entry("a peer unreachable in a three node cluster", restarting,
+			[]drain.PeerStatus{peer("10.0.0.2", drain.Synced), peer("10.0.0.3", drain.Unreachable)}, false),
+		Entry("a peer unreachable in a five node cluster", restarting,
+			[]drain.PeerStatus{peer("10.0.0.2", drain.Synced), peer("10.0.0.3", drain.Synced),
+				peer("10.0.0.4", drain.Synced), peer("10.0.0.5", drain.Unreachable)}, true),
+		Entry("peers already deleted during a scale-down", deleting,
+			[]drain.PeerStatus{peer("10.0.0.2", drain.Synced), peer("10.0.0.3", drain.Unreachable)}, true),
+		Entry("a peer unreachable while the node is stopped", stopping,
+			[]drain.PeerStatus{peer("10.0.0.2", drain.Synced), peer("10.0.0.3", drain.Unreachable)}, false),
+		Entry("a peer joining during a scale-down", deleting,
+			[]drain.PeerStatus{peer("10.0.0.2", drain.NotSynced), peer("10.0.0.3", drain.Unreachable)}, false),
+	)
+
+	It("explains what it is waiting for", func() {
+		decision := drain.Decide([]drain.PeerStatus{
+			{Address: "10.0.0.2", State: drain.NotSynced, Detail: "Donor/Desynced"},
+			{Address: "10.0.0.3", State: drain.Synced},
+		}, restarting)
+
+		Expect(decision.Reason).To(Equal("waiting for peers to sync: 10.0.0.2 (Donor/Desynced)"))
+	})
+
+	It("names unreachable peers when quorum would be lost", func() {
+		decision := drain.Decide([]drain.PeerStatus{
+			peer("10.0.0.2", drain.Unreachable),
+			peer("10.0.0.3", drain.Synced),
+		}, restarting)
+
+		Expect(decision.Reason).To(Equal("stopping would leave 1 of 2 peers synced, which is not a quorum; unreachable: 10.0.0.2"))
+	})
+})
+
+var _ = Describe("ParseChange", func() {
+	It("detects a node being deleted", func() {
+		change, err := drain.ParseChange(`{"persistent_disk":10240}`, `{"persistent_disk":0}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change.Deleting()).To(BeTrue())
+	})
+
+	It("does not treat an update as a deletion", func() {
+		change, err := drain.ParseChange(`{"persistent_disk":10240}`, `{"persistent_disk":20480}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change.Deleting()).To(BeFalse())
+	})
+
+	It("does not treat a stop, which has an empty next state, as a deletion", func() {
+		change, err := drain.ParseChange(`{"persistent_disk":10240}`, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change.Deleting()).To(BeFalse())
+
+		change, err = drain.ParseChange(`{"persistent_disk":10240}`, "{}")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change.Deleting()).To(BeFalse())
+	})
+
+	It("treats missing states as empty", func() {
+		change, err := drain.ParseChange("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change).To(Equal(drain.Change{}))
+		Expect(change.Deleting()).To(BeFalse())
+	})
+
+	It("returns an error for invalid JSON", func() {
+		_, err := drain.ParseChange("{", "")
+		Expect(err).To(MatchError(ContainSubstring("parsing BOSH_JOB_STATE")))
+	})
+})
@@ -0,0 +1,115 @@
+package drain
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Drainer runs one invocation of BOSH's dynamic drain protocol. BOSH calls
+// the drain script once, and then again with job_check_status for as long as
+// the script prints a negative number of seconds to wait.
+type Drainer struct {
+	// Self is this node's address; it is skipped when it appears in Peers.
+	Self  string
+	Peers []string
+
+	Fetcher StatusFetcher
+	Change  Change
+
+	// PollInterval is how long BOSH waits before asking again.
+	PollInterval time.Duration
+	// Timeout is how long the drain waits for the cluster to converge
+	// before it fails.
+	Timeout time.Duration
+	// StateFile records when the first invocation started.
+	StateFile string
+
+	Logger *log.Logger
+	Now    func() time.Time
+}
+
+// Drain returns the value to print for BOSH: 0 to stop the node now, or a
+// negative wait in seconds. It returns an error when the cluster has not
+// converged within Timeout; the drain must then fail so that BOSH does not
+// stop the node.
+func (d *Drainer) Drain(checkStatus bool) (int, error) {
+	started, err := d.started(checkStatus)
+	if err != nil {
+		return 0, err
+	}
+
+	var peers []PeerStatus
+	for _, address := range d.Peers {
+		if address == d.Self {
+			continue
+		}
+		peer := d.Fetcher.Status(address)
+		d.Logger.Printf("peer %s: %s %s", peer.Address, peer.State, peer.Detail)
+		peers = append(peers, peer)
+	}
+
+	decision := Decide(peers, d.Change)
+	if decision.Safe {
+		d.Logger.Printf("OK to drain: %s", decision.Reason)
+		d.clearState()
+		return 0, nil
+	}
+
+	waited := d.now().Sub(started)
+	if waited >= d.Timeout {
+		d.clearState()
+		return 0, fmt.Errorf("refusing to drain after waiting %s: %s", waited.Truncate(time.Second), decision.Reason)
+	}
+
+	wait := int(d.PollInterval / time.Second)
+	if wait < 1 {
+		wait = 1
+	}
+	d.Logger.Printf("not yet safe to drain, checking again in %ds: %s", wait, decision.Reason)
+	return -wait, nil
+}
+
+// started returns when this drain began. A first invocation starts the clock
+// afresh, so that a previous drain that was interrupted does not count.
+func (d *Drainer) started(checkStatus bool) (time.Time, error) {
+	if checkStatus {
+		data, err := ioutil.ReadFile(d.StateFile)
+		if err == nil {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+			if err == nil {
+				return time.Unix(seconds, 0), nil
+			}
+		}
+		d.Logger.Printf("no valid drain start time in %s; starting the clock now", d.StateFile)
+	}
+
+	now := d.now()
+	err := os.MkdirAll(filepath.Dir(d.StateFile), 0755)
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = ioutil.WriteFile(d.StateFile, []byte(strconv.FormatInt(now.Unix(), 10)), 0644)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now, nil
+}
+
+func (d *Drainer) clearState() {
+	if err := os.Remove(d.StateFile); err != nil && !os.IsNotExist(err) {
+		d.Logger.Printf("removing %s: %s", d.StateFile, err)
+	}
+}
+
+func (d *Drainer) now() time.Time {
+	if d.Now == nil {
+		return time.Now()
+	}
+	return d.Now()
+}
@@ -0,0 +1,123 @@
+package drain_test
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"pxc-utils/drain"
+)
+
+type stubFetcher struct {
+	states  map[string]drain.NodeState
+	queried []string
+}
+
+func (f *stubFetcher) Status(address string) drain.PeerStatus {
+	f.queried = append(f.queried, address)
+	return drain.PeerStatus{Address: address, State: f.states[address]}
+}
+
+var _ = Describe("Drainer", func() {
+	var (
+		tmpDir  string
+		now     time.Time
+		fetcher *stubFetcher
+		logs    *gbytes.Buffer
+		drainer *drain.Drainer
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "drain")
+		Expect(err).NotTo(HaveOccurred())
+
+		now = time.Unix(1500000000, 0)
+		fetcher = &stubFetcher{states: map[string]drain.NodeState{
+			"10.0.0.2": drain.Synced,
+			"10.0.0.3": drain.Synced,
+		}}
+		logs = gbytes.NewBuffer()
+
+		drainer = &drain.Drainer{
+			Self:         "10.0.0.1",
+			Peers:        []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+			Fetcher:      fetcher,
+			PollInterval: 10 * time.Second,
+			Timeout:      time.Minute,
+			StateFile:    filepath.Join(tmpDir, "state", "drain-started"),
+			Logger:       log.New(logs, "", 0),
+			Now:          func() time.Time { return now },
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("drains immediately when the cluster is healthy", func() {
+		Expect(drainer.Drain(false)).To(Equal(0))
+		Expect(fetcher.queried).To(Equal([]string{"10.0.0.2", "10.0.0.3"}))
+		Expect(logs).To(gbytes.Say("OK to drain: 2 of 2 peers synced"))
+		Expect(drainer.StateFile).NotTo(BeAnExistingFile())
+	})
+
+	Context("when a peer is not synced", func() {
+		BeforeEach(func() {
+			fetcher.states["10.0.0.3"] = drain.NotSynced
+		})
+
+		It("asks BOSH to check again after the poll interval", func() {
+			Expect(drainer.Drain(false)).To(Equal(-10))
+			Expect(drainer.StateFile).To(BeAnExistingFile())
+			Expect(logs).To(gbytes.Say("checking again in 10s: waiting for peers to sync: 10.0.0.3"))
+		})
+
+		It("drains once the peer has synced", func() {
+			Expect(drainer.Drain(false)).To(Equal(-10))
+
+			now = now.Add(30 * time.Second)
+			fetcher.states["10.0.0.3"] = drain.Synced
+			Expect(drainer.Drain(true)).To(Equal(0))
+			Expect(drainer.StateFile).NotTo(BeAnExistingFile())
+		})
+
+		It("refuses to drain once the timeout has passed since the first call", func() {
+			Expect(drainer.Drain(false)).To(Equal(-10))
+
+			now = now.Add(59 * time.Second)
+			Expect(drainer.Drain(true)).To(Equal(-10))
+
+			now = now.Add(time.Second)
+			_, err := drainer.Drain(true)
+			Expect(err).To(MatchError("refusing to drain after waiting 1m0s: waiting for peers to sync: 10.0.0.3"))
+			Expect(drainer.StateFile).NotTo(BeAnExistingFile())
+		})
+
+		It("restarts the clock on a new drain", func() {
+			Expect(drainer.Drain(false)).To(Equal(-10))
+
+			now = now.Add(time.Hour)
+			Expect(drainer.Drain(false)).To(Equal(-10))
+		})
+
+		It("starts the clock when a status check finds no state file", func() {
+			Expect(drainer.Drain(true)).To(Equal(-10))
+			Expect(logs).To(gbytes.Say("no valid drain start time"))
+			Expect(drainer.StateFile).To(BeAnExistingFile())
+		})
+	})
+
+	It("drains when the unreachable peers are being deleted with this node", func() {
+		fetcher.states["10.0.0.3"] = drain.Unreachable
+		drainer.Change = drain.Change{Current: drain.JobState{PersistentDisk: diskSize(1024)}, Next: drain.JobState{PersistentDisk: diskSize(0)}}
+
+		Expect(drainer.Drain(false)).To(Equal(0))
+	})
+})
@@ -0,0 +1,13 @@
+package mycnf_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMycnf(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mycnf Suite")
+}
@@ -0,0 +1,228 @@
+// Package mycnf checks a rendered MySQL option file before mysqld reads it,
+// so that a bad value fails pre-start with a clear message instead of
+// leaving mysqld unable to start.
+package mycnf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Option is the value of an option and where it was set.
+type Option struct {
+	Value string
+	// Source is the file and line that set the value, as "path:line".
+	Source string
+
+	order int
+}
+
+// OptionFile holds the options of a MySQL option file, keyed by section and
+// then by normalized option name. Later occurrences of an option override
+// earlier ones, as they do for mysqld.
+type OptionFile map[string]map[string]Option
+
+// Options are the options a program reads from the sections it uses.
+type Options map[string]Option
+
+// ParseOptionFile reads a MySQL option file, following !include and
+// !includedir directives.
+func ParseOptionFile(path string) (OptionFile, error) {
+	p := &parser{options: OptionFile{}, visited: map[string]bool{}}
+	err := p.parse(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.options, nil
+}
+
+// Merge returns the options of sections as a program that reads them sees
+// them: an option set in more than one of them takes the value that appears
+// last in the file.
+func (o OptionFile) Merge(sections ...string) Options {
+	merged := Options{}
+	for _, section := range sections {
+		for name, option := range o[section] {
+			if current, ok := merged[name]; !ok || option.order > current.order {
+				merged[name] = option
+			}
+		}
+	}
+	return merged
+}
+
+// Values returns the value of each option, without where it was set.
+func (o Options) Values() map[string]string {
+	values := map[string]string{}
+	for name, option := range o {
+		values[name] = option.Value
+	}
+	return values
+}
+
+// NormalizeOptionName converts an option name to its system variable form,
+// so that "pid-file" and "pid_file" are the same option.
+func NormalizeOptionName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.Replace(name, "-", "_", -1)
+	return strings.TrimPrefix(name, "loose_")
+}
+
+type parser struct {
+	options OptionFile
+	visited map[string]bool
+	order   int
+}
+
+func (p *parser) parse(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if p.visited[absPath] {
+		return fmt.Errorf("%s includes itself", path)
+	}
+	p.visited[absPath] = true
+	defer delete(p.visited, absPath)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "!includedir"):
+			dir := includePath(path, strings.TrimSpace(strings.TrimPrefix(line, "!includedir")))
+			if err := p.parseDir(dir); err != nil {
+				return fmt.Errorf("%s:%d: %s", path, lineNumber, err)
+			}
+		case strings.HasPrefix(line, "!include"):
+			included := includePath(path, strings.TrimSpace(strings.TrimPrefix(line, "!include")))
+			if err := p.parse(included); err != nil {
+				return fmt.Errorf("%s:%d: %s", path, lineNumber, err)
+			}
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("%s:%d: malformed section header %q", path, lineNumber, line)
+			}
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+		default:
+			if section == "" {
+				return fmt.Errorf("%s:%d: option %q appears before any section", path, lineNumber, line)
+			}
+			name, value := splitOption(line)
+			if p.options[section] == nil {
+				p.options[section] = map[string]Option{}
+			}
+			p.order++
+			p.options[section][NormalizeOptionName(name)] = Option{
+				Value:  value,
+				Source: fmt.Sprintf("%s:%d", path, lineNumber),
+				order:  p.order,
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (p *parser) parseDir(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".cnf") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := p.parse(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func includePath(includingFile, included string) string {
+	if filepath.IsAbs(included) {
+		return included
+	}
+	return filepath.Join(filepath.Dir(includingFile), included)
+}
+
+// splitOption splits "name = value" into its parts. Options without a value,
+// such as "quick", are boolean options and get the value "ON".
+func splitOption(line string) (name, value string) {
+	parts := strings.SplitN(line, "=", 2)
+	name = strings.TrimSpace(parts[0])
+	if len(parts) == 1 {
+		return name, "ON"
+	}
+
+	value = strings.TrimSpace(parts[1])
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return name, value[1 : end+1]
+		}
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return name, value
+}
+
+// ParseByteSize converts a size such as 256M, 1G or 1024MB into bytes.
+// Suffixes are case insensitive and use powers of 1024, as in MySQL option
+// files.
+func ParseByteSize(size string) (uint64, error) {
+	s := strings.TrimSpace(size)
+	if len(s) > 2 && strings.ContainsAny(s[len(s)-2:len(s)-1], "KMGTkmgt") && strings.ContainsAny(s[len(s)-1:], "Bb") {
+		s = s[:len(s)-1]
+	}
+	if s == "" {
+		return 0, errors.New("empty byte size")
+	}
+
+	multiplier := uint64(1)
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	case "T":
+		multiplier = 1 << 40
+	}
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n > math.MaxUint64/multiplier {
+		return 0, fmt.Errorf("invalid byte size %q", size)
+	}
+	return n * multiplier, nil
+}
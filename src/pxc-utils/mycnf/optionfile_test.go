@@ -0,0 +1,138 @@
+package mycnf_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/mycnf"
+)
+
+var _ = Describe("ParseOptionFile", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "mycnf")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeFile := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	It("follows includes, normalizes names and records where options were set", func() {
+		autoTune := writeFile("auto-tune.cnf", `
+[mysqld]
+innodb_buffer_pool_size = 4294967296
+max_connections = 100
+`)
+		myCnf := writeFile("my.cnf", `
+[client]
+port                            = 3306
+
+!include                        auto-tune.cnf
+
+[mysqld]
+wsrep_provider_options          = "gcache.size=512M;pc.recovery=FALSE"
+pid-file                        = /var/vcap/store/pxc-mysql/mysql.pid
+max_connections                 = 1500 # inline comment
+
+[mysqldump]
+quick
+`)
+
+		options, err := mycnf.ParseOptionFile(myCnf)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(options.Merge("mysqld").Values()).To(Equal(map[string]string{
+			"innodb_buffer_pool_size": "4294967296",
+			"wsrep_provider_options":  "gcache.size=512M;pc.recovery=FALSE",
+			"pid_file":                "/var/vcap/store/pxc-mysql/mysql.pid",
+			"max_connections":         "1500",
+		}))
+		Expect(options.Merge("mysqldump").Values()).To(Equal(map[string]string{"quick": "ON"}))
+
+		mysqld := options.Merge("mysqld")
+		Expect(mysqld["innodb_buffer_pool_size"].Source).To(Equal(autoTune + ":3"))
+		Expect(mysqld["max_connections"].Source).To(Equal(myCnf + ":10"))
+	})
+
+	It("merges sections in the order their options appear", func() {
+		myCnf := writeFile("my.cnf", `
+[galera]
+wsrep_on = OFF
+binlog_format = MIXED
+
+[mysqld]
+wsrep_on = ON
+
+[server]
+binlog_format = ROW
+`)
+
+		options, err := mycnf.ParseOptionFile(myCnf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.Merge(mycnf.MysqldSections...).Values()).To(Equal(map[string]string{
+			"wsrep_on":      "ON",
+			"binlog_format": "ROW",
+		}))
+	})
+
+	It("reads the .cnf files of an !includedir in order", func() {
+		Expect(os.Mkdir(filepath.Join(dir, "conf.d"), 0755)).To(Succeed())
+		writeFile("conf.d/b.cnf", "[mysqld]\nmax_connections = 2\n")
+		writeFile("conf.d/a.cnf", "[mysqld]\nmax_connections = 1\n")
+		writeFile("conf.d/ignored.txt", "[mysqld]\nmax_connections = 3\n")
+		myCnf := writeFile("my.cnf", "!includedir conf.d\n")
+
+		options, err := mycnf.ParseOptionFile(myCnf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.Merge("mysqld")["max_connections"].Value).To(Equal("2"))
+	})
+
+	It("reports where parsing failed", func() {
+		writeFile("auto-tune.cnf", "innodb_buffer_pool_size = 1G\n")
+		myCnf := writeFile("my.cnf", "[mysqld]\n!include auto-tune.cnf\n")
+
+		_, err := mycnf.ParseOptionFile(myCnf)
+		Expect(err).To(MatchError(ContainSubstring("my.cnf:2: ")))
+		Expect(err).To(MatchError(ContainSubstring(`auto-tune.cnf:1: option "innodb_buffer_pool_size = 1G" appears before any section`)))
+	})
+
+	It("rejects missing includes and include loops", func() {
+		myCnf := writeFile("my.cnf", "!include missing.cnf\n")
+		_, err := mycnf.ParseOptionFile(myCnf)
+		Expect(err).To(HaveOccurred())
+
+		myCnf = writeFile("my.cnf", "!include my.cnf\n")
+		_, err = mycnf.ParseOptionFile(myCnf)
+		Expect(err).To(MatchError(ContainSubstring("includes itself")))
+	})
+})
+
+var _ = Describe("ParseByteSize", func() {
+	It("accepts plain and suffixed sizes", func() {
+		Expect(mycnf.ParseByteSize("1024")).To(Equal(uint64(1024)))
+		Expect(mycnf.ParseByteSize("512M")).To(Equal(uint64(512 << 20)))
+		Expect(mycnf.ParseByteSize("2gb")).To(Equal(uint64(2 << 30)))
+	})
+
+	It("rejects invalid sizes", func() {
+		_, err := mycnf.ParseByteSize("lots")
+		Expect(err).To(MatchError(`invalid byte size "lots"`))
+		_, err = mycnf.ParseByteSize("-1G")
+		Expect(err).To(HaveOccurred())
+		_, err = mycnf.ParseByteSize("17179869184G")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,307 @@
+package mycnf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity is how bad a Problem is. Errors fail pre-start; warnings are
+// only reported.
+type Severity string
+
+const (
+	Error   Severity = "ERROR"
+	Warning Severity = "WARNING"
+)
+
+// Problem is an option that breaks a rule.
+type Problem struct {
+	Severity Severity
+	Option   string
+	// Source is where the option was set, or empty when it is missing.
+	Source  string
+	Message string
+}
+
+func (p Problem) String() string {
+	s := fmt.Sprintf("%-7s %s: %s", p.Severity, p.Option, p.Message)
+	if p.Source != "" {
+		s += fmt.Sprintf(" (%s)", p.Source)
+	}
+	return s
+}
+
+// Rule checks the options mysqld reads.
+type Rule interface {
+	Check(options Options) []Problem
+}
+
+// RuleFunc adapts a function to a Rule.
+type RuleFunc func(options Options) []Problem
+
+func (f RuleFunc) Check(options Options) []Problem { return f(options) }
+
+// MysqldSections are the sections mysqld reads.
+var MysqldSections = []string{"mysqld", "server", "mysqld-5.7", "galera"}
+
+// Rules returns the rules for a pxc-mysql node with totalMemory bytes of
+// memory.
+func Rules(totalMemory uint64) []Rule {
+	return []Rule{
+		Galera{
+			Require{Option: "binlog_format", Value: "ROW", Reason: "Galera only replicates row based events"},
+			Require{Option: "default_storage_engine", Value: "InnoDB", Reason: "Galera only replicates InnoDB tables"},
+			Require{Option: "innodb_autoinc_lock_mode", Value: "2", Reason: "Galera applies writesets with interleaved auto-increment locks"},
+			Require{Option: "innodb_doublewrite", Value: "1", Reason: "required by Galera"},
+			Require{Option: "query_cache_size", Value: "0", Reason: "the query cache is not supported with Galera"},
+			Require{Option: "query_cache_type", Value: "OFF", Reason: "the query cache is not supported with Galera"},
+			RuleFunc(checkClusterAddress),
+			RuleFunc(checkProviderOptions),
+			Conflict{
+				A:      Setting{Option: "pxc_strict_mode", Values: []string{"ENFORCING", "MASTER"}},
+				B:      Setting{Option: "wsrep_replicate_myisam", Values: []string{"ON"}},
+				Reason: "PXC strict mode refuses to replicate MyISAM tables",
+			},
+		},
+		Conflict{
+			A:      Setting{Option: "log_bin"},
+			B:      Setting{Option: "skip_log_bin", Values: []string{"ON"}},
+			Reason: "binary logging cannot be both enabled and disabled",
+		},
+		BufferPool{TotalMemory: totalMemory, WarnFraction: 0.9},
+	}
+}
+
+// Check runs rules against the options and returns the problems sorted by
+// severity, errors first.
+func Check(options Options, rules []Rule) []Problem {
+	var problems []Problem
+	for _, rule := range rules {
+		problems = append(problems, rule.Check(options)...)
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Severity == Error && problems[j].Severity != Error
+	})
+	return problems
+}
+
+// HasErrors reports whether any problem is an error.
+func HasErrors(problems []Problem) bool {
+	for _, problem := range problems {
+		if problem.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Galera applies its rules only when mysqld loads the Galera provider.
+type Galera []Rule
+
+func (g Galera) Check(options Options) []Problem {
+	provider, ok := options["wsrep_provider"]
+	if !ok || strings.EqualFold(provider.Value, "none") {
+		return nil
+	}
+
+	var problems []Problem
+	for _, rule := range g {
+		problems = append(problems, rule.Check(options)...)
+	}
+	return problems
+}
+
+// Require fails unless Option is set to Value. Boolean values are compared
+// by meaning, so ON, 1 and TRUE are the same.
+type Require struct {
+	Option string
+	Value  string
+	Reason string
+}
+
+func (r Require) Check(options Options) []Problem {
+	option, ok := options[r.Option]
+	if !ok {
+		return []Problem{{
+			Severity: Error,
+			Option:   r.Option,
+			Message:  fmt.Sprintf("must be set to %s: %s", r.Value, r.Reason),
+		}}
+	}
+	if normalizeValue(option.Value) != normalizeValue(r.Value) {
+		return []Problem{{
+			Severity: Error,
+			Option:   r.Option,
+			Source:   option.Source,
+			Message:  fmt.Sprintf("is %s, must be %s: %s", option.Value, r.Value, r.Reason),
+		}}
+	}
+	return nil
+}
+
+// Setting matches an option set to one of Values, or to any value when
+// Values is empty.
+type Setting struct {
+	Option string
+	Values []string
+}
+
+func (s Setting) match(options Options) (Option, bool) {
+	option, ok := options[s.Option]
+	if !ok {
+		return option, false
+	}
+	if len(s.Values) == 0 {
+		return option, true
+	}
+	for _, value := range s.Values {
+		if normalizeValue(option.Value) == normalizeValue(value) {
+			return option, true
+		}
+	}
+	return option, false
+}
+
+// Conflict fails when both settings match.
+type Conflict struct {
+	A      Setting
+	B      Setting
+	Reason string
+}
+
+func (c Conflict) Check(options Options) []Problem {
+	a, aMatches := c.A.match(options)
+	b, bMatches := c.B.match(options)
+	if !aMatches || !bMatches {
+		return nil
+	}
+	return []Problem{{
+		Severity: Error,
+		Option:   c.B.Option,
+		Source:   b.Source,
+		Message: fmt.Sprintf("%s cannot be combined with %s=%s (%s): %s",
+			b.Value, c.A.Option, a.Value, a.Source, c.Reason),
+	}}
+}
+
+// BufferPool fails when innodb_buffer_pool_size does not fit in memory, and
+// warns when it leaves less than 1-WarnFraction of it to everything else.
+type BufferPool struct {
+	TotalMemory  uint64
+	WarnFraction float64
+}
+
+func (b BufferPool) Check(options Options) []Problem {
+	option, ok := options["innodb_buffer_pool_size"]
+	if !ok {
+		return nil
+	}
+
+	size, err := ParseByteSize(option.Value)
+	if err != nil {
+		return []Problem{{Severity: Error, Option: "innodb_buffer_pool_size", Source: option.Source, Message: err.Error()}}
+	}
+
+	problem := Problem{Option: "innodb_buffer_pool_size", Source: option.Source}
+	switch {
+	case size >= b.TotalMemory:
+		problem.Severity = Error
+		problem.Message = fmt.Sprintf("%s is not less than the %s of memory on this VM; lower engine_config.innodb_buffer_pool_size or use a larger VM",
+			formatBytes(size), formatBytes(b.TotalMemory))
+	case float64(size) > b.WarnFraction*float64(b.TotalMemory):
+		problem.Severity = Warning
+		problem.Message = fmt.Sprintf("%s is more than %.0f%% of the %s of memory on this VM, which leaves little for connections and the OS",
+			formatBytes(size), b.WarnFraction*100, formatBytes(b.TotalMemory))
+	default:
+		return nil
+	}
+	return []Problem{problem}
+}
+
+// checkClusterAddress fails unless wsrep_cluster_address is a gcomm URL.
+func checkClusterAddress(options Options) []Problem {
+	option, ok := options["wsrep_cluster_address"]
+	if !ok {
+		return []Problem{{Severity: Error, Option: "wsrep_cluster_address", Message: "must be set for the node to join a cluster"}}
+	}
+	if !strings.HasPrefix(option.Value, "gcomm://") {
+		return []Problem{{
+			Severity: Error,
+			Option:   "wsrep_cluster_address",
+			Source:   option.Source,
+			Message:  fmt.Sprintf("is %q, must start with gcomm://", option.Value),
+		}}
+	}
+	return nil
+}
+
+// checkProviderOptions fails when wsrep_provider_options is not a list of
+// key=value pairs, sets a key twice, or enables socket.ssl without its
+// certificates.
+func checkProviderOptions(options Options) []Problem {
+	option, ok := options["wsrep_provider_options"]
+	if !ok {
+		return nil
+	}
+
+	problem := func(format string, args ...interface{}) []Problem {
+		return []Problem{{
+			Severity: Error,
+			Option:   "wsrep_provider_options",
+			Source:   option.Source,
+			Message:  fmt.Sprintf(format, args...),
+		}}
+	}
+
+	values := map[string]string{}
+	for _, pair := range strings.Split(option.Value, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return problem("%q is not a key=value pair", pair)
+		}
+		key := strings.TrimSpace(parts[0])
+		if _, ok := values[key]; ok {
+			return problem("sets %s more than once", key)
+		}
+		values[key] = strings.TrimSpace(parts[1])
+	}
+
+	if size, ok := values["gcache.size"]; ok {
+		if _, err := ParseByteSize(size); err != nil {
+			return problem("gcache.size: %s", err)
+		}
+	}
+
+	if normalizeValue(values["socket.ssl"]) == "ON" {
+		for _, key := range []string{"socket.ssl_ca", "socket.ssl_cert", "socket.ssl_key"} {
+			if values[key] == "" {
+				return problem("socket.ssl is enabled without %s", key)
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeValue(value string) string {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	switch value {
+	case "1", "TRUE", "YES", "ON":
+		return "ON"
+	case "0", "FALSE", "NO", "OFF":
+		return "OFF"
+	}
+	return value
+}
+
+func formatBytes(n uint64) string {
+	const gb = 1 << 30
+	if n >= gb {
+		return fmt.Sprintf("%.1fGB", float64(n)/gb)
+	}
+	return fmt.Sprintf("%dMB", n>>20)
+}
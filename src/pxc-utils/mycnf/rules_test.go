@@ -0,0 +1,131 @@
+package mycnf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/mycnf"
+)
+
+const gb = 1 << 30
+
+func optionsFrom(values map[string]string) mycnf.Options {
+	options := mycnf.Options{}
+	for name, value := range values {
+		options[name] = mycnf.Option{Value: value, Source: "my.cnf:1"}
+	}
+	return options
+}
+
+var _ = Describe("Rules", func() {
+	var values map[string]string
+
+	BeforeEach(func() {
+		values = map[string]string{
+			"wsrep_on":                 "ON",
+			"wsrep_provider":           "/var/vcap/packages/libgalera/lib/libgalera_smm.so",
+			"wsrep_provider_options":   "gcache.size=512M;pc.recovery=FALSE;socket.ssl=yes;socket.ssl_ca=ca.pem;socket.ssl_cert=cert.pem;socket.ssl_key=key.pem",
+			"wsrep_cluster_address":    "gcomm://10.0.0.1,10.0.0.2,10.0.0.3",
+			"pxc_strict_mode":          "MASTER",
+			"wsrep_replicate_myisam":   "OFF",
+			"binlog_format":            "ROW",
+			"default_storage_engine":   "InnoDB",
+			"innodb_autoinc_lock_mode": "2",
+			"innodb_doublewrite":       "1",
+			"query_cache_size":         "0",
+			"query_cache_type":         "OFF",
+			"log_bin":                  "mysql-bin",
+			"innodb_buffer_pool_size":  "4294967296",
+		}
+	})
+
+	check := func() []mycnf.Problem {
+		return mycnf.Check(optionsFrom(values), mycnf.Rules(16*gb))
+	}
+
+	It("accepts the configuration pxc-mysql renders", func() {
+		Expect(check()).To(BeEmpty())
+	})
+
+	It("requires the values Galera depends on", func() {
+		values["binlog_format"] = "MIXED"
+		delete(values, "innodb_autoinc_lock_mode")
+
+		problems := check()
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].String()).To(Equal("ERROR   binlog_format: is MIXED, must be ROW: Galera only replicates row based events (my.cnf:1)"))
+		Expect(problems[1].String()).To(Equal("ERROR   innodb_autoinc_lock_mode: must be set to 2: Galera applies writesets with interleaved auto-increment locks"))
+		Expect(mycnf.HasErrors(problems)).To(BeTrue())
+	})
+
+	It("compares booleans by meaning", func() {
+		values["innodb_doublewrite"] = "ON"
+		values["query_cache_type"] = "0"
+		Expect(check()).To(BeEmpty())
+	})
+
+	It("skips the Galera rules when the provider is not loaded", func() {
+		delete(values, "wsrep_provider")
+		values["binlog_format"] = "MIXED"
+		Expect(check()).To(BeEmpty())
+	})
+
+	DescribeTable("wsrep_provider_options and wsrep_cluster_address",
+		func(option, value, message string) {
+			values[option] = value
+			problems := check()
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].Option).To(Equal(option))
+			Expect(problems[0].Message).To(Equal(message))
+		},
+		Entry("a pair without a value", "wsrep_provider_options", "gcache.size=1G;pc.recovery", `"pc.recovery" is not a key=value pair`),
+		Entry("a repeated key", "wsrep_provider_options", "gcache.size=1G;gcache.size=2G", "sets gcache.size more than once"),
+		Entry("an invalid gcache.size", "wsrep_provider_options", "gcache.size=big", `gcache.size: invalid byte size "big"`),
+		Entry("ssl without a key", "wsrep_provider_options", "socket.ssl=yes;socket.ssl_ca=ca.pem;socket.ssl_cert=cert.pem", "socket.ssl is enabled without socket.ssl_key"),
+		Entry("a cluster address that is not gcomm", "wsrep_cluster_address", "10.0.0.1", `is "10.0.0.1", must start with gcomm://`),
+	)
+
+	It("rejects mutually exclusive options", func() {
+		values["wsrep_replicate_myisam"] = "ON"
+		values["skip_log_bin"] = "ON"
+
+		problems := check()
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Option).To(Equal("wsrep_replicate_myisam"))
+		Expect(problems[0].Message).To(Equal("ON cannot be combined with pxc_strict_mode=MASTER (my.cnf:1): PXC strict mode refuses to replicate MyISAM tables"))
+		Expect(problems[1].Option).To(Equal("skip_log_bin"))
+	})
+
+	Describe("innodb_buffer_pool_size", func() {
+		It("fails when it does not fit in memory", func() {
+			values["innodb_buffer_pool_size"] = "16G"
+			problems := check()
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].Severity).To(Equal(mycnf.Error))
+			Expect(problems[0].Message).To(Equal("16.0GB is not less than the 16.0GB of memory on this VM; lower engine_config.innodb_buffer_pool_size or use a larger VM"))
+		})
+
+		It("warns when it leaves little memory", func() {
+			values["innodb_buffer_pool_size"] = "15G"
+			problems := check()
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].Severity).To(Equal(mycnf.Warning))
+			Expect(mycnf.HasErrors(problems)).To(BeFalse())
+		})
+
+		It("fails when it is not a size", func() {
+			values["innodb_buffer_pool_size"] = "half"
+			Expect(check()[0].Message).To(Equal(`invalid byte size "half"`))
+		})
+	})
+
+	It("sorts errors before warnings", func() {
+		values["innodb_buffer_pool_size"] = "15G"
+		values["binlog_format"] = "STATEMENT"
+
+		problems := check()
+		Expect(problems[0].Severity).To(Equal(mycnf.Error))
+		Expect(problems[1].Severity).To(Equal(mycnf.Warning))
+	})
+})
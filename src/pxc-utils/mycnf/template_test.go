@@ -0,0 +1,86 @@
+package mycnf_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/mycnf"
+)
+
+const templatePath = "../../../jobs/pxc-mysql/templates/my.cnf.erb"
+
+var erbTag = regexp.MustCompile(`(?s)<%.*?%>`)
+
+// literalLines returns the option file lines of the template that do not
+// depend on properties: those without ERB tags, other than includes.
+func literalLines() []string {
+	template, err := ioutil.ReadFile(templatePath)
+	Expect(err).NotTo(HaveOccurred())
+
+	var lines []string
+	for _, line := range strings.Split(erbTag.ReplaceAllString(string(template), "\x00"), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, "\x00") || strings.HasPrefix(line, "!include") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+var _ = Describe("the pxc-mysql my.cnf template", func() {
+	It("sets the values Galera requires without depending on properties", func() {
+		dir, err := ioutil.TempDir("", "mycnf")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "my.cnf")
+		Expect(ioutil.WriteFile(path, []byte(strings.Join(literalLines(), "\n")), 0644)).To(Succeed())
+
+		options, err := mycnf.ParseOptionFile(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		var requirements []mycnf.Rule
+		for _, rule := range mycnf.Rules(16 * gb) {
+			if galera, ok := rule.(mycnf.Galera); ok {
+				for _, rule := range galera {
+					if _, ok := rule.(mycnf.Require); ok {
+						requirements = append(requirements, rule)
+					}
+				}
+			}
+		}
+		Expect(requirements).NotTo(BeEmpty())
+
+		Expect(mycnf.Check(options.Merge(mycnf.MysqldSections...), requirements)).To(BeEmpty())
+	})
+
+	Context("rendered with the default properties", func() {
+		It("contains every line of the template that does not depend on properties", func() {
+			rendered, err := ioutil.ReadFile("testdata/my.cnf")
+			Expect(err).NotTo(HaveOccurred())
+
+			renderedLines := map[string]bool{}
+			for _, line := range strings.Split(string(rendered), "\n") {
+				renderedLines[strings.TrimSpace(line)] = true
+			}
+
+			for _, line := range literalLines() {
+				Expect(renderedLines).To(HaveKey(line), "testdata/my.cnf is out of date with %s", templatePath)
+			}
+		})
+
+		It("passes every rule", func() {
+			options, err := mycnf.ParseOptionFile("testdata/my.cnf")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(mycnf.Check(options.Merge(mycnf.MysqldSections...), mycnf.Rules(16*gb))).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package prestart
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/cloudfoundry/gosigar"
+)
+
+// Sigar is the part of gosigar the disk checks use, as in
+// migrate-to-pxc/disk.
+type Sigar interface {
+	GetFileSystemUsage(string) (sigar.FileSystemUsage, error)
+}
+
+// FileSystem is the file system the steps change.
+type FileSystem interface {
+	Stat(path string) (os.FileInfo, error)
+	// Device returns the ID of the device that holds path, as
+	// mountpoint -d prints it.
+	Device(path string) (uint64, error)
+	IsEmptyDir(path string) (bool, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Chmod(path string, perm os.FileMode) error
+	// ChownR changes the owner of path and everything below it.
+	ChownR(path string, uid, gid int) error
+	Rename(oldPath, newPath string) error
+	WriteFile(path string, data []byte, perm os.FileMode) error
+}
+
+// Runner runs external programs.
+type Runner interface {
+	Run(env []string, name string, args ...string) error
+}
+
+// OSFileSystem is the real file system. Paths are relative to Root when it
+// is set, so that tests can work in a temporary directory.
+type OSFileSystem struct {
+	Root string
+}
+
+func (f OSFileSystem) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(f.path(path))
+}
+
+func (f OSFileSystem) Device(path string) (uint64, error) {
+	info, err := os.Stat(f.path(path))
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no device information for %s", path)
+	}
+	return uint64(stat.Dev), nil
+}
+
+func (f OSFileSystem) IsEmptyDir(path string) (bool, error) {
+	entries, err := ioutil.ReadDir(f.path(path))
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+func (f OSFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(f.path(path), perm)
+}
+
+func (f OSFileSystem) Chmod(path string, perm os.FileMode) error {
+	return os.Chmod(f.path(path), perm)
+}
+
+func (f OSFileSystem) ChownR(path string, uid, gid int) error {
+	return filepath.Walk(f.path(path), func(p string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(p, uid, gid)
+	})
+}
+
+func (f OSFileSystem) Rename(oldPath, newPath string) error {
+	return os.Rename(f.path(oldPath), f.path(newPath))
+}
+
+func (f OSFileSystem) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(f.path(path), data, perm)
+}
+
+func (f OSFileSystem) path(path string) string {
+	return filepath.Join(f.Root, path)
+}
+
+func exists(fs FileSystem, path string) (bool, error) {
+	_, err := fs.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
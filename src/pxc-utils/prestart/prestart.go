@@ -0,0 +1,48 @@
+// Package prestart prepares a pxc-mysql node's persistent disk before
+// galera-init starts. Each step checks the state it is responsible for and
+// only changes what is missing, so pre-start can be rerun after a failure.
+package prestart
+
+import (
+	"fmt"
+	"io"
+)
+
+// Step is one part of pre-start. Run returns a short description of what
+// it did, or why nothing needed doing.
+type Step interface {
+	Name() string
+	Run() (string, error)
+}
+
+// Outcome records one step of a run.
+type Outcome struct {
+	Step    string
+	Summary string
+	Err     error
+}
+
+// Run runs steps in order, stopping at the first failure. The outcomes of
+// the steps that ran are returned in either case.
+func Run(steps []Step) ([]Outcome, error) {
+	var outcomes []Outcome
+	for _, step := range steps {
+		summary, err := step.Run()
+		outcomes = append(outcomes, Outcome{Step: step.Name(), Summary: summary, Err: err})
+		if err != nil {
+			return outcomes, fmt.Errorf("%s: %s", step.Name(), err)
+		}
+	}
+	return outcomes, nil
+}
+
+// PrintSummary writes one line per outcome.
+func PrintSummary(w io.Writer, outcomes []Outcome) {
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			fmt.Fprintf(w, "%-24s FAILED: %s\n", outcome.Step, outcome.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%-24s %s\n", outcome.Step, outcome.Summary)
+	}
+}
@@ -0,0 +1,13 @@
+package prestart_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPrestart(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Prestart Suite")
+}
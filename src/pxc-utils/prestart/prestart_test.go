@@ -0,0 +1,63 @@
+package prestart_test
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/prestart"
+)
+
+type stubStep struct {
+	name    string
+	summary string
+	err     error
+	ran     bool
+}
+
+func (s *stubStep) Name() string { return s.name }
+
+func (s *stubStep) Run() (string, error) {
+	s.ran = true
+	return s.summary, s.err
+}
+
+var _ = Describe("Run", func() {
+	It("runs every step in order", func() {
+		first := &stubStep{name: "first", summary: "did one thing"}
+		second := &stubStep{name: "second", summary: "nothing to do"}
+
+		outcomes, err := prestart.Run([]prestart.Step{first, second})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes).To(Equal([]prestart.Outcome{
+			{Step: "first", Summary: "did one thing"},
+			{Step: "second", Summary: "nothing to do"},
+		}))
+	})
+
+	It("stops at the first failure", func() {
+		first := &stubStep{name: "first", err: errors.New("boom")}
+		second := &stubStep{name: "second"}
+
+		outcomes, err := prestart.Run([]prestart.Step{first, second})
+		Expect(err).To(MatchError("first: boom"))
+		Expect(outcomes).To(HaveLen(1))
+		Expect(second.ran).To(BeFalse())
+	})
+})
+
+var _ = Describe("PrintSummary", func() {
+	It("prints a line per step", func() {
+		var b bytes.Buffer
+		prestart.PrintSummary(&b, []prestart.Outcome{
+			{Step: "persistent-disk", Summary: "/var/vcap/store is mounted"},
+			{Step: "disk-capacity", Err: errors.New("too small")},
+		})
+
+		Expect(b.String()).To(Equal(
+			"persistent-disk          /var/vcap/store is mounted\n" +
+				"disk-capacity            FAILED: too small\n"))
+	})
+})
@@ -0,0 +1,295 @@
+package prestart
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config holds the paths and settings the pre-start steps use.
+type Config struct {
+	StoreDir string
+	// DataDir is the PXC data directory; LegacyDataDir is where releases
+	// before it was renamed kept it.
+	DataDir       string
+	LegacyDataDir string
+	// MinimumCapacityMB is the smallest persistent disk pxc-mysql accepts.
+	MinimumCapacityMB uint64
+
+	Mysqld       string
+	DefaultsFile string
+
+	// MigrateBinary migrates data from cf-mysql-release, which can only be
+	// done on a single node deployment.
+	MigrateBinary string
+	NodeCount     int
+	AdminUsername string
+	AdminPassword string
+	// CFMySQLDirs are the directories cf-mysql-release's mysqld needs while
+	// its data is migrated.
+	CFMySQLDirs []Dir
+
+	UID int
+	GID int
+}
+
+// Dir is a directory that must exist with the given mode.
+type Dir struct {
+	Path string
+	Mode os.FileMode
+}
+
+// DefaultConfig returns the paths of a pxc-mysql job.
+func DefaultConfig() Config {
+	return Config{
+		StoreDir:          "/var/vcap/store",
+		DataDir:           "/var/vcap/store/pxc-mysql",
+		LegacyDataDir:     "/var/vcap/store/mysql-clustered",
+		MinimumCapacityMB: 5000,
+		Mysqld:            "/var/vcap/packages/pxc/bin/mysqld",
+		DefaultsFile:      "/var/vcap/jobs/pxc-mysql/config/my.cnf",
+		MigrateBinary:     "/var/vcap/packages/migrate-to-pxc/bin/migrate-to-pxc",
+		CFMySQLDirs: []Dir{
+			{Path: "/var/vcap/data/mysql/files", Mode: 0750},
+			{Path: "/var/vcap/sys/log/mysql", Mode: 0755},
+			{Path: "/var/vcap/data/mysql/tmp", Mode: 0755},
+		},
+	}
+}
+
+// DatadirSteps check the persistent disk and make sure it holds an
+// initialized data directory. They run before galera-init starts.
+func DatadirSteps(config Config, fs FileSystem, sigar Sigar, runner Runner) []Step {
+	return []Step{
+		&PersistentDisk{FS: fs, StoreDir: config.StoreDir},
+		&DiskCapacity{Sigar: sigar, StoreDir: config.StoreDir, MinimumMB: config.MinimumCapacityMB},
+		&MoveDatadir{FS: fs, From: config.LegacyDataDir, To: config.DataDir},
+		&InitializeDatadir{FS: fs, Runner: runner, DataDir: config.DataDir, Mysqld: config.Mysqld,
+			DefaultsFile: config.DefaultsFile, UID: config.UID, GID: config.GID},
+	}
+}
+
+// MigrationSteps migrate a cf-mysql-release data directory into the running
+// node. They run once galera-init is up.
+func MigrationSteps(config Config, fs FileSystem, runner Runner) []Step {
+	return []Step{
+		&Migrate{FS: fs, Runner: runner, StoreDir: config.StoreDir, NodeCount: config.NodeCount,
+			Binary: config.MigrateBinary, Username: config.AdminUsername, Password: config.AdminPassword,
+			Dirs: config.CFMySQLDirs, UID: config.UID, GID: config.GID},
+	}
+}
+
+// PersistentDisk fails unless StoreDir is on a different device from the
+// root file system, so that data is never written to the ephemeral disk.
+type PersistentDisk struct {
+	FS       FileSystem
+	StoreDir string
+}
+
+func (s *PersistentDisk) Name() string { return "persistent-disk" }
+
+func (s *PersistentDisk) Run() (string, error) {
+	store, err := s.FS.Device(s.StoreDir)
+	if os.IsNotExist(err) {
+		return "", errors.New("Persistent disk not found")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	root, err := s.FS.Device("/")
+	if err != nil {
+		return "", err
+	}
+
+	if store == root {
+		return "", errors.New("Persistent disk not found")
+	}
+	return fmt.Sprintf("%s is mounted", s.StoreDir), nil
+}
+
+// DiskCapacity fails when the persistent disk is smaller than MinimumMB.
+type DiskCapacity struct {
+	Sigar     Sigar
+	StoreDir  string
+	MinimumMB uint64
+}
+
+func (s *DiskCapacity) Name() string { return "disk-capacity" }
+
+func (s *DiskCapacity) Run() (string, error) {
+	usage, err := s.Sigar.GetFileSystemUsage(s.StoreDir)
+	if err != nil {
+		return "", err
+	}
+
+	capacityMB := usage.Total / 1024
+	if capacityMB < s.MinimumMB {
+		return "", fmt.Errorf("Datadir capacity is %dMB, which is under the minimum required: %dMB", capacityMB, s.MinimumMB)
+	}
+	return fmt.Sprintf("%dMB, minimum %dMB", capacityMB, s.MinimumMB), nil
+}
+
+// MoveDatadir renames a data directory left at its old location.
+type MoveDatadir struct {
+	FS   FileSystem
+	From string
+	To   string
+}
+
+func (s *MoveDatadir) Name() string { return "move-datadir" }
+
+func (s *MoveDatadir) Run() (string, error) {
+	fromExists, err := exists(s.FS, s.From)
+	if err != nil {
+		return "", err
+	}
+	if !fromExists {
+		return "nothing to move", nil
+	}
+
+	toExists, err := exists(s.FS, s.To)
+	if err != nil {
+		return "", err
+	}
+	if toExists {
+		return "", fmt.Errorf("both %s and %s exist; move or remove one of them", s.From, s.To)
+	}
+
+	err = s.FS.Rename(s.From, s.To)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("moved %s to %s", s.From, s.To), nil
+}
+
+// InitializeDatadir runs mysqld --initialize when the data directory is
+// missing or empty, and makes sure vcap owns it.
+type InitializeDatadir struct {
+	FS           FileSystem
+	Runner       Runner
+	DataDir      string
+	Mysqld       string
+	DefaultsFile string
+	UID          int
+	GID          int
+}
+
+func (s *InitializeDatadir) Name() string { return "initialize-datadir" }
+
+func (s *InitializeDatadir) Run() (string, error) {
+	summary := "already initialized"
+
+	empty, err := s.FS.IsEmptyDir(s.DataDir)
+	if os.IsNotExist(err) {
+		empty = true
+		err = s.FS.MkdirAll(s.DataDir, 0755)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if empty {
+		err = s.Runner.Run(nil, s.Mysqld, "--defaults-file="+s.DefaultsFile, "--initialize")
+		if err != nil {
+			return "", fmt.Errorf("mysqld --initialize: %s", err)
+		}
+		summary = fmt.Sprintf("initialized %s", s.DataDir)
+	}
+
+	err = s.FS.ChownR(s.DataDir, s.UID, s.GID)
+	if err != nil {
+		return "", err
+	}
+	return summary, nil
+}
+
+// Migrate runs migrate-to-pxc when the store holds cf-mysql-release data
+// that has not been migrated. Afterwards the old data directory is kept as a
+// backup and replaced by an inaccessible one, so that cf-mysql-release can
+// never start again with an empty database.
+type Migrate struct {
+	FS        FileSystem
+	Runner    Runner
+	StoreDir  string
+	NodeCount int
+	Binary    string
+	Username  string
+	Password  string
+	Dirs      []Dir
+	UID       int
+	GID       int
+}
+
+func (s *Migrate) Name() string { return "migrate-to-pxc" }
+
+func (s *Migrate) Run() (string, error) {
+	legacy := filepath.Join(s.StoreDir, "mysql")
+	backup := filepath.Join(s.StoreDir, "mysql-migration-backup")
+	marker := filepath.Join(s.StoreDir, "migrated-successfully")
+
+	migrated, err := exists(s.FS, marker)
+	if err != nil || migrated {
+		return "already migrated", err
+	}
+
+	backedUp, err := exists(s.FS, backup)
+	if err != nil {
+		return "", err
+	}
+	if backedUp {
+		return "finished an interrupted migration", s.finish(legacy, marker)
+	}
+
+	legacyExists, err := exists(s.FS, legacy)
+	if err != nil || !legacyExists {
+		return "nothing to migrate", err
+	}
+
+	if s.NodeCount != 1 {
+		return "", errors.New("You must scale to 1 node before migrating to pxc")
+	}
+
+	for _, dir := range s.Dirs {
+		if err := s.ensureDir(dir); err != nil {
+			return "", err
+		}
+	}
+
+	env := append(os.Environ(), "MYSQL_USERNAME="+s.Username, "MYSQL_PASSWORD="+s.Password)
+	err = s.Runner.Run(env, s.Binary)
+	if err != nil {
+		return "", fmt.Errorf("migrate-to-pxc: %s", err)
+	}
+
+	err = s.FS.Rename(legacy, backup)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("migrated; cf-mysql data kept in %s", backup), s.finish(legacy, marker)
+}
+
+func (s *Migrate) finish(legacy, marker string) error {
+	err := s.FS.MkdirAll(legacy, 0000)
+	if err != nil {
+		return err
+	}
+	err = s.FS.Chmod(legacy, 0000)
+	if err != nil {
+		return err
+	}
+	return s.FS.WriteFile(marker, []byte("DO NOT DELETE THIS FILE; YOU WILL LOSE DATA\n"), 0644)
+}
+
+func (s *Migrate) ensureDir(dir Dir) error {
+	err := s.FS.MkdirAll(dir.Path, dir.Mode)
+	if err != nil {
+		return err
+	}
+	err = s.FS.Chmod(dir.Path, dir.Mode)
+	if err != nil {
+		return err
+	}
+	return s.FS.ChownR(dir.Path, s.UID, s.GID)
+}
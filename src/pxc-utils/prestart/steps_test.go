@@ -0,0 +1,276 @@
+package prestart_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/gosigar"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/prestart"
+)
+
+// fileSystem is a file system rooted in a temporary directory whose devices
+// are set by the test.
+type fileSystem struct {
+	prestart.OSFileSystem
+	devices map[string]uint64
+}
+
+func (f fileSystem) Device(path string) (uint64, error) {
+	if _, err := f.Stat(path); err != nil {
+		return 0, err
+	}
+	return f.devices[path], nil
+}
+
+type stubSigar struct {
+	usage sigar.FileSystemUsage
+	err   error
+	path  string
+}
+
+func (s *stubSigar) GetFileSystemUsage(path string) (sigar.FileSystemUsage, error) {
+	s.path = path
+	return s.usage, s.err
+}
+
+type command struct {
+	env  []string
+	name string
+	args []string
+}
+
+type stubRunner struct {
+	commands []command
+	err      error
+	// run simulates the command's side effects.
+	run func()
+}
+
+func (r *stubRunner) Run(env []string, name string, args ...string) error {
+	r.commands = append(r.commands, command{env: env, name: name, args: args})
+	if r.err != nil {
+		return r.err
+	}
+	if r.run != nil {
+		r.run()
+	}
+	return nil
+}
+
+var _ = Describe("Steps", func() {
+	var (
+		root   string
+		fs     fileSystem
+		runner *stubRunner
+		config prestart.Config
+	)
+
+	path := func(p string) string {
+		return filepath.Join(root, p)
+	}
+
+	mkdir := func(p string) {
+		Expect(os.MkdirAll(path(p), 0755)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "prestart")
+		Expect(err).NotTo(HaveOccurred())
+
+		fs = fileSystem{OSFileSystem: prestart.OSFileSystem{Root: root}, devices: map[string]uint64{"/": 1, "/var/vcap/store": 2}}
+		runner = &stubRunner{}
+
+		config = prestart.DefaultConfig()
+		config.UID = os.Getuid()
+		config.GID = os.Getgid()
+		config.NodeCount = 1
+		config.AdminUsername = "admin"
+		config.AdminPassword = "secret"
+		mkdir("/var/vcap/store")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	Describe("PersistentDisk", func() {
+		var step prestart.Step
+
+		BeforeEach(func() {
+			step = prestart.DatadirSteps(config, fs, nil, runner)[0]
+		})
+
+		It("passes when the store is a separate device", func() {
+			Expect(step.Run()).To(Equal("/var/vcap/store is mounted"))
+		})
+
+		It("fails when the store is on the root device", func() {
+			fs.devices["/var/vcap/store"] = 1
+			_, err := step.Run()
+			Expect(err).To(MatchError("Persistent disk not found"))
+		})
+
+		It("fails when there is no store", func() {
+			Expect(os.RemoveAll(path("/var/vcap/store"))).To(Succeed())
+			_, err := step.Run()
+			Expect(err).To(MatchError("Persistent disk not found"))
+		})
+	})
+
+	Describe("DiskCapacity", func() {
+		var sigar *stubSigar
+
+		BeforeEach(func() {
+			sigar = &stubSigar{}
+		})
+
+		run := func() (string, error) {
+			return prestart.DatadirSteps(config, fs, sigar, runner)[1].Run()
+		}
+
+		It("passes for disks of at least 5000MB", func() {
+			sigar.usage.Total = 5000 * 1024
+			Expect(run()).To(Equal("5000MB, minimum 5000MB"))
+			Expect(sigar.path).To(Equal("/var/vcap/store"))
+		})
+
+		It("fails for smaller disks", func() {
+			sigar.usage.Total = 4999 * 1024
+			_, err := run()
+			Expect(err).To(MatchError("Datadir capacity is 4999MB, which is under the minimum required: 5000MB"))
+		})
+
+		It("returns sigar errors", func() {
+			sigar.err = errors.New("statfs failed")
+			_, err := run()
+			Expect(err).To(MatchError("statfs failed"))
+		})
+	})
+
+	Describe("MoveDatadir", func() {
+		run := func() (string, error) {
+			return prestart.DatadirSteps(config, fs, nil, runner)[2].Run()
+		}
+
+		It("moves the legacy data directory", func() {
+			mkdir("/var/vcap/store/mysql-clustered/mysql")
+
+			Expect(run()).To(Equal("moved /var/vcap/store/mysql-clustered to /var/vcap/store/pxc-mysql"))
+			Expect(path("/var/vcap/store/pxc-mysql/mysql")).To(BeADirectory())
+			Expect(path("/var/vcap/store/mysql-clustered")).NotTo(BeAnExistingFile())
+
+			Expect(run()).To(Equal("nothing to move"))
+		})
+
+		It("refuses to move into an existing data directory", func() {
+			mkdir("/var/vcap/store/mysql-clustered")
+			mkdir("/var/vcap/store/pxc-mysql")
+
+			_, err := run()
+			Expect(err).To(MatchError(ContainSubstring("both /var/vcap/store/mysql-clustered and /var/vcap/store/pxc-mysql exist")))
+		})
+	})
+
+	Describe("InitializeDatadir", func() {
+		run := func() (string, error) {
+			return prestart.DatadirSteps(config, fs, nil, runner)[3].Run()
+		}
+
+		BeforeEach(func() {
+			runner.run = func() {
+				Expect(ioutil.WriteFile(path("/var/vcap/store/pxc-mysql/ibdata1"), nil, 0644)).To(Succeed())
+			}
+		})
+
+		It("initializes a missing data directory once", func() {
+			Expect(run()).To(Equal("initialized /var/vcap/store/pxc-mysql"))
+			Expect(runner.commands).To(Equal([]command{{
+				name: "/var/vcap/packages/pxc/bin/mysqld",
+				args: []string{"--defaults-file=/var/vcap/jobs/pxc-mysql/config/my.cnf", "--initialize"},
+			}}))
+
+			Expect(run()).To(Equal("already initialized"))
+			Expect(runner.commands).To(HaveLen(1))
+		})
+
+		It("initializes an empty data directory", func() {
+			mkdir("/var/vcap/store/pxc-mysql")
+			Expect(run()).To(Equal("initialized /var/vcap/store/pxc-mysql"))
+		})
+
+		It("returns mysqld errors", func() {
+			runner.err = errors.New("exit status 1")
+			_, err := run()
+			Expect(err).To(MatchError("mysqld --initialize: exit status 1"))
+		})
+	})
+
+	Describe("Migrate", func() {
+		run := func() (string, error) {
+			return prestart.MigrationSteps(config, fs, runner)[0].Run()
+		}
+
+		It("does nothing without cf-mysql data", func() {
+			Expect(run()).To(Equal("nothing to migrate"))
+			Expect(runner.commands).To(BeEmpty())
+		})
+
+		Context("with cf-mysql data", func() {
+			BeforeEach(func() {
+				mkdir("/var/vcap/store/mysql/cf_db")
+			})
+
+			It("migrates it and keeps a backup", func() {
+				Expect(run()).To(Equal("migrated; cf-mysql data kept in /var/vcap/store/mysql-migration-backup"))
+
+				Expect(runner.commands).To(HaveLen(1))
+				Expect(runner.commands[0].name).To(Equal("/var/vcap/packages/migrate-to-pxc/bin/migrate-to-pxc"))
+				Expect(strings.Join(runner.commands[0].env, "\n")).To(ContainSubstring("MYSQL_USERNAME=admin\nMYSQL_PASSWORD=secret"))
+
+				Expect(path("/var/vcap/data/mysql/files")).To(BeADirectory())
+				Expect(path("/var/vcap/sys/log/mysql")).To(BeADirectory())
+				Expect(path("/var/vcap/data/mysql/tmp")).To(BeADirectory())
+				Expect(path("/var/vcap/store/mysql-migration-backup/cf_db")).To(BeADirectory())
+
+				info, err := os.Stat(path("/var/vcap/store/mysql"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0000)))
+				Expect(ioutil.ReadFile(path("/var/vcap/store/migrated-successfully"))).To(ContainSubstring("DO NOT DELETE THIS FILE"))
+
+				Expect(run()).To(Equal("already migrated"))
+				Expect(runner.commands).To(HaveLen(1))
+			})
+
+			It("refuses to migrate a cluster", func() {
+				config.NodeCount = 3
+				_, err := run()
+				Expect(err).To(MatchError("You must scale to 1 node before migrating to pxc"))
+				Expect(runner.commands).To(BeEmpty())
+			})
+
+			It("keeps the data in place when the migration fails", func() {
+				runner.err = errors.New("exit status 1")
+				_, err := run()
+				Expect(err).To(MatchError("migrate-to-pxc: exit status 1"))
+				Expect(path("/var/vcap/store/mysql/cf_db")).To(BeADirectory())
+				Expect(path("/var/vcap/store/migrated-successfully")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		It("finishes a migration that stopped after the backup was made", func() {
+			mkdir("/var/vcap/store/mysql-migration-backup/cf_db")
+
+			Expect(run()).To(Equal("finished an interrupted migration"))
+			Expect(runner.commands).To(BeEmpty())
+			Expect(path("/var/vcap/store/mysql")).To(BeADirectory())
+			Expect(path("/var/vcap/store/migrated-successfully")).To(BeAnExistingFile())
+		})
+	})
+})
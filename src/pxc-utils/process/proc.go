@@ -0,0 +1,55 @@
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ProcTable reads the process table from /proc.
+type ProcTable struct {
+	// Dir is the proc filesystem; it defaults to /proc.
+	Dir string
+}
+
+// Running is false for pids that do not exist and for zombies, which have
+// exited but not yet been reaped.
+func (t ProcTable) Running(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	if err != nil && err != syscall.EPERM {
+		return false
+	}
+
+	stat, err := ioutil.ReadFile(filepath.Join(t.dir(), strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+
+	// The state follows the command name, which is in parentheses and may
+	// itself contain spaces or parentheses.
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+func (t ProcTable) Executable(pid int) (string, error) {
+	path, err := os.Readlink(filepath.Join(t.dir(), strconv.Itoa(pid), "exe"))
+	if err != nil {
+		return "", fmt.Errorf("reading executable: %s", err)
+	}
+	return path, nil
+}
+
+func (t ProcTable) Signal(pid int, signal syscall.Signal) error {
+	return syscall.Kill(pid, signal)
+}
+
+func (t ProcTable) dir() string {
+	if t.Dir == "" {
+		return "/proc"
+	}
+	return t.Dir
+}
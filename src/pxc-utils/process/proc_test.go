@@ -0,0 +1,69 @@
+package process_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/process"
+)
+
+var _ = Describe("ProcTable", func() {
+	var cmd *exec.Cmd
+
+	BeforeEach(func() {
+		if _, err := os.Stat("/proc/self/exe"); err != nil {
+			Skip("requires /proc")
+		}
+
+		cmd = exec.Command("sleep", "60")
+		Expect(cmd.Start()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	It("reads the executable of a running process", func() {
+		table := process.ProcTable{}
+		Expect(table.Running(cmd.Process.Pid)).To(BeTrue())
+
+		executable, err := table.Executable(cmd.Process.Pid)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Base(executable)).To(Equal("sleep"))
+	})
+
+	It("does not treat an unreaped process as running", func() {
+		table := process.ProcTable{}
+		Expect(table.Signal(cmd.Process.Pid, syscall.SIGKILL)).To(Succeed())
+		Eventually(func() bool { return table.Running(cmd.Process.Pid) }).Should(BeFalse())
+	})
+
+	It("stops a real process from its pid file", func() {
+		tmpDir, err := ioutil.TempDir("", "process")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+
+		pidFile := filepath.Join(tmpDir, "sleep.pid")
+		Expect(ioutil.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0644)).To(Succeed())
+
+		stopper := &process.Stopper{
+			Table:        process.ProcTable{},
+			Stages:       []process.Stage{{Signal: syscall.SIGTERM, Timeout: 5 * time.Second}},
+			Executable:   "sleep",
+			PollInterval: 10 * time.Millisecond,
+		}
+
+		result := stopper.Stop(pidFile)
+		Expect(result.Outcome).To(Equal(process.Stopped))
+		Expect(pidFile).NotTo(BeAnExistingFile())
+	})
+})
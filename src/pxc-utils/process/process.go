@@ -0,0 +1,198 @@
+// Package process stops the processes that BOSH jobs record in pid files,
+// escalating through signals and reporting what happened.
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Table is the view of the process table that a Stopper needs.
+type Table interface {
+	Running(pid int) bool
+	// Executable returns the path of the program pid is running.
+	Executable(pid int) (string, error)
+	Signal(pid int, signal syscall.Signal) error
+}
+
+// Stage sends Signal and then waits up to Timeout for the process to exit.
+type Stage struct {
+	Signal  syscall.Signal
+	Timeout time.Duration
+}
+
+// Outcome says how a Stop ended.
+type Outcome string
+
+const (
+	// NoPIDFile means there was nothing to stop.
+	NoPIDFile Outcome = "no-pidfile"
+	// NotRunning means the pid file was stale.
+	NotRunning Outcome = "not-running"
+	// PIDReused means the pid now belongs to another program, which was
+	// left alone.
+	PIDReused Outcome = "pid-reused"
+	Stopped   Outcome = "stopped"
+	// TimedOut means the process survived every stage.
+	TimedOut Outcome = "timed-out"
+)
+
+// Result describes a Stop.
+type Result struct {
+	PIDFile    string        `json:"pidfile"`
+	PID        int           `json:"pid,omitempty"`
+	Executable string        `json:"executable,omitempty"`
+	Outcome    Outcome       `json:"outcome"`
+	Signals    []string      `json:"signals,omitempty"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Succeeded is true unless the process is still running or could not be
+// stopped.
+func (r Result) Succeeded() bool {
+	return r.Outcome != TimedOut && r.Error == ""
+}
+
+// Stopper stops the process recorded in a pid file.
+type Stopper struct {
+	Table  Table
+	Stages []Stage
+	// Executable is the base name of the program the pid file is for, such
+	// as "galera-init". When it is set, a process running anything else is
+	// never signalled.
+	Executable string
+	// PollInterval is how often a stage checks whether the process exited.
+	PollInterval time.Duration
+}
+
+// Stop signals the process in pidFile through each stage until it exits,
+// and removes the pid file once the process is gone. The executable is
+// checked again before every signal, in case the process exited and its
+// pid was reused while waiting.
+func (s *Stopper) Stop(pidFile string) Result {
+	start := time.Now()
+	result := s.stop(pidFile)
+	result.PIDFile = pidFile
+	result.Elapsed = time.Since(start)
+	return result
+}
+
+func (s *Stopper) stop(pidFile string) Result {
+	pid, err := ReadPIDFile(pidFile)
+	if os.IsNotExist(err) {
+		return Result{Outcome: NoPIDFile}
+	}
+	if err != nil {
+		return Result{Error: err.Error()}
+	}
+
+	result := Result{PID: pid}
+	for _, stage := range s.Stages {
+		if !s.Table.Running(pid) {
+			return s.finish(pidFile, result, s.outcome(result))
+		}
+
+		executable, err := s.Table.Executable(pid)
+		if err != nil {
+			if !s.Table.Running(pid) {
+				return s.finish(pidFile, result, s.outcome(result))
+			}
+			result.Error = fmt.Sprintf("reading the executable of pid %d: %s", pid, err)
+			return result
+		}
+		result.Executable = executable
+		if !s.matches(executable) {
+			return s.finish(pidFile, result, PIDReused)
+		}
+
+		err = s.Table.Signal(pid, stage.Signal)
+		if err != nil && err != syscall.ESRCH {
+			result.Error = fmt.Sprintf("sending %s to pid %d: %s", signalName(stage.Signal), pid, err)
+			return result
+		}
+		result.Signals = append(result.Signals, signalName(stage.Signal))
+
+		if s.waitForExit(pid, stage.Timeout) {
+			return s.finish(pidFile, result, Stopped)
+		}
+	}
+
+	if !s.Table.Running(pid) {
+		return s.finish(pidFile, result, s.outcome(result))
+	}
+	result.Outcome = TimedOut
+	return result
+}
+
+// outcome is NotRunning if the process was gone before it was signalled.
+func (s *Stopper) outcome(result Result) Outcome {
+	if len(result.Signals) == 0 {
+		return NotRunning
+	}
+	return Stopped
+}
+
+func (s *Stopper) finish(pidFile string, result Result, outcome Outcome) Result {
+	result.Outcome = outcome
+	if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (s *Stopper) matches(executable string) bool {
+	if s.Executable == "" {
+		return true
+	}
+	executable = strings.TrimSuffix(executable, " (deleted)")
+	return filepath.Base(executable) == filepath.Base(s.Executable)
+}
+
+func (s *Stopper) waitForExit(pid int, timeout time.Duration) bool {
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if !s.Table.Running(pid) {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(interval)
+	}
+}
+
+// ReadPIDFile returns the pid on the first line of path.
+func ReadPIDFile(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	line := strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+	pid, err := strconv.Atoi(line)
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("unable to get pid from %s", path)
+	}
+	return pid, nil
+}
+
+func signalName(signal syscall.Signal) string {
+	for name, s := range signals {
+		if s == signal {
+			return name
+		}
+	}
+	return strconv.Itoa(int(signal))
+}
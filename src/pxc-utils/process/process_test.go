@@ -0,0 +1,208 @@
+package process_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pxc-utils/process"
+)
+
+// fakeTable is a process table with one process that exits after receiving
+// one of exitOn.
+type fakeTable struct {
+	pid        int
+	running    bool
+	executable string
+	exitOn     map[syscall.Signal]bool
+	signalErr  error
+	signals    []syscall.Signal
+	// onCheck runs every time the executable is read.
+	onCheck func()
+}
+
+func (t *fakeTable) Running(pid int) bool {
+	return pid == t.pid && t.running
+}
+
+func (t *fakeTable) Executable(pid int) (string, error) {
+	if t.onCheck != nil {
+		t.onCheck()
+	}
+	if pid != t.pid || !t.running {
+		return "", errors.New("no such process")
+	}
+	return t.executable, nil
+}
+
+func (t *fakeTable) Signal(pid int, signal syscall.Signal) error {
+	if t.signalErr != nil {
+		return t.signalErr
+	}
+	t.signals = append(t.signals, signal)
+	if t.exitOn[signal] {
+		t.running = false
+	}
+	return nil
+}
+
+var _ = Describe("Stopper", func() {
+	var (
+		tmpDir  string
+		pidFile string
+		table   *fakeTable
+		stopper *process.Stopper
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "process")
+		Expect(err).NotTo(HaveOccurred())
+
+		pidFile = filepath.Join(tmpDir, "galera-init.pid")
+		Expect(ioutil.WriteFile(pidFile, []byte("1234\n"), 0644)).To(Succeed())
+
+		table = &fakeTable{
+			pid:        1234,
+			running:    true,
+			executable: "/var/vcap/data/packages/galera-init/abc/bin/galera-init",
+			exitOn:     map[syscall.Signal]bool{syscall.SIGTERM: true},
+		}
+		stopper = &process.Stopper{
+			Table:        table,
+			Stages:       []process.Stage{{Signal: syscall.SIGTERM, Timeout: 20 * time.Millisecond}, {Signal: syscall.SIGKILL, Timeout: 20 * time.Millisecond}},
+			Executable:   "galera-init",
+			PollInterval: time.Millisecond,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("stops the process with the first signal that works and removes the pid file", func() {
+		result := stopper.Stop(pidFile)
+
+		Expect(result.Outcome).To(Equal(process.Stopped))
+		Expect(result.PID).To(Equal(1234))
+		Expect(result.Signals).To(Equal([]string{"TERM"}))
+		Expect(result.Succeeded()).To(BeTrue())
+		Expect(table.signals).To(Equal([]syscall.Signal{syscall.SIGTERM}))
+		Expect(pidFile).NotTo(BeAnExistingFile())
+	})
+
+	It("escalates when the process survives a stage", func() {
+		table.exitOn = map[syscall.Signal]bool{syscall.SIGKILL: true}
+
+		result := stopper.Stop(pidFile)
+
+		Expect(result.Outcome).To(Equal(process.Stopped))
+		Expect(result.Signals).To(Equal([]string{"TERM", "KILL"}))
+	})
+
+	It("times out when the process survives every stage", func() {
+		table.exitOn = nil
+
+		result := stopper.Stop(pidFile)
+
+		Expect(result.Outcome).To(Equal(process.TimedOut))
+		Expect(result.Succeeded()).To(BeFalse())
+		Expect(pidFile).To(BeAnExistingFile())
+	})
+
+	It("succeeds when there is no pid file", func() {
+		result := stopper.Stop(filepath.Join(tmpDir, "missing.pid"))
+
+		Expect(result.Outcome).To(Equal(process.NoPIDFile))
+		Expect(result.Succeeded()).To(BeTrue())
+	})
+
+	It("removes a stale pid file without signalling", func() {
+		table.running = false
+
+		result := stopper.Stop(pidFile)
+
+		Expect(result.Outcome).To(Equal(process.NotRunning))
+		Expect(table.signals).To(BeEmpty())
+		Expect(pidFile).NotTo(BeAnExistingFile())
+	})
+
+	It("fails on a pid file without a pid", func() {
+		Expect(ioutil.WriteFile(pidFile, []byte("\n"), 0644)).To(Succeed())
+
+		result := stopper.Stop(pidFile)
+
+		Expect(result.Succeeded()).To(BeFalse())
+		Expect(result.Error).To(Equal("unable to get pid from " + pidFile))
+	})
+
+	It("never signals a process running another program", func() {
+		table.executable = "/usr/sbin/sshd"
+
+		result := stopper.Stop(pidFile)
+
+		Expect(result.Outcome).To(Equal(process.PIDReused))
+		Expect(result.Executable).To(Equal("/usr/sbin/sshd"))
+		Expect(result.Succeeded()).To(BeTrue())
+		Expect(table.signals).To(BeEmpty())
+		Expect(pidFile).NotTo(BeAnExistingFile())
+	})
+
+	It("checks the program again before escalating", func() {
+		table.exitOn = nil
+		checks := 0
+		table.onCheck = func() {
+			checks++
+			if checks == 2 {
+				table.executable = "/usr/sbin/sshd"
+			}
+		}
+
+		result := stopper.Stop(pidFile)
+
+		Expect(result.Outcome).To(Equal(process.PIDReused))
+		Expect(table.signals).To(Equal([]syscall.Signal{syscall.SIGTERM}))
+	})
+
+	It("recognises a program whose package was replaced while it ran", func() {
+		table.executable = "/var/vcap/data/packages/galera-init/abc/bin/galera-init (deleted)"
+
+		Expect(stopper.Stop(pidFile).Outcome).To(Equal(process.Stopped))
+	})
+
+	It("reports signalling errors", func() {
+		table.signalErr = syscall.EPERM
+
+		result := stopper.Stop(pidFile)
+
+		Expect(result.Succeeded()).To(BeFalse())
+		Expect(result.Error).To(Equal("sending TERM to pid 1234: operation not permitted"))
+	})
+})
+
+var _ = Describe("ParseStages", func() {
+	It("parses signals and timeouts", func() {
+		stages, err := process.ParseStages("TERM:25s, SIGKILL:500ms")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stages).To(Equal([]process.Stage{
+			{Signal: syscall.SIGTERM, Timeout: 25 * time.Second},
+			{Signal: syscall.SIGKILL, Timeout: 500 * time.Millisecond},
+		}))
+	})
+
+	It("rejects unknown signals", func() {
+		_, err := process.ParseStages("HUP:1s")
+		Expect(err).To(MatchError(`invalid stage "HUP:1s": unknown signal HUP`))
+	})
+
+	It("rejects stages without a timeout", func() {
+		_, err := process.ParseStages("TERM")
+		Expect(err).To(MatchError(`invalid stage "TERM": expected SIGNAL:TIMEOUT`))
+	})
+})
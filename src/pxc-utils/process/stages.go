@@ -0,0 +1,40 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var signals = map[string]syscall.Signal{
+	"TERM": syscall.SIGTERM,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+}
+
+// ParseStages parses a comma separated list of SIGNAL:TIMEOUT pairs, such
+// as "TERM:25s,KILL:5s".
+func ParseStages(s string) ([]Stage, error) {
+	var stages []Stage
+	for _, field := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(field), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid stage %q: expected SIGNAL:TIMEOUT", field)
+		}
+
+		signal, found := signals[strings.TrimPrefix(strings.ToUpper(parts[0]), "SIG")]
+		if !found {
+			return nil, fmt.Errorf("invalid stage %q: unknown signal %s", field, parts[0])
+		}
+
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid stage %q: %s", field, err)
+		}
+
+		stages = append(stages, Stage{Signal: signal, Timeout: timeout})
+	}
+	return stages, nil
+}
@@ -0,0 +1,336 @@
+// Package reconciler keeps the accounts and seeded databases that pxc-mysql
+// declares in place while mysqld runs. The init_file only applies them at
+// startup, so an account that is changed or dropped at runtime would
+// otherwise stay that way until the next restart.
+package reconciler
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"pxc-utils/dbinit"
+)
+
+// SeededDatabase is an entry of the seeded_databases property. Its user may
+// connect from any host and has every privilege on the database.
+type SeededDatabase struct {
+	Name     string `yaml:"name"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Privileges are the *_priv columns of a grant table row.
+type Privileges struct {
+	// Columns lists every privilege column of the table.
+	Columns []string
+	// Granted holds the columns that are 'Y'.
+	Granted map[string]bool
+}
+
+// AccountState is an account's row in mysql.user.
+type AccountState struct {
+	Exists               bool
+	Plugin               string
+	AuthenticationString string
+	Privileges           Privileges
+}
+
+// Store reads and changes the server's accounts.
+type Store interface {
+	// Synced is false while the node is not a synced member of the cluster,
+	// when changes could conflict with the state it is receiving.
+	Synced() (bool, error)
+	Account(account dbinit.Account) (AccountState, error)
+	AnonymousAccounts() ([]dbinit.Account, error)
+	DatabaseExists(name string) (bool, error)
+	// DatabasePrivileges returns the account's mysql.db row for database.
+	DatabasePrivileges(account dbinit.Account, database string) (Privileges, error)
+	Exec(statement string) error
+}
+
+// Drift is a difference between the declared and actual state, and the
+// statements that repair it.
+type Drift struct {
+	Subject    string
+	Problem    string
+	Statements []string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s: %s", d.Subject, d.Problem)
+}
+
+// Reconciler compares a Store with the declared accounts and databases.
+type Reconciler struct {
+	Store     Store
+	Accounts  dbinit.Spec
+	Databases []SeededDatabase
+	// Repair applies the statements for every drift found; otherwise drift
+	// is only reported.
+	Repair bool
+	Logger *log.Logger
+}
+
+// privilegeColumns maps the privileges dbinit grants to mysql.user columns.
+var privilegeColumns = map[string]string{
+	"SELECT":             "Select_priv",
+	"PROCESS":            "Process_priv",
+	"REPLICATION CLIENT": "Repl_client_priv",
+	"RELOAD":             "Reload_priv",
+	"LOCK TABLES":        "Lock_tables_priv",
+}
+
+const grantColumn = "Grant_priv"
+
+// Reconcile finds drift and, when Repair is set, repairs it. Every drift is
+// logged without the statements, which may contain passwords.
+func (r *Reconciler) Reconcile() ([]Drift, error) {
+	synced, err := r.Store.Synced()
+	if err != nil {
+		return nil, err
+	}
+	if !synced {
+		r.Logger.Printf("node is not synced; skipping")
+		return nil, nil
+	}
+
+	drifts, err := r.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, drift := range drifts {
+		if !r.Repair {
+			r.Logger.Printf("drift: %s", drift)
+			continue
+		}
+
+		for _, statement := range drift.Statements {
+			if err := r.Store.Exec(statement); err != nil {
+				return drifts, fmt.Errorf("repairing %s: %s", drift, err)
+			}
+		}
+		r.Logger.Printf("repaired: %s", drift)
+	}
+	return drifts, nil
+}
+
+// Check returns the drift between the store and the declared state, in the
+// order it should be repaired.
+func (r *Reconciler) Check() ([]Drift, error) {
+	plan, err := dbinit.PlanFor(r.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	add := func(d []Drift, err error) error {
+		drifts = append(drifts, d...)
+		return err
+	}
+
+	if err := add(r.checkAbsent(plan.Drop)); err != nil {
+		return nil, err
+	}
+	for _, user := range plan.Users {
+		if err := add(r.checkUser(user)); err != nil {
+			return nil, err
+		}
+	}
+	for _, database := range r.Databases {
+		if err := add(r.checkDatabase(database)); err != nil {
+			return nil, err
+		}
+	}
+
+	anonymous, err := r.Store.AnonymousAccounts()
+	if err != nil {
+		return nil, err
+	}
+	if err := add(r.checkAbsent(append(plan.DropAfter, anonymous...))); err != nil {
+		return nil, err
+	}
+	return drifts, nil
+}
+
+func (r *Reconciler) checkAbsent(accounts []dbinit.Account) ([]Drift, error) {
+	var drifts []Drift
+	for _, account := range accounts {
+		state, err := r.Store.Account(account)
+		if err != nil {
+			return nil, err
+		}
+		if state.Exists {
+			drifts = append(drifts, Drift{
+				Subject:    account.String(),
+				Problem:    "account should not exist",
+				Statements: []string{dbinit.DropUser(account)},
+			})
+		}
+	}
+	return drifts, nil
+}
+
+func (r *Reconciler) checkUser(user dbinit.User) ([]Drift, error) {
+	state, err := r.Store.Account(user.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	if !state.Exists {
+		return []Drift{{
+			Subject:    user.Account.String(),
+			Problem:    "account is missing",
+			Statements: append(dbinit.CreateUser(user), dbinit.Grant(user)),
+		}}, nil
+	}
+
+	var drifts []Drift
+	if !passwordMatches(state, user.Password) {
+		drifts = append(drifts, Drift{
+			Subject:    user.Account.String(),
+			Problem:    "password was changed",
+			Statements: []string{dbinit.SetPassword(user.Account, user.Password)},
+		})
+	}
+
+	missing, extra := compare(state.Privileges, wantedColumns(user, state.Privileges.Columns))
+	switch {
+	case len(extra) > 0:
+		drifts = append(drifts, Drift{
+			Subject: user.Account.String(),
+			Problem: fmt.Sprintf("has undeclared privileges %s", strings.Join(extra, ", ")),
+			Statements: []string{
+				fmt.Sprintf("REVOKE ALL PRIVILEGES, GRANT OPTION FROM %s", user.Account),
+				dbinit.Grant(user),
+			},
+		})
+	case len(missing) > 0:
+		drifts = append(drifts, Drift{
+			Subject:    user.Account.String(),
+			Problem:    fmt.Sprintf("is missing privileges %s", strings.Join(missing, ", ")),
+			Statements: []string{dbinit.Grant(user)},
+		})
+	}
+	return drifts, nil
+}
+
+func (r *Reconciler) checkDatabase(database SeededDatabase) ([]Drift, error) {
+	var drifts []Drift
+
+	exists, err := r.Store.DatabaseExists(database.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		drifts = append(drifts, Drift{
+			Subject:    dbinit.QuoteIdentifier(database.Name),
+			Problem:    "database is missing",
+			Statements: []string{fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", dbinit.QuoteIdentifier(database.Name))},
+		})
+	}
+
+	if database.Username == "" {
+		return drifts, nil
+	}
+
+	account := dbinit.Account{Username: database.Username, Host: "%"}
+	grant := fmt.Sprintf("GRANT ALL PRIVILEGES ON %s.* TO %s", dbinit.QuoteIdentifier(database.Name), account)
+
+	state, err := r.Store.Account(account)
+	if err != nil {
+		return nil, err
+	}
+	if !state.Exists {
+		return append(drifts, Drift{
+			Subject:    account.String(),
+			Problem:    "account is missing",
+			Statements: append(dbinit.CreateUser(dbinit.User{Account: account, Password: database.Password}), grant),
+		}), nil
+	}
+
+	if !passwordMatches(state, database.Password) {
+		drifts = append(drifts, Drift{
+			Subject:    account.String(),
+			Problem:    "password was changed",
+			Statements: []string{dbinit.SetPassword(account, database.Password)},
+		})
+	}
+
+	privileges, err := r.Store.DatabasePrivileges(account, database.Name)
+	if err != nil {
+		return nil, err
+	}
+	missing, _ := compare(privileges, allBut(privileges.Columns, grantColumn))
+	if len(missing) > 0 {
+		drifts = append(drifts, Drift{
+			Subject:    account.String(),
+			Problem:    fmt.Sprintf("is missing privileges %s on %s", strings.Join(missing, ", "), dbinit.QuoteIdentifier(database.Name)),
+			Statements: []string{grant},
+		})
+	}
+	return drifts, nil
+}
+
+// wantedColumns returns the mysql.user columns user's grant sets.
+func wantedColumns(user dbinit.User, columns []string) map[string]bool {
+	wanted := map[string]bool{}
+	for _, privilege := range user.Privileges {
+		if privilege == dbinit.AllPrivileges {
+			for column := range allBut(columns, grantColumn) {
+				wanted[column] = true
+			}
+			continue
+		}
+		wanted[privilegeColumns[privilege]] = true
+	}
+	if user.GrantOption {
+		wanted[grantColumn] = true
+	}
+	return wanted
+}
+
+func allBut(columns []string, except string) map[string]bool {
+	set := map[string]bool{}
+	for _, column := range columns {
+		if column != except {
+			set[column] = true
+		}
+	}
+	return set
+}
+
+func compare(privileges Privileges, wanted map[string]bool) (missing, extra []string) {
+	for column := range wanted {
+		if !privileges.Granted[column] {
+			missing = append(missing, column)
+		}
+	}
+	for column, granted := range privileges.Granted {
+		if granted && !wanted[column] {
+			extra = append(extra, column)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return missing, extra
+}
+
+func passwordMatches(state AccountState, password string) bool {
+	return state.Plugin == "mysql_native_password" && state.AuthenticationString == NativePasswordHash(password)
+}
+
+// NativePasswordHash returns the authentication_string mysql_native_password
+// stores for password. An empty password is stored as an empty string.
+func NativePasswordHash(password string) string {
+	if password == "" {
+		return ""
+	}
+	first := sha1.Sum([]byte(password))
+	second := sha1.Sum(first[:])
+	return "*" + strings.ToUpper(hex.EncodeToString(second[:]))
+}
@@ -0,0 +1,315 @@
+package reconciler_test
+
+import (
+	"errors"
+	"log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"pxc-utils/dbinit"
+	"pxc-utils/reconciler"
+)
+
+var (
+	userColumns = []string{"Select_priv", "Insert_priv", "Reload_priv", "Process_priv", "Lock_tables_priv", "Repl_client_priv", "Grant_priv"}
+	dbColumns   = []string{"Select_priv", "Insert_priv", "Drop_priv", "Grant_priv"}
+)
+
+type dbKey struct {
+	account  dbinit.Account
+	database string
+}
+
+// memoryStore is a server whose grant tables are maps.
+type memoryStore struct {
+	notSynced    bool
+	accounts     map[dbinit.Account]reconciler.AccountState
+	databases    map[string]bool
+	dbPrivileges map[dbKey]map[string]bool
+	executed     []string
+	execErr      error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		accounts:     map[dbinit.Account]reconciler.AccountState{},
+		databases:    map[string]bool{},
+		dbPrivileges: map[dbKey]map[string]bool{},
+	}
+}
+
+func (s *memoryStore) Synced() (bool, error) { return !s.notSynced, nil }
+
+func (s *memoryStore) Account(account dbinit.Account) (reconciler.AccountState, error) {
+	state := s.accounts[account]
+	state.Privileges.Columns = userColumns
+	return state, nil
+}
+
+func (s *memoryStore) AnonymousAccounts() ([]dbinit.Account, error) {
+	var anonymous []dbinit.Account
+	for account := range s.accounts {
+		if account.Username == "" {
+			anonymous = append(anonymous, account)
+		}
+	}
+	return anonymous, nil
+}
+
+func (s *memoryStore) DatabaseExists(name string) (bool, error) { return s.databases[name], nil }
+
+func (s *memoryStore) DatabasePrivileges(account dbinit.Account, database string) (reconciler.Privileges, error) {
+	return reconciler.Privileges{Columns: dbColumns, Granted: s.dbPrivileges[dbKey{account, database}]}, nil
+}
+
+func (s *memoryStore) Exec(statement string) error {
+	s.executed = append(s.executed, statement)
+	return s.execErr
+}
+
+func (s *memoryStore) addAccount(username, host, password string, privileges ...string) {
+	granted := map[string]bool{}
+	for _, privilege := range privileges {
+		granted[privilege] = true
+	}
+	s.accounts[dbinit.Account{Username: username, Host: host}] = reconciler.AccountState{
+		Exists:               true,
+		Plugin:               "mysql_native_password",
+		AuthenticationString: reconciler.NativePasswordHash(password),
+		Privileges:           reconciler.Privileges{Granted: granted},
+	}
+}
+
+var _ = Describe("Reconciler", func() {
+	var (
+		store  *memoryStore
+		logs   *gbytes.Buffer
+		r      *reconciler.Reconciler
+		admins []string
+	)
+
+	BeforeEach(func() {
+		store = newMemoryStore()
+		logs = gbytes.NewBuffer()
+		r = &reconciler.Reconciler{
+			Store: store,
+			Accounts: dbinit.Spec{
+				AdminUsername: "admin",
+				AdminPassword: "admin-password",
+				Roadmin:       dbinit.Roadmin{Enabled: true, Password: "roadmin-password"},
+			},
+			Databases: []reconciler.SeededDatabase{{Name: "app", Username: "app-user", Password: "app-password"}},
+			Repair:    true,
+			Logger:    log.New(logs, "", 0),
+		}
+
+		admins = userColumns
+		for _, host := range dbinit.LocalHosts {
+			store.addAccount("admin", host, "admin-password", admins...)
+			store.addAccount("roadmin", host, "roadmin-password", "Select_priv", "Process_priv", "Repl_client_priv")
+		}
+		store.addAccount("app-user", "%", "app-password")
+		store.databases["app"] = true
+		store.dbPrivileges[dbKey{dbinit.Account{Username: "app-user", Host: "%"}, "app"}] = map[string]bool{
+			"Select_priv": true, "Insert_priv": true, "Drop_priv": true,
+		}
+	})
+
+	It("finds no drift when the server matches the declaration", func() {
+		drifts, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts).To(BeEmpty())
+		Expect(store.executed).To(BeEmpty())
+	})
+
+	It("recreates a dropped account", func() {
+		delete(store.accounts, dbinit.Account{Username: "roadmin", Host: "127.0.0.1"})
+
+		drifts, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts).To(HaveLen(1))
+		Expect(store.executed).To(Equal([]string{
+			"CREATE USER IF NOT EXISTS 'roadmin'@'127.0.0.1' IDENTIFIED BY 'roadmin-password'",
+			"ALTER USER 'roadmin'@'127.0.0.1' IDENTIFIED BY 'roadmin-password'",
+			"GRANT SELECT, PROCESS, REPLICATION CLIENT ON *.* TO 'roadmin'@'127.0.0.1'",
+		}))
+		Expect(logs).To(gbytes.Say(`repaired: 'roadmin'@'127.0.0.1': account is missing`))
+	})
+
+	It("resets a changed password without logging it", func() {
+		store.addAccount("admin", "localhost", "changed", admins...)
+
+		_, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.executed).To(Equal([]string{"ALTER USER 'admin'@'localhost' IDENTIFIED BY 'admin-password'"}))
+		Expect(logs).To(gbytes.Say("'admin'@'localhost': password was changed"))
+		Expect(string(logs.Contents())).NotTo(ContainSubstring("admin-password"))
+	})
+
+	It("treats an account using another authentication plugin as drift", func() {
+		state := store.accounts[dbinit.Account{Username: "admin", Host: "::1"}]
+		state.Plugin = "sha256_password"
+		store.accounts[dbinit.Account{Username: "admin", Host: "::1"}] = state
+
+		drifts, err := r.Check()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts).To(HaveLen(1))
+		Expect(drifts[0].Problem).To(Equal("password was changed"))
+	})
+
+	It("regrants revoked privileges", func() {
+		store.addAccount("admin", "localhost", "admin-password", "Select_priv")
+
+		drifts, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts[0].Problem).To(Equal("is missing privileges Grant_priv, Insert_priv, Lock_tables_priv, Process_priv, Reload_priv, Repl_client_priv"))
+		Expect(store.executed).To(Equal([]string{"GRANT ALL PRIVILEGES ON *.* TO 'admin'@'localhost' WITH GRANT OPTION"}))
+	})
+
+	It("revokes privileges that were added to a read-only account", func() {
+		store.addAccount("roadmin", "localhost", "roadmin-password", "Select_priv", "Process_priv", "Repl_client_priv", "Insert_priv")
+
+		drifts, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts[0].Problem).To(Equal("has undeclared privileges Insert_priv"))
+		Expect(store.executed).To(Equal([]string{
+			"REVOKE ALL PRIVILEGES, GRANT OPTION FROM 'roadmin'@'localhost'",
+			"GRANT SELECT, PROCESS, REPLICATION CLIENT ON *.* TO 'roadmin'@'localhost'",
+		}))
+	})
+
+	It("drops accounts that must not exist", func() {
+		store.addAccount("root", "localhost", "")
+		store.addAccount("admin", "%", "admin-password", admins...)
+		store.addAccount("", "localhost", "")
+
+		_, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.executed).To(ConsistOf(
+			"DROP USER IF EXISTS 'root'@'localhost'",
+			"DROP USER IF EXISTS 'admin'@'%'",
+			"DROP USER IF EXISTS ''@'localhost'",
+		))
+		Expect(store.executed[0]).To(Equal("DROP USER IF EXISTS 'root'@'localhost'"))
+	})
+
+	Describe("seeded databases", func() {
+		It("recreates a dropped database", func() {
+			delete(store.databases, "app")
+
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.executed).To(Equal([]string{"CREATE DATABASE IF NOT EXISTS `app`"}))
+		})
+
+		It("recreates a dropped user with its grant", func() {
+			delete(store.accounts, dbinit.Account{Username: "app-user", Host: "%"})
+
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.executed).To(Equal([]string{
+				"CREATE USER IF NOT EXISTS 'app-user'@'%' IDENTIFIED BY 'app-password'",
+				"ALTER USER 'app-user'@'%' IDENTIFIED BY 'app-password'",
+				"GRANT ALL PRIVILEGES ON `app`.* TO 'app-user'@'%'",
+			}))
+		})
+
+		It("regrants revoked database privileges", func() {
+			delete(store.dbPrivileges, dbKey{dbinit.Account{Username: "app-user", Host: "%"}, "app"})
+
+			drifts, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifts[0].Problem).To(Equal("is missing privileges Drop_priv, Insert_priv, Select_priv on `app`"))
+			Expect(store.executed).To(Equal([]string{"GRANT ALL PRIVILEGES ON `app`.* TO 'app-user'@'%'"}))
+		})
+	})
+
+	It("only reports drift when repair is disabled", func() {
+		r.Repair = false
+		delete(store.databases, "app")
+
+		drifts, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts).To(HaveLen(1))
+		Expect(store.executed).To(BeEmpty())
+		Expect(logs).To(gbytes.Say("drift: `app`: database is missing"))
+	})
+
+	It("leaves a node that is not synced alone", func() {
+		store.notSynced = true
+		delete(store.databases, "app")
+
+		drifts, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts).To(BeEmpty())
+		Expect(logs).To(gbytes.Say("node is not synced; skipping"))
+	})
+
+	It("restores the admin account when it was dropped or its password changed", func() {
+		r.Accounts.Reconciler = dbinit.Reconciler{Username: "pxc-reconciler"}
+		delete(store.accounts, dbinit.Account{Username: "admin", Host: "localhost"})
+		store.addAccount("admin", "127.0.0.1", "changed", admins...)
+
+		drifts, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts).To(HaveLen(2))
+		Expect(store.executed).To(Equal([]string{
+			"CREATE USER IF NOT EXISTS 'admin'@'localhost' IDENTIFIED BY 'admin-password'",
+			"ALTER USER 'admin'@'localhost' IDENTIFIED BY 'admin-password'",
+			"GRANT ALL PRIVILEGES ON *.* TO 'admin'@'localhost' WITH GRANT OPTION",
+			"ALTER USER 'admin'@'127.0.0.1' IDENTIFIED BY 'admin-password'",
+		}))
+	})
+
+	It("does not reconcile its own account", func() {
+		r.Accounts.Reconciler = dbinit.Reconciler{Username: "pxc-reconciler"}
+
+		drifts, err := r.Check()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifts).To(BeEmpty())
+	})
+
+	It("returns repair errors", func() {
+		delete(store.databases, "app")
+		store.execErr = errors.New("read only")
+
+		_, err := r.Reconcile()
+		Expect(err).To(MatchError("repairing `app`: database is missing: read only"))
+	})
+
+	It("returns errors for invalid declarations", func() {
+		r.Accounts.AdminPassword = ""
+		_, err := r.Reconcile()
+		Expect(err).To(MatchError("admin_password is required"))
+	})
+})
+
+var _ = Describe("NativePasswordHash", func() {
+	It("matches mysql_native_password", func() {
+		// SELECT PASSWORD('password') on MySQL 5.7
+		Expect(reconciler.NativePasswordHash("password")).To(Equal("*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19"))
+		Expect(reconciler.NativePasswordHash("")).To(Equal(""))
+	})
+})
+
+var _ = Describe("ConnectionConfig", func() {
+	It("logs in as the reconciler account over the socket, not as the admin", func() {
+		config, err := reconciler.ConnectionConfig(dbinit.Spec{
+			AdminUsername: "admin",
+			AdminPassword: "admin-password",
+			Reconciler:    dbinit.Reconciler{Username: "pxc-reconciler"},
+		}, "/var/vcap/sys/run/pxc-mysql/mysqld.sock")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.User).To(Equal("pxc-reconciler"))
+		Expect(config.Passwd).To(BeEmpty())
+		Expect(config.Net).To(Equal("unix"))
+		Expect(config.Addr).To(Equal("/var/vcap/sys/run/pxc-mysql/mysqld.sock"))
+	})
+
+	It("fails when no reconciler account is declared", func() {
+		_, err := reconciler.ConnectionConfig(dbinit.Spec{AdminUsername: "admin", AdminPassword: "admin-password"}, "mysqld.sock")
+		Expect(err).To(MatchError("the reconciler account is not declared"))
+	})
+})
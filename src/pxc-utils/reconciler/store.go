@@ -0,0 +1,139 @@
+package reconciler
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"pxc-utils/dbinit"
+)
+
+// ConnectionConfig returns the config for logging in as the reconciler's
+// own auth_socket account over socket. The admin account is not used, since
+// it is one of the accounts the reconciler may have to repair.
+func ConnectionConfig(accounts dbinit.Spec, socket string) (*mysql.Config, error) {
+	if accounts.Reconciler.Username == "" {
+		return nil, errors.New("the reconciler account is not declared")
+	}
+	return &mysql.Config{
+		User:                 accounts.Reconciler.Username,
+		Net:                  "unix",
+		Addr:                 socket,
+		Timeout:              10 * time.Second,
+		AllowNativePasswords: true,
+	}, nil
+}
+
+// SQLStore reads accounts from the grant tables of a server.
+type SQLStore struct {
+	DB *sql.DB
+}
+
+// Synced is true when wsrep_local_state is Synced, or when the server is
+// not running Galera.
+func (s SQLStore) Synced() (bool, error) {
+	var name, value string
+	err := s.DB.QueryRow("SHOW GLOBAL STATUS LIKE 'wsrep_local_state'").Scan(&name, &value)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return value == "4", nil
+}
+
+func (s SQLStore) Account(account dbinit.Account) (AccountState, error) {
+	row, privileges, err := s.row("SELECT * FROM mysql.user WHERE User = ? AND Host = ?", account.Username, account.Host)
+	if err != nil || row == nil {
+		return AccountState{Privileges: privileges}, err
+	}
+
+	return AccountState{
+		Exists:               true,
+		Plugin:               row["plugin"],
+		AuthenticationString: row["authentication_string"],
+		Privileges:           privileges,
+	}, nil
+}
+
+func (s SQLStore) AnonymousAccounts() ([]dbinit.Account, error) {
+	rows, err := s.DB.Query("SELECT Host FROM mysql.user WHERE User = ''")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []dbinit.Account
+	for rows.Next() {
+		var host string
+		if err := rows.Scan(&host); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, dbinit.Account{Host: host})
+	}
+	return accounts, rows.Err()
+}
+
+func (s SQLStore) DatabaseExists(name string) (bool, error) {
+	var count int
+	err := s.DB.QueryRow("SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", name).Scan(&count)
+	return count > 0, err
+}
+
+func (s SQLStore) DatabasePrivileges(account dbinit.Account, database string) (Privileges, error) {
+	_, privileges, err := s.row("SELECT * FROM mysql.db WHERE User = ? AND Host = ? AND Db = ?", account.Username, account.Host, database)
+	return privileges, err
+}
+
+func (s SQLStore) Exec(statement string) error {
+	_, err := s.DB.Exec(statement)
+	return err
+}
+
+// row returns the first row of query by column name, or nil when there is
+// none, and the privilege columns of the table either way.
+func (s SQLStore) row(query string, args ...interface{}) (map[string]string, Privileges, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, Privileges{}, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, Privileges{}, err
+	}
+
+	privileges := Privileges{Granted: map[string]bool{}}
+	for _, column := range columns {
+		if strings.HasSuffix(column, "_priv") {
+			privileges.Columns = append(privileges.Columns, column)
+		}
+	}
+
+	if !rows.Next() {
+		return nil, privileges, rows.Err()
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, Privileges{}, err
+	}
+
+	row := map[string]string{}
+	for i, column := range columns {
+		row[column] = string(values[i])
+		if strings.HasSuffix(column, "_priv") && row[column] == "Y" {
+			privileges.Granted[column] = true
+		}
+	}
+	return row, privileges, rows.Err()
+}
@@ -2,45 +2,24 @@ package bootstrap_test
 
 import (
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"time"
 
 	boshdir "github.com/cloudfoundry/bosh-cli/director"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/pkg/errors"
 
 	helpers "specs/test_helpers"
+	"specs/test_helpers/galeraagent"
 )
 
 func stopMySQL(host string) error {
-	stopMySQLEndpoint := fmt.Sprintf("http://%s:9200/stop_mysql", host)
-	req, err := http.NewRequest("POST", stopMySQLEndpoint, nil)
-	if err != nil {
-		return err
-	}
-
 	galeraAgentPassword, err := helpers.GetGaleraAgentPassword()
 	if err != nil {
 		return err
 	}
 
-	req.SetBasicAuth(galeraAgentUsername, galeraAgentPassword)
-
-	res, err := helpers.HttpClient.Do(req)
-	if err != nil {
-		return err
-	}
-
-	responseBody, _ := ioutil.ReadAll(res.Body)
-	fmt.Fprintln(GinkgoWriter, string(responseBody))
-
-	if res.StatusCode != http.StatusOK {
-		return errors.Errorf(`Expected [HTTP 200], but got %s. body: %v`, res.Status, string(responseBody))
-	}
-
-	return nil
+	client := galeraagent.NewClientForHost(host, galeraAgentUsername, galeraAgentPassword, helpers.HttpClient)
+	return client.StopMySQL()
 }
 
 func stopGaleraInitOnAllMysqls() {
@@ -9,7 +9,6 @@ import (
 	. "github.com/onsi/gomega"
 
 	helpers "specs/test_helpers"
-	"strings"
 )
 
 func deleteMysqlVM(host string) error {
@@ -23,24 +22,9 @@ func deleteMysqlVM(host string) error {
 		return fmt.Errorf("finding deployment: %s", err)
 	}
 
-	instances, err := deployment.Instances()
+	vmcid, err := helpers.VMIDForHost(deployment, "mysql", host)
 	if err != nil {
-		return fmt.Errorf("listing instances: %s", err)
-	}
-
-	var vmcid string
-	for _, instance := range instances {
-		if instance.Group == "mysql" {
-			hostArray := strings.Split(host, ".")
-			if instance.IPs[0] == host || (len(hostArray) > 0 && hostArray[0] == instance.ID) {
-				vmcid = instance.VMID
-				break
-			}
-		}
-	}
-
-	if vmcid == "" {
-		return fmt.Errorf("no vm found with %s", host)
+		return err
 	}
 
 	return deployment.DeleteVM(vmcid)
@@ -82,6 +66,16 @@ var _ = Describe("CF PXC MySQL Failover", func() {
 		_, err := mysqlConn.Query(query)
 		Expect(err).NotTo(HaveOccurred())
 
+		workload := &helpers.Workload{
+			Store:    &helpers.SQLWorkloadStore{DB: db, Table: "failover_workload"},
+			Writers:  4,
+			Readers:  2,
+			Interval: 50 * time.Millisecond,
+			Retries:  3,
+		}
+		Expect(workload.Start()).To(Succeed())
+		defer workload.Stop()
+
 		By("querying the proxy for the current mysql backend", func() {
 			var err error
 			oldBackend, err = helpers.ActiveProxyBackend(proxyUsername, proxyPassword, firstProxy, helpers.HttpClient)
@@ -102,6 +96,20 @@ var _ = Describe("CF PXC MySQL Failover", func() {
 			}, 5*time.Minute, 20*time.Second).Should(BeTrue())
 		})
 
+		By("checking writes made during the failover", func() {
+			workload.Stop()
+
+			var report helpers.WorkloadReport
+			Eventually(func() error {
+				var err error
+				report, err = workload.Check()
+				return err
+			}, time.Minute, time.Second).Should(Succeed())
+
+			fmt.Fprint(GinkgoWriter, report.String())
+			Expect(report.Consistent()).To(BeTrue(), report.String())
+		})
+
 		var queryResultString string
 		query = "SELECT * FROM pxc_release_test_db.failover_test_table"
 		rows, err := mysqlConn.Query(query)
@@ -0,0 +1,41 @@
+package partition_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	helpers "specs/test_helpers"
+)
+
+func TestPartition(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PXC Acceptance Tests -- Partition")
+}
+
+var _ = BeforeSuite(func() {
+	requiredEnvs := []string{
+		"BOSH_ENVIRONMENT",
+		"BOSH_CA_CERT",
+		"BOSH_CLIENT",
+		"BOSH_CLIENT_SECRET",
+		"BOSH_DEPLOYMENT",
+		"CREDHUB_SERVER",
+		"CREDHUB_CLIENT",
+		"CREDHUB_SECRET",
+	}
+
+	helpers.CheckForRequiredEnvVars(requiredEnvs)
+
+	helpers.SetupBoshDeployment()
+
+	if os.Getenv("BOSH_ALL_PROXY") != "" {
+		helpers.SetupSocks5Proxy()
+	}
+})
+
+var _ = AfterSuite(func() {
+	Expect(helpers.HealAllChaos()).To(Succeed())
+})
@@ -0,0 +1,263 @@
+package partition_test
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	helpers "specs/test_helpers"
+	"specs/test_helpers/switchboard"
+)
+
+// wsrepNotReadyError is ER_UNKNOWN_COM_ERROR, which a node that is not part
+// of the primary component returns for every query.
+const wsrepNotReadyError = 1047
+
+// primaryWriteMonitor writes through the proxy in a loop and records every
+// committed write made on a node that was not part of the primary component.
+// A non-Primary node rejects writes, so once the proxy should have failed
+// over, a rejection also means the proxy routed a write to such a node.
+type primaryWriteMonitor struct {
+	db   *sql.DB
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	mutex      sync.Mutex
+	deadline   time.Time
+	committed  int
+	violations []string
+}
+
+func startPrimaryWriteMonitor(db *sql.DB) *primaryWriteMonitor {
+	m := &primaryWriteMonitor{db: db, stop: make(chan struct{})}
+	m.wg.Add(1)
+	go m.run()
+	return m
+}
+
+func (m *primaryWriteMonitor) run() {
+	defer m.wg.Done()
+	for i := 0; ; i++ {
+		select {
+		case <-m.stop:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		status, err := m.write(i)
+		if err != nil {
+			m.rejected(i, err)
+			continue
+		}
+
+		m.mutex.Lock()
+		m.committed++
+		if status != "Primary" {
+			m.violations = append(m.violations, fmt.Sprintf("write %d committed on a %s component", i, status))
+		}
+		m.mutex.Unlock()
+	}
+}
+
+// write reads the node's cluster status and writes in one transaction, so
+// both happen on the node the proxy routed the connection to.
+func (m *primaryWriteMonitor) write(i int) (string, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var name, status string
+	err = tx.QueryRow("SHOW GLOBAL STATUS LIKE 'wsrep_cluster_status'").Scan(&name, &status)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec("INSERT INTO pxc_release_test_db.partition_test_table VALUES (?)", fmt.Sprintf("monitor-%d", i))
+	if err != nil {
+		return "", err
+	}
+
+	return status, tx.Commit()
+}
+
+// FailoverBy sets when the proxy must route only to Primary nodes. Writes a
+// non-Primary node rejects after it are violations.
+func (m *primaryWriteMonitor) FailoverBy(deadline time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.deadline = deadline
+}
+
+func (m *primaryWriteMonitor) rejected(i int, err error) {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	if !ok || mysqlErr.Number != wsrepNotReadyError {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.deadline.IsZero() && time.Now().After(m.deadline) {
+		m.violations = append(m.violations, fmt.Sprintf("write %d was routed to a non-Primary node: %s", i, err))
+	}
+}
+
+func (m *primaryWriteMonitor) Stop() (int, []string) {
+	select {
+	case <-m.stop:
+	default:
+		close(m.stop)
+	}
+	m.wg.Wait()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.committed, append([]string(nil), m.violations...)
+}
+
+var _ = Describe("CF PXC MySQL Network Partitions", func() {
+	var (
+		db       *sql.DB
+		proxy    *switchboard.Client
+		chaos    *helpers.Chaos
+		workload *helpers.Workload
+		monitor  *primaryWriteMonitor
+		nodes    []helpers.ChaosNode
+		active   helpers.ChaosNode
+	)
+
+	BeforeEach(func() {
+		db, chaos, workload, monitor = nil, nil, nil, nil
+
+		proxyHost, err := helpers.FirstProxyHost(helpers.BoshDeployment)
+		Expect(err).NotTo(HaveOccurred())
+		proxyPassword, err := helpers.GetProxyPassword()
+		Expect(err).NotTo(HaveOccurred())
+		mysqlPassword, err := helpers.GetMySQLAdminPassword()
+		Expect(err).NotTo(HaveOccurred())
+
+		proxy = switchboard.NewClientForHost(proxyHost, "proxy", proxyPassword, helpers.HttpClient)
+		db = helpers.DbConnWithUser("root", mysqlPassword, proxyHost)
+		helpers.DbSetup(db, "partition_test_table")
+
+		nodes, err = helpers.ChaosNodes(helpers.BoshDeployment, "mysql")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(HaveLen(3), "partition specs need a three node cluster")
+
+		activeHost, err := proxy.ActiveBackend()
+		Expect(err).NotTo(HaveOccurred())
+		for _, node := range nodes {
+			if node.IP == activeHost {
+				active = node
+			}
+		}
+		Expect(active.IP).NotTo(BeEmpty(), "no mysql instance has the active backend's address %s", activeHost)
+
+		chaos = helpers.NewChaos(nil)
+
+		workload = &helpers.Workload{
+			Store:    &helpers.SQLWorkloadStore{DB: db, Table: "partition_workload"},
+			Writers:  4,
+			Readers:  2,
+			Interval: 50 * time.Millisecond,
+			Retries:  3,
+		}
+		Expect(workload.Start()).To(Succeed())
+		monitor = startPrimaryWriteMonitor(db)
+	})
+
+	AfterEach(func() {
+		if chaos != nil {
+			Expect(chaos.Heal()).To(Succeed())
+		}
+		if workload != nil {
+			workload.Stop()
+		}
+		if monitor != nil {
+			monitor.Stop()
+		}
+		if db == nil {
+			return
+		}
+
+		By("waiting for the cluster to re-form")
+		Eventually(func() (string, error) {
+			var name, size string
+			err := db.QueryRow("SHOW GLOBAL STATUS LIKE 'wsrep_cluster_size'").Scan(&name, &size)
+			return size, err
+		}, 5*time.Minute, 5*time.Second).Should(Equal("3"))
+
+		helpers.DbCleanup(db)
+	})
+
+	expectFailoverWithoutNonPrimaryWrites := func() {
+		By("waiting for the proxy to route away from the disrupted node")
+		Eventually(func() (string, error) {
+			return proxy.ActiveBackend()
+		}, 5*time.Minute, 5*time.Second).ShouldNot(Or(BeEmpty(), Equal(active.IP)))
+		// Allow for writes that were already in flight to the old backend
+		// when the proxy switched.
+		monitor.FailoverBy(time.Now().Add(5 * time.Second))
+
+		By("writing through the new backend for a while")
+		time.Sleep(30 * time.Second)
+
+		Expect(chaos.Heal()).To(Succeed())
+		workload.Stop()
+		committed, violations := monitor.Stop()
+		Expect(committed).To(BeNumerically(">", 0), "no writes succeeded through the proxy")
+		Expect(violations).To(BeEmpty())
+
+		var report helpers.WorkloadReport
+		Eventually(func() error {
+			var err error
+			report, err = workload.Check()
+			return err
+		}, time.Minute, time.Second).Should(Succeed())
+		fmt.Fprint(GinkgoWriter, report.String())
+		Expect(report.Consistent()).To(BeTrue(), report.String())
+	}
+
+	It("does not write to the active node once it is partitioned from the Galera cluster", func() {
+		Expect(chaos.Isolate(active, nodes, helpers.GaleraPorts)).To(Succeed())
+		expectFailoverWithoutNonPrimaryWrites()
+	})
+
+	It("fails over when the proxy can no longer reach the active node", func() {
+		proxies, err := helpers.ChaosNodes(helpers.BoshDeployment, "proxy")
+		Expect(err).NotTo(HaveOccurred())
+		for _, p := range proxies {
+			Expect(chaos.PartitionOneWay(p, active, helpers.MySQLPorts)).To(Succeed())
+		}
+		expectFailoverWithoutNonPrimaryWrites()
+	})
+
+	It("keeps writes consistent while Galera traffic is delayed", func() {
+		var peers []helpers.ChaosNode
+		for _, node := range nodes {
+			if node.Instance != active.Instance {
+				peers = append(peers, node)
+			}
+		}
+		Expect(chaos.Delay(active, peers, 500*time.Millisecond)).To(Succeed())
+		// Delay alone must not take any node out of the primary component.
+		monitor.FailoverBy(time.Now())
+
+		time.Sleep(30 * time.Second)
+
+		Expect(chaos.Heal()).To(Succeed())
+		workload.Stop()
+		_, violations := monitor.Stop()
+		Expect(violations).To(BeEmpty())
+
+		report, err := workload.Check()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Consistent()).To(BeTrue(), report.String())
+	})
+})
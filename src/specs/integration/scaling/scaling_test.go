@@ -9,9 +9,6 @@ import (
 
 	"database/sql"
 	helpers "specs/test_helpers"
-
-	boshdir "github.com/cloudfoundry/bosh-cli/director"
-	yaml "gopkg.in/yaml.v2"
 )
 
 func scaleDeployment(instanceCount int) error {
@@ -25,35 +22,7 @@ func scaleDeployment(instanceCount int) error {
 		return fmt.Errorf("finding deployment: %s", err)
 	}
 
-	manifestString, err := deployment.Manifest()
-	if err != nil {
-		return fmt.Errorf("getting manifest: %s", err)
-	}
-	var manifest map[string]interface{}
-	err = yaml.Unmarshal([]byte(manifestString), &manifest)
-	if err != nil {
-		return fmt.Errorf("unmarshalling manifest: %s", err)
-	}
-
-	instanceGroups := manifest["instance_groups"].([]interface{})
-	for _, instanceGroup := range instanceGroups {
-		if instanceGroup.(map[interface{}]interface{})["name"] == "mysql" {
-			instanceGroup.(map[interface{}]interface{})["instances"] = instanceCount
-			break
-		}
-	}
-
-	updatedManifest, err := yaml.Marshal(manifest)
-	if err != nil {
-		return fmt.Errorf("marshalling manifest: %s", err)
-	}
-
-	err = deployment.Update(updatedManifest, boshdir.UpdateOpts{})
-	if err != nil {
-		return fmt.Errorf("deploying: %s", err)
-	}
-
-	return err
+	return helpers.ScaleInstanceGroup(deployment, "mysql", instanceCount)
 }
 
 func verifyDataExists(expectedString string, databaseConnection *sql.DB) {
@@ -0,0 +1,150 @@
+package tls_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	helpers "specs/test_helpers"
+)
+
+// Each rotation starts from whichever certificate the manifest references
+// and moves to the other of a pair of variables, so that a run leaves the
+// deployment ready for the next one.
+var _ = Describe("CA rotation", func() {
+	var workload *helpers.Workload
+
+	BeforeEach(func() {
+		workload = &helpers.Workload{
+			Store:    &helpers.SQLWorkloadStore{DB: mysqlConn, Table: "ca_rotation_workload"},
+			Writers:  2,
+			Readers:  1,
+			Interval: 100 * time.Millisecond,
+			Retries:  3,
+		}
+		Expect(workload.Start()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		workload.Stop()
+	})
+
+	expectPhase := func(pair, phase, issuer string, trustedCAs ...string) {
+		rotations, err := helpers.CertificateRotations(helpers.BoshDeployment, "mysql", pair, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotations).NotTo(BeEmpty())
+
+		for instance, rotation := range rotations {
+			Expect(rotation.Phase).To(Equal(phase), instance)
+			Expect(rotation.Issuer).To(Equal(issuer), instance)
+			Expect(rotation.TrustedCAs).To(ConsistOf(trustedCAs), instance)
+		}
+	}
+
+	deploy := func(change func(manifest map[string]interface{}) error) {
+		Expect(helpers.UpdateManifest(helpers.BoshDeployment, change)).To(Succeed())
+
+		var clusterSize int
+		Expect(mysqlConn.QueryRow(`SELECT variable_value FROM performance_schema.global_status
+			WHERE variable_name = 'wsrep_cluster_size'`).Scan(&clusterSize)).To(Succeed())
+		hosts, err := helpers.MySQLHosts(helpers.BoshDeployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusterSize).To(Equal(len(hosts)))
+	}
+
+	other := func(name string) string {
+		if strings.HasSuffix(name, "_rotated") {
+			return strings.TrimSuffix(name, "_rotated")
+		}
+		return name + "_rotated"
+	}
+
+	rotate := func(pair string) {
+		property := "tls." + pair
+		trustedCAsProperty := "tls." + pair + "_trusted_cas"
+		setTrustedCAs := func(manifest map[string]interface{}, value interface{}) error {
+			return helpers.SetJobProperty(manifest, "mysql", "pxc-mysql", trustedCAsProperty, value)
+		}
+
+		manifest, err := helpers.Manifest(helpers.BoshDeployment)
+		Expect(err).NotTo(HaveOccurred())
+		oldCertificate, err := helpers.CertificateVariable(manifest, "mysql", "pxc-mysql", property)
+		Expect(err).NotTo(HaveOccurred())
+		certificateOptions, err := helpers.VariableOptions(manifest, oldCertificate)
+		Expect(err).NotTo(HaveOccurred())
+		oldCA, ok := certificateOptions["ca"].(string)
+		Expect(ok).To(BeTrue(), "%s has no ca option", oldCertificate)
+		caOptions, err := helpers.VariableOptions(manifest, oldCA)
+		Expect(err).NotTo(HaveOccurred())
+		oldCAName := fmt.Sprint(caOptions["common_name"])
+
+		newCertificate, newCA := other(oldCertificate), other(oldCA)
+
+		expectPhase(pair, "single-ca", oldCAName, oldCAName)
+
+		By("trusting the new CA alongside the old one", func() {
+			deploy(func(manifest map[string]interface{}) error {
+				helpers.SetVariable(manifest, newCA, "certificate", map[string]interface{}{
+					"is_ca":       true,
+					"common_name": newCA,
+				})
+				return setTrustedCAs(manifest, fmt.Sprintf("((%s.certificate))", newCA))
+			})
+			expectPhase(pair, "trusting-new-ca", oldCAName, oldCAName, newCA)
+		})
+
+		By("switching to certificates issued by the new CA", func() {
+			deploy(func(manifest map[string]interface{}) error {
+				options := map[string]interface{}{}
+				for key, value := range certificateOptions {
+					options[key] = value
+				}
+				options["ca"] = newCA
+				helpers.SetVariable(manifest, newCertificate, "certificate", options)
+
+				err := helpers.SetJobProperty(manifest, "mysql", "pxc-mysql", property, fmt.Sprintf("((%s))", newCertificate))
+				if err != nil {
+					return err
+				}
+				return setTrustedCAs(manifest, fmt.Sprintf("((%s.certificate))", oldCA))
+			})
+			expectPhase(pair, "using-new-ca", newCA, oldCAName, newCA)
+		})
+
+		By("no longer trusting the old CA", func() {
+			deploy(func(manifest map[string]interface{}) error {
+				return setTrustedCAs(manifest, nil)
+			})
+			expectPhase(pair, "single-ca", newCA, newCA)
+		})
+
+		By("checking writes made during the rotation", func() {
+			workload.Stop()
+
+			report, err := workload.Check()
+			Expect(err).NotTo(HaveOccurred())
+			fmt.Fprint(GinkgoWriter, report.String())
+			Expect(report.Consistent()).To(BeTrue(), report.String())
+		})
+	}
+
+	It("moves galera and SST to a new CA without losing writes", func() {
+		rotate("galera")
+	})
+
+	It("moves the server certificate to a new CA without losing writes", func() {
+		rotate("server")
+
+		serverCA, err := helpers.GetMySQLServerCA(helpers.BoshDeployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(helpers.RegisterPinnedTLSConfig("rotated-server-ca", serverCA)).To(Succeed())
+
+		db, err := helpers.DbConnWithTLSConfig("root", mysqlPassword, firstProxy, "rotated-server-ca")
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+		Expect(db.Ping()).To(Succeed())
+	})
+})
@@ -17,7 +17,9 @@ func TestTls(t *testing.T) {
 }
 
 var (
-	mysqlConn *sql.DB
+	mysqlConn     *sql.DB
+	mysqlPassword string
+	firstProxy    string
 )
 
 var _ = BeforeSuite(func() {
@@ -39,10 +41,11 @@ var _ = BeforeSuite(func() {
 		helpers.SetupSocks5Proxy()
 	}
 
+	var err error
 	mysqlUsername := "root"
-	mysqlPassword, err := helpers.GetMySQLAdminPassword()
+	mysqlPassword, err = helpers.GetMySQLAdminPassword()
 	Expect(err).NotTo(HaveOccurred())
-	firstProxy, err := helpers.FirstProxyHost(helpers.BoshDeployment)
+	firstProxy, err = helpers.FirstProxyHost(helpers.BoshDeployment)
 	Expect(err).NotTo(HaveOccurred())
 	mysqlConn = helpers.DbConnWithUser(mysqlUsername, mysqlPassword, firstProxy)
 })
@@ -0,0 +1,155 @@
+package tls_test
+
+import (
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	helpers "specs/test_helpers"
+)
+
+var _ = Describe("TLS verification", func() {
+	It("presents a certificate issued by the deployment's server CA", func() {
+		serverCA, err := helpers.GetMySQLServerCA(helpers.BoshDeployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(helpers.RegisterPinnedTLSConfig("deployment-ca", serverCA)).To(Succeed())
+
+		db, err := helpers.DbConnWithTLSConfig("root", mysqlPassword, firstProxy, "deployment-ca")
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+		Expect(db.Ping()).To(Succeed())
+
+		status, err := helpers.SessionTLSStatus(db)
+		Expect(err).NotTo(HaveOccurred())
+		switch status.Version {
+		case "TLSv1.3":
+			// TLS 1.3 suites do not name the key exchange.
+			Expect(status.Cipher).To(MatchRegexp("^TLS_(AES_(128|256)_GCM_SHA(256|384)|CHACHA20_POLY1305_SHA256)$"))
+		case "TLSv1.2":
+			Expect(status.Cipher).To(MatchRegexp("^ECDHE-RSA-.*GCM"))
+		default:
+			Fail(fmt.Sprintf("negotiated %s, expected TLSv1.2 or TLSv1.3", status.Version))
+		}
+	})
+
+	It("is refused by clients that trust another CA", func() {
+		otherCA, err := helpers.NewCA("untrusted-ca")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(helpers.RegisterPinnedTLSConfig("untrusted-ca", otherCA)).To(Succeed())
+
+		db, err := helpers.DbConnWithTLSConfig("root", mysqlPassword, firstProxy, "untrusted-ca")
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+		Expect(db.Ping()).To(MatchError(ContainSubstring("certificate signed by unknown authority")))
+	})
+
+	// require_secure_transport is not set by the job, so the spec turns it
+	// on for its duration. It connects to the nodes directly rather than
+	// through the proxy, since galera-agent connects to mysql over TCP and
+	// the proxy may mark nodes unhealthy while it is on.
+	Context("when secure transport is required", func() {
+		var (
+			hosts    []string
+			restored []*sql.DB
+		)
+
+		BeforeEach(func() {
+			restored = nil
+
+			var err error
+			hosts, err = helpers.MySQLHosts(helpers.BoshDeployment)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, host := range hosts {
+				node := helpers.DbConnWithUser("root", mysqlPassword, host)
+
+				required, err := helpers.RequiresSecureTransport(node)
+				if err == nil && !required {
+					err = helpers.SetRequireSecureTransport(node, true)
+					if err == nil {
+						restored = append(restored, node)
+						continue
+					}
+				}
+				node.Close()
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		// The setting is restored on every node even when the spec or
+		// BeforeEach fails, so that the cluster stays usable for galera-agent,
+		// the proxy health checks and later specs.
+		AfterEach(func() {
+			var errs []error
+			for _, node := range restored {
+				if err := helpers.SetRequireSecureTransport(node, false); err != nil {
+					errs = append(errs, err)
+				}
+				node.Close()
+			}
+			restored = nil
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("rejects plaintext connections", func() {
+			for _, host := range hosts {
+				db, err := helpers.DbConnWithTLSConfig("root", mysqlPassword, host, "false")
+				Expect(err).NotTo(HaveOccurred())
+				defer db.Close()
+
+				err = db.Ping()
+				Expect(helpers.IsInsecureTransportError(err)).To(BeTrue(), fmt.Sprintf("expected ER_SECURE_TRANSPORT_REQUIRED from %s, got %v", host, err))
+			}
+		})
+	})
+
+	Describe("galera", func() {
+		var galera helpers.Certificate
+
+		BeforeEach(func() {
+			var err error
+			galera, err = helpers.GetGaleraCertificate(helpers.BoshDeployment)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("replicates over TLS with the galera certificates", func() {
+			options, err := helpers.GaleraProviderOptions(mysqlConn)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(options).To(HaveKeyWithValue("socket.ssl", "YES"))
+			Expect(options).To(HaveKeyWithValue("socket.ssl_ca", "/var/vcap/jobs/pxc-mysql/certificates/galera-ca.pem"))
+
+			hosts, err := helpers.MySQLHosts(helpers.BoshDeployment)
+			Expect(err).NotTo(HaveOccurred())
+			// Galera only completes the handshake with peers that present a
+			// certificate, so the probe presents the nodes' own.
+			clientCertificate, err := galera.KeyPair()
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, host := range hosts {
+				probe := helpers.ProbeTLS(net.JoinHostPort(host, "4567"), galera.CA, clientCertificate)
+				Expect(probe.Certificates).NotTo(BeEmpty(), "%s:4567 did not answer with TLS: %v", host, probe.HandshakeErr)
+				Expect(probe.HandshakeErr).NotTo(HaveOccurred(), host)
+				Expect(probe.VerifyErr).NotTo(HaveOccurred(), host)
+				Expect(probe.Version).To(BeNumerically(">=", tls.VersionTLS12), host)
+				Expect(helpers.IsSecureCipherSuite(probe.CipherSuite)).To(BeTrue(), "%s negotiated %s", host, tls.CipherSuiteName(probe.CipherSuite))
+			}
+		})
+
+		It("encrypts SST with the galera certificates", func() {
+			options, err := helpers.SSTOptions(helpers.BoshDeployment, "mysql", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(options).NotTo(BeEmpty())
+
+			for instance, sst := range options {
+				Expect(sst).To(HaveKeyWithValue("encrypt", "4"), instance)
+				Expect(sst).To(HaveKeyWithValue("ssl-ca", "/var/vcap/jobs/pxc-mysql/certificates/galera-ca.pem"), instance)
+				Expect(sst).To(HaveKeyWithValue("ssl-cert", "/var/vcap/jobs/pxc-mysql/certificates/galera-cert.pem"), instance)
+				Expect(sst).To(HaveKeyWithValue("ssl-key", "/var/vcap/jobs/pxc-mysql/certificates/galera-key.pem"), instance)
+			}
+		})
+	})
+})
@@ -14,9 +14,21 @@ import (
 var (
 	BoshDeployment    boshdir.Deployment
 	BoshCredhubPrefix string
+
+	boshDirector boshdir.Director
 )
 
+// UseBoshDirector makes BuildBoshDirector return director, such as a
+// fakedirector.Director, instead of connecting to BOSH_ENVIRONMENT. Pass nil
+// to connect again.
+func UseBoshDirector(director boshdir.Director) {
+	boshDirector = director
+}
+
 func BuildBoshDirector() (boshdir.Director, error) {
+	if boshDirector != nil {
+		return boshDirector, nil
+	}
 
 	logger := boshlog.NewLogger(boshlog.LevelError)
 	factory := boshdir.NewFactory(logger)
@@ -131,4 +143,4 @@ func FirstProxyHost(boshDeployment boshdir.Deployment) (string, error) {
 	}
 
 	return proxyHosts[0], nil
-}
\ No newline at end of file
+}
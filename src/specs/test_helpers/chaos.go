@@ -0,0 +1,244 @@
+package test_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	boshdir "github.com/cloudfoundry/bosh-cli/director"
+)
+
+var (
+	// GaleraPorts carry group communication, IST and SST.
+	GaleraPorts = []int{4567, 4568, 4444}
+	// MySQLPorts carry client connections and the proxy's health checks.
+	MySQLPorts = []int{3306, 9200}
+)
+
+// chaosChain is the iptables chain that holds every rule Chaos adds, so
+// that healing never touches rules that belong to the deployment.
+const chaosChain = "PXC_CHAOS"
+
+// RemoteCommand runs a shell command as root on a BOSH instance, such as
+// "mysql/0", and returns its output.
+type RemoteCommand func(instance, command string) (string, error)
+
+// ChaosNode is an instance that Chaos can disrupt.
+type ChaosNode struct {
+	Instance string
+	IP       string
+}
+
+// Chaos injects network faults between instances with iptables and tc.
+// Every instance it touches is remembered, and Heal removes all faults from
+// all of them. Create it with NewChaos so that HealAllChaos can find it.
+type Chaos struct {
+	Run RemoteCommand
+
+	mutex   sync.Mutex
+	touched map[string]bool
+}
+
+var (
+	chaosMutex     sync.Mutex
+	chaosInstances []*Chaos
+)
+
+// NewChaos returns a Chaos that runs commands with run, or with BoshSSH when
+// run is nil.
+func NewChaos(run RemoteCommand) *Chaos {
+	if run == nil {
+		run = BoshSSH
+	}
+
+	chaos := &Chaos{Run: run, touched: map[string]bool{}}
+
+	chaosMutex.Lock()
+	defer chaosMutex.Unlock()
+	chaosInstances = append(chaosInstances, chaos)
+	return chaos
+}
+
+// HealAllChaos heals every Chaos created with NewChaos. Call it from an
+// AfterSuite so that faults do not outlive a failed or interrupted spec.
+func HealAllChaos() error {
+	chaosMutex.Lock()
+	instances := append([]*Chaos(nil), chaosInstances...)
+	chaosMutex.Unlock()
+
+	var failures []string
+	for _, chaos := range instances {
+		if err := chaos.Heal(); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// ChaosNodes returns the instances of an instance group as chaos targets.
+func ChaosNodes(deployment boshdir.Deployment, instanceGroupName string) ([]ChaosNode, error) {
+	instances, err := deployment.Instances()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []ChaosNode
+	for _, instance := range instances {
+		if instance.Group == instanceGroupName && len(instance.IPs) > 0 {
+			nodes = append(nodes, ChaosNode{Instance: instanceGroupName + "/" + instance.ID, IP: instance.IPs[0]})
+		}
+	}
+	return nodes, nil
+}
+
+// Partition drops traffic on ports between a and b in both directions.
+func (c *Chaos) Partition(a, b ChaosNode, ports []int) error {
+	if err := c.PartitionOneWay(a, b, ports); err != nil {
+		return err
+	}
+	return c.PartitionOneWay(b, a, ports)
+}
+
+// PartitionOneWay drops packets that from sends to to's ports. Replies to
+// connections to opened are unaffected.
+func (c *Chaos) PartitionOneWay(from, to ChaosNode, ports []int) error {
+	rule := fmt.Sprintf("-s %s -p tcp -m multiport --dports %s -j DROP", from.IP, joinPorts(ports))
+	return c.run(to, c.chainSetup()+
+		fmt.Sprintf("iptables -C %s %s 2>/dev/null || iptables -A %s %s", chaosChain, rule, chaosChain, rule))
+}
+
+// Isolate partitions node from every peer on ports.
+func (c *Chaos) Isolate(node ChaosNode, peers []ChaosNode, ports []int) error {
+	for _, peer := range peers {
+		if peer.Instance == node.Instance {
+			continue
+		}
+		if err := c.Partition(node, peer, ports); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delay adds latency to packets node sends to each peer.
+func (c *Chaos) Delay(node ChaosNode, peers []ChaosNode, delay time.Duration) error {
+	if len(peers) == 0 {
+		return fmt.Errorf("no peers to delay traffic to")
+	}
+
+	iface := fmt.Sprintf(`$(ip route get %s | grep -o 'dev [^ ]*' | cut -d' ' -f2)`, peers[0].IP)
+	script := []string{
+		fmt.Sprintf("iface=%s", iface),
+		`tc qdisc show dev "${iface}" | grep -q 'qdisc prio 1:' || tc qdisc add dev "${iface}" root handle 1: prio`,
+		fmt.Sprintf(`tc qdisc replace dev "${iface}" parent 1:3 handle 30: netem delay %dms`, delay.Nanoseconds()/int64(time.Millisecond)),
+	}
+	for _, peer := range peers {
+		script = append(script, fmt.Sprintf(`tc filter add dev "${iface}" protocol ip parent 1:0 prio 3 u32 match ip dst %s/32 flowid 1:3`, peer.IP))
+	}
+	return c.run(node, strings.Join(script, "; "))
+}
+
+// Heal removes all partitions and delays from every instance Chaos has
+// touched. It tries every instance and reports all failures.
+func (c *Chaos) Heal() error {
+	c.mutex.Lock()
+	var instances []string
+	for instance := range c.touched {
+		instances = append(instances, instance)
+	}
+	c.mutex.Unlock()
+	sort.Strings(instances)
+
+	heal := fmt.Sprintf("iptables -D INPUT -j %s 2>/dev/null; iptables -F %s 2>/dev/null; iptables -X %s 2>/dev/null; "+
+		`for iface in $(ls /sys/class/net); do tc qdisc del dev "${iface}" root 2>/dev/null; done; true`,
+		chaosChain, chaosChain, chaosChain)
+
+	var failures []string
+	for _, instance := range instances {
+		if _, err := c.Run(instance, heal); err != nil {
+			failures = append(failures, fmt.Sprintf("healing %s: %s", instance, err))
+			continue
+		}
+		c.mutex.Lock()
+		delete(c.touched, instance)
+		c.mutex.Unlock()
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// chainSetup creates the chaos chain and jumps to it from INPUT, unless a
+// previous run already did.
+func (c *Chaos) chainSetup() string {
+	return fmt.Sprintf("iptables -N %s 2>/dev/null; iptables -C INPUT -j %s 2>/dev/null || iptables -I INPUT -j %s; ",
+		chaosChain, chaosChain, chaosChain)
+}
+
+// run records the instance before running, so that a command that fails
+// half way is still healed.
+func (c *Chaos) run(node ChaosNode, command string) error {
+	c.mutex.Lock()
+	c.touched[node.Instance] = true
+	c.mutex.Unlock()
+
+	output, err := c.Run(node.Instance, command)
+	if err != nil {
+		return fmt.Errorf("running chaos on %s: %s: %s", node.Instance, err, output)
+	}
+	return nil
+}
+
+// BoshSSH runs command as root on instance with the bosh CLI, which reads
+// the environment and deployment from its BOSH_* environment variables.
+func BoshSSH(instance, command string) (string, error) {
+	var result struct {
+		Tables []struct {
+			Rows []struct {
+				Stdout string `json:"stdout"`
+				Stderr string `json:"stderr"`
+			}
+		}
+	}
+
+	output, err := exec.Command(
+		"bosh",
+		"ssh",
+		instance,
+		"--json",
+		"--results",
+		"-c", "sudo bash -c "+shellQuote(command),
+	).Output()
+
+	if jsonErr := json.Unmarshal(output, &result); jsonErr != nil || len(result.Tables) == 0 || len(result.Tables[0].Rows) == 0 {
+		if err == nil {
+			err = fmt.Errorf("unexpected bosh ssh output: %s", output)
+		}
+		return string(output), err
+	}
+
+	row := result.Tables[0].Rows[0]
+	return row.Stdout + row.Stderr, err
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func joinPorts(ports []int) string {
+	var s []string
+	for _, port := range ports {
+		s = append(s, strconv.Itoa(port))
+	}
+	return strings.Join(s, ",")
+}
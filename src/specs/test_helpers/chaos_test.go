@@ -0,0 +1,107 @@
+package test_helpers_test
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	helpers "specs/test_helpers"
+	"specs/test_helpers/fakedirector"
+)
+
+var _ = Describe("Chaos", func() {
+	var (
+		commands map[string][]string
+		failOn   map[string]bool
+		chaos    *helpers.Chaos
+
+		mysql0 = helpers.ChaosNode{Instance: "mysql/0", IP: "10.0.0.2"}
+		mysql1 = helpers.ChaosNode{Instance: "mysql/1", IP: "10.0.0.3"}
+		mysql2 = helpers.ChaosNode{Instance: "mysql/2", IP: "10.0.0.4"}
+	)
+
+	BeforeEach(func() {
+		commands = map[string][]string{}
+		failOn = map[string]bool{}
+		chaos = helpers.NewChaos(func(instance, command string) (string, error) {
+			commands[instance] = append(commands[instance], command)
+			if failOn[instance] {
+				return "iptables: command not found", errors.New("exit status 127")
+			}
+			return "", nil
+		})
+	})
+
+	AfterEach(func() {
+		failOn = map[string]bool{}
+		Expect(helpers.HealAllChaos()).To(Succeed())
+	})
+
+	It("partitions two nodes in both directions", func() {
+		Expect(chaos.Partition(mysql0, mysql1, helpers.GaleraPorts)).To(Succeed())
+
+		Expect(commands["mysql/0"]).To(HaveLen(1))
+		Expect(commands["mysql/0"][0]).To(ContainSubstring("iptables -A PXC_CHAOS -s 10.0.0.3 -p tcp -m multiport --dports 4567,4568,4444 -j DROP"))
+		Expect(commands["mysql/1"][0]).To(ContainSubstring("iptables -A PXC_CHAOS -s 10.0.0.2 -p tcp -m multiport --dports 4567,4568,4444 -j DROP"))
+		Expect(commands["mysql/1"][0]).To(ContainSubstring("iptables -I INPUT -j PXC_CHAOS"))
+	})
+
+	It("partitions one direction only", func() {
+		Expect(chaos.PartitionOneWay(mysql0, mysql1, helpers.MySQLPorts)).To(Succeed())
+
+		Expect(commands).NotTo(HaveKey("mysql/0"))
+		Expect(commands["mysql/1"][0]).To(ContainSubstring("-s 10.0.0.2 -p tcp -m multiport --dports 3306,9200 -j DROP"))
+	})
+
+	It("isolates a node from its peers", func() {
+		Expect(chaos.Isolate(mysql0, []helpers.ChaosNode{mysql0, mysql1, mysql2}, helpers.GaleraPorts)).To(Succeed())
+
+		Expect(commands["mysql/0"]).To(HaveLen(2))
+		Expect(commands["mysql/1"]).To(HaveLen(1))
+		Expect(commands["mysql/2"]).To(HaveLen(1))
+	})
+
+	It("delays traffic to the chosen peers", func() {
+		Expect(chaos.Delay(mysql0, []helpers.ChaosNode{mysql1, mysql2}, 250*time.Millisecond)).To(Succeed())
+
+		command := commands["mysql/0"][0]
+		Expect(command).To(ContainSubstring("netem delay 250ms"))
+		Expect(command).To(ContainSubstring("match ip dst 10.0.0.3/32"))
+		Expect(command).To(ContainSubstring("match ip dst 10.0.0.4/32"))
+	})
+
+	It("heals every node it touched, even when one fails", func() {
+		Expect(chaos.Partition(mysql0, mysql1, helpers.GaleraPorts)).To(Succeed())
+		failOn["mysql/2"] = true
+		Expect(chaos.Delay(mysql2, []helpers.ChaosNode{mysql0}, time.Second)).To(MatchError(ContainSubstring("running chaos on mysql/2")))
+
+		err := chaos.Heal()
+		Expect(err).To(MatchError("healing mysql/2: exit status 127"))
+		for _, instance := range []string{"mysql/0", "mysql/1", "mysql/2"} {
+			last := commands[instance][len(commands[instance])-1]
+			Expect(last).To(ContainSubstring("iptables -X PXC_CHAOS"))
+			Expect(last).To(ContainSubstring("tc qdisc del"))
+		}
+
+		failOn["mysql/2"] = false
+		Expect(helpers.HealAllChaos()).To(Succeed())
+		Expect(strings.Join(commands["mysql/0"], "\n")).To(ContainSubstring("iptables -F PXC_CHAOS"))
+		Expect(commands["mysql/0"]).To(HaveLen(2))
+		Expect(commands["mysql/2"]).To(HaveLen(3))
+	})
+
+	It("finds the nodes of an instance group", func() {
+		director := fakedirector.NewDirector("fake")
+		deployment, err := director.AddDeployment("pxc", []byte("instance_groups: [{name: mysql, instances: 2}, {name: proxy, instances: 1}]"))
+		Expect(err).NotTo(HaveOccurred())
+
+		nodes, err := helpers.ChaosNodes(deployment, "mysql")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(HaveLen(2))
+		Expect(nodes[0].Instance).To(HavePrefix("mysql/"))
+		Expect(nodes[0].IP).NotTo(BeEmpty())
+	})
+})
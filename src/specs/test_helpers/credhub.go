@@ -6,6 +6,7 @@ import (
 
 	"code.cloudfoundry.org/credhub-cli/credhub"
 	"code.cloudfoundry.org/credhub-cli/credhub/auth"
+	boshdir "github.com/cloudfoundry/bosh-cli/director"
 )
 
 func NewCredhubClient() (*credhub.CredHub, error) {
@@ -26,16 +27,22 @@ func NewCredhubClient() (*credhub.CredHub, error) {
 	return chClient, err
 }
 
-func credhubKey(name string) string {
-	return fmt.Sprintf("%s/%s/%s", BoshCredhubPrefix, os.Getenv("BOSH_DEPLOYMENT"), name)
+// CredhubSecretSource reads the deployment's variables from CredHub, under
+// the director and deployment prefix.
+type CredhubSecretSource struct {
+	Client *credhub.CredHub
 }
 
-func GetMySQLAdminPassword() (string, error) {
+func NewCredhubSecretSource() (*CredhubSecretSource, error) {
 	client, err := NewCredhubClient()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	pw, err := client.GetLatestPassword(credhubKey("cf_mysql_mysql_admin_password"))
+	return &CredhubSecretSource{Client: client}, nil
+}
+
+func (s *CredhubSecretSource) Password(name string) (string, error) {
+	pw, err := s.Client.GetLatestPassword(credhubKey(name))
 	if err != nil {
 		return "", err
 	}
@@ -43,28 +50,78 @@ func GetMySQLAdminPassword() (string, error) {
 	return string(pw.Value), nil
 }
 
-func GetGaleraAgentPassword() (string, error) {
-	client, err := NewCredhubClient()
-	if err != nil {
-		return "", err
-	}
-	pw, err := client.GetLatestPassword(credhubKey("cf_mysql_mysql_galera_healthcheck_endpoint_password"))
+func (s *CredhubSecretSource) Certificate(name string) (Certificate, error) {
+	cert, err := s.Client.GetLatestCertificate(credhubKey(name))
 	if err != nil {
-		return "", err
+		return Certificate{}, err
 	}
 
-	return string(pw.Value), nil
+	return Certificate{
+		CA:          cert.Value.Ca,
+		Certificate: cert.Value.Certificate,
+		PrivateKey:  cert.Value.PrivateKey,
+	}, nil
+}
+
+func credhubKey(name string) string {
+	return fmt.Sprintf("%s/%s/%s", BoshCredhubPrefix, os.Getenv("BOSH_DEPLOYMENT"), name)
+}
+
+func GetMySQLAdminPassword() (string, error) {
+	return secretPassword("cf_mysql_mysql_admin_password")
+}
+
+func GetGaleraAgentPassword() (string, error) {
+	return secretPassword("cf_mysql_mysql_galera_healthcheck_endpoint_password")
 }
 
 func GetProxyPassword() (string, error) {
-	client, err := NewCredhubClient()
+	return secretPassword("cf_mysql_proxy_api_password")
+}
+
+// GetMySQLServerCA returns the CA that issued the certificate mysql presents
+// to clients: the one tls.server references in the deployment's manifest.
+func GetMySQLServerCA(deployment boshdir.Deployment) (string, error) {
+	certificate, err := jobCertificate(deployment, "tls.server")
+	return certificate.CA, err
+}
+
+// GetGaleraCA returns the CA that issued the certificate galera and SST use:
+// the one tls.galera references in the deployment's manifest.
+func GetGaleraCA(deployment boshdir.Deployment) (string, error) {
+	certificate, err := GetGaleraCertificate(deployment)
+	return certificate.CA, err
+}
+
+// GetGaleraCertificate returns the certificate tls.galera references in the
+// deployment's manifest. Galera only accepts peers that present it, so it
+// is also the client certificate for probing galera's port.
+func GetGaleraCertificate(deployment boshdir.Deployment) (Certificate, error) {
+	return jobCertificate(deployment, "tls.galera")
+}
+
+func jobCertificate(deployment boshdir.Deployment, property string) (Certificate, error) {
+	manifest, err := Manifest(deployment)
 	if err != nil {
-		return "", err
+		return Certificate{}, err
 	}
-	pw, err := client.GetLatestPassword(credhubKey("cf_mysql_proxy_api_password"))
+
+	name, err := CertificateVariable(manifest, "mysql", "pxc-mysql", property)
 	if err != nil {
-		return "", err
+		return Certificate{}, err
 	}
 
-	return string(pw.Value), nil
+	source, err := Secrets()
+	if err != nil {
+		return Certificate{}, err
+	}
+	return source.Certificate(name)
+}
+
+func secretPassword(name string) (string, error) {
+	source, err := Secrets()
+	if err != nil {
+		return "", err
+	}
+	return source.Password(name)
 }
@@ -0,0 +1,216 @@
+package test_helpers
+
+import (
+	"fmt"
+	"strings"
+
+	boshdir "github.com/cloudfoundry/bosh-cli/director"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ScaleInstanceGroup redeploys with instanceCount instances in the named
+// instance group.
+func ScaleInstanceGroup(deployment boshdir.Deployment, instanceGroupName string, instanceCount int) error {
+	return UpdateManifest(deployment, func(manifest map[string]interface{}) error {
+		group, err := instanceGroup(manifest, instanceGroupName)
+		if err != nil {
+			return err
+		}
+		group["instances"] = instanceCount
+		return nil
+	})
+}
+
+// Manifest returns the deployment's manifest.
+func Manifest(deployment boshdir.Deployment) (map[string]interface{}, error) {
+	manifestString, err := deployment.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("getting manifest: %s", err)
+	}
+
+	var manifest map[string]interface{}
+	err = yaml.Unmarshal([]byte(manifestString), &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling manifest: %s", err)
+	}
+	return manifest, nil
+}
+
+// UpdateManifest redeploys with the deployment's manifest as change leaves
+// it. Nothing is deployed when change fails.
+func UpdateManifest(deployment boshdir.Deployment, change func(manifest map[string]interface{}) error) error {
+	manifest, err := Manifest(deployment)
+	if err != nil {
+		return err
+	}
+
+	err = change(manifest)
+	if err != nil {
+		return err
+	}
+
+	updatedManifest, err := yaml.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("marshalling manifest: %s", err)
+	}
+
+	err = deployment.Update(updatedManifest, boshdir.UpdateOpts{})
+	if err != nil {
+		return fmt.Errorf("deploying: %s", err)
+	}
+
+	return nil
+}
+
+// JobProperty returns a property of a job in an instance group, or nil when
+// it is not set. The property is a dotted path such as "tls.galera".
+func JobProperty(manifest map[string]interface{}, instanceGroupName, jobName, property string) (interface{}, error) {
+	job, err := findJob(manifest, instanceGroupName, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{} = job["properties"]
+	for _, key := range strings.Split(property, ".") {
+		properties, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, nil
+		}
+		value = properties[key]
+	}
+	return value, nil
+}
+
+// CertificateVariable returns the name of the variable a certificate
+// property such as "tls.galera" references, as in ((galera_server_certificate)).
+func CertificateVariable(manifest map[string]interface{}, instanceGroupName, jobName, property string) (string, error) {
+	value, err := JobProperty(manifest, instanceGroupName, jobName, property)
+	if err != nil {
+		return "", err
+	}
+
+	reference, _ := value.(string)
+	if !strings.HasPrefix(reference, "((") || !strings.HasSuffix(reference, "))") {
+		return "", fmt.Errorf("%s of job %s does not reference a variable", property, jobName)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(reference, "(("), "))"), nil
+}
+
+// VariableOptions returns the options of a variable in the manifest.
+func VariableOptions(manifest map[string]interface{}, name string) (map[string]interface{}, error) {
+	variables, _ := manifest["variables"].([]interface{})
+	for _, v := range variables {
+		variable, ok := v.(map[interface{}]interface{})
+		if !ok || variable["name"] != name {
+			continue
+		}
+
+		options := map[string]interface{}{}
+		existing, _ := variable["options"].(map[interface{}]interface{})
+		for key, value := range existing {
+			options[fmt.Sprint(key)] = value
+		}
+		return options, nil
+	}
+	return nil, fmt.Errorf("variable %s not found in manifest", name)
+}
+
+// SetJobProperty sets a property of a job in an instance group. The
+// property is a dotted path such as "tls.galera_trusted_cas"; a nil value
+// removes it.
+func SetJobProperty(manifest map[string]interface{}, instanceGroupName, jobName, property string, value interface{}) error {
+	job, err := findJob(manifest, instanceGroupName, jobName)
+	if err != nil {
+		return err
+	}
+
+	properties, ok := job["properties"].(map[interface{}]interface{})
+	if !ok {
+		properties = map[interface{}]interface{}{}
+		job["properties"] = properties
+	}
+
+	keys := strings.Split(property, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := properties[key].(map[interface{}]interface{})
+		if !ok {
+			next = map[interface{}]interface{}{}
+			properties[key] = next
+		}
+		properties = next
+	}
+
+	last := keys[len(keys)-1]
+	if value == nil {
+		delete(properties, last)
+	} else {
+		properties[last] = value
+	}
+	return nil
+}
+
+// SetVariable adds a variable to the manifest, or replaces the one with the
+// same name.
+func SetVariable(manifest map[string]interface{}, name, variableType string, options map[string]interface{}) {
+	variable := map[interface{}]interface{}{"name": name, "type": variableType}
+	if options != nil {
+		variable["options"] = options
+	}
+
+	variables, _ := manifest["variables"].([]interface{})
+	for i, v := range variables {
+		if existing, ok := v.(map[interface{}]interface{}); ok && existing["name"] == name {
+			variables[i] = variable
+			return
+		}
+	}
+	manifest["variables"] = append(variables, variable)
+}
+
+func findJob(manifest map[string]interface{}, instanceGroupName, jobName string) (map[interface{}]interface{}, error) {
+	group, err := instanceGroup(manifest, instanceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, _ := group["jobs"].([]interface{})
+	for _, j := range jobs {
+		job, ok := j.(map[interface{}]interface{})
+		if ok && job["name"] == jobName {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("job %s not found in instance group %s", jobName, instanceGroupName)
+}
+
+func instanceGroup(manifest map[string]interface{}, name string) (map[interface{}]interface{}, error) {
+	instanceGroups, _ := manifest["instance_groups"].([]interface{})
+	for _, instanceGroup := range instanceGroups {
+		group, ok := instanceGroup.(map[interface{}]interface{})
+		if ok && group["name"] == name {
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("instance group %s not found in manifest", name)
+}
+
+// VMIDForHost returns the VM CID of the instance in the named instance group
+// whose first IP is host, or whose ID is the first label of host.
+func VMIDForHost(deployment boshdir.Deployment, instanceGroupName, host string) (string, error) {
+	instances, err := deployment.Instances()
+	if err != nil {
+		return "", fmt.Errorf("listing instances: %s", err)
+	}
+
+	for _, instance := range instances {
+		if instance.Group != instanceGroupName {
+			continue
+		}
+		hostArray := strings.Split(host, ".")
+		if instance.IPs[0] == host || (len(hostArray) > 0 && hostArray[0] == instance.ID) {
+			return instance.VMID, nil
+		}
+	}
+
+	return "", fmt.Errorf("no vm found with %s", host)
+}
@@ -0,0 +1,136 @@
+package test_helpers_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+
+	helpers "specs/test_helpers"
+	"specs/test_helpers/fakedirector"
+)
+
+var _ = Describe("UpdateManifest", func() {
+	var fake *fakedirector.Deployment
+
+	BeforeEach(func() {
+		var err error
+		fake, err = fakedirector.NewDirector("fake").AddDeployment("pxc", []byte(`
+instance_groups:
+- name: mysql
+  instances: 1
+  jobs:
+  - name: pxc-mysql
+    properties:
+      tls:
+        galera: ((galera_server_certificate))
+variables:
+- name: pxc_galera_ca
+  type: certificate
+`))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	deployed := func() map[string]interface{} {
+		manifestString, err := fake.Manifest()
+		Expect(err).NotTo(HaveOccurred())
+		var manifest map[string]interface{}
+		Expect(yaml.Unmarshal([]byte(manifestString), &manifest)).To(Succeed())
+		return manifest
+	}
+
+	properties := func() map[interface{}]interface{} {
+		group := deployed()["instance_groups"].([]interface{})[0].(map[interface{}]interface{})
+		job := group["jobs"].([]interface{})[0].(map[interface{}]interface{})
+		return job["properties"].(map[interface{}]interface{})
+	}
+
+	It("sets and removes nested job properties and variables", func() {
+		err := helpers.UpdateManifest(fake, func(manifest map[string]interface{}) error {
+			helpers.SetVariable(manifest, "pxc_galera_ca_2", "certificate", map[string]interface{}{"is_ca": true})
+			return helpers.SetJobProperty(manifest, "mysql", "pxc-mysql", "tls.galera_trusted_cas", "((pxc_galera_ca_2.certificate))")
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(properties()).To(Equal(map[interface{}]interface{}{
+			"tls": map[interface{}]interface{}{
+				"galera":             "((galera_server_certificate))",
+				"galera_trusted_cas": "((pxc_galera_ca_2.certificate))",
+			},
+		}))
+		Expect(deployed()["variables"]).To(HaveLen(2))
+
+		err = helpers.UpdateManifest(fake, func(manifest map[string]interface{}) error {
+			helpers.SetVariable(manifest, "pxc_galera_ca", "certificate", map[string]interface{}{"common_name": "renamed"})
+			return helpers.SetJobProperty(manifest, "mysql", "pxc-mysql", "tls.galera_trusted_cas", nil)
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(properties()).To(Equal(map[interface{}]interface{}{
+			"tls": map[interface{}]interface{}{"galera": "((galera_server_certificate))"},
+		}))
+		Expect(deployed()["variables"]).To(HaveLen(2))
+		Expect(fake.Updates()).To(HaveLen(2))
+	})
+
+	It("does not deploy when the change fails", func() {
+		err := helpers.UpdateManifest(fake, func(manifest map[string]interface{}) error {
+			return helpers.SetJobProperty(manifest, "mysql", "proxy", "port", 3306)
+		})
+		Expect(err).To(MatchError("job proxy not found in instance group mysql"))
+
+		err = helpers.UpdateManifest(fake, func(map[string]interface{}) error { return errors.New("invalid") })
+		Expect(err).To(MatchError("invalid"))
+		Expect(fake.Updates()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("manifest lookups", func() {
+	var manifest map[string]interface{}
+
+	BeforeEach(func() {
+		Expect(yaml.Unmarshal([]byte(`
+instance_groups:
+- name: mysql
+  jobs:
+  - name: pxc-mysql
+    properties:
+      tls:
+        galera: ((galera_server_certificate))
+        server: literal-pem
+variables:
+- name: galera_server_certificate
+  type: certificate
+  options:
+    ca: pxc_galera_ca
+    extended_key_usage: [server_auth, client_auth]
+`), &manifest)).To(Succeed())
+	})
+
+	It("reads job properties", func() {
+		Expect(helpers.JobProperty(manifest, "mysql", "pxc-mysql", "tls.server")).To(Equal("literal-pem"))
+		Expect(helpers.JobProperty(manifest, "mysql", "pxc-mysql", "tls.galera_trusted_cas")).To(BeNil())
+		Expect(helpers.JobProperty(manifest, "mysql", "pxc-mysql", "tls.server.ca")).To(BeNil())
+
+		_, err := helpers.JobProperty(manifest, "proxy", "proxy", "port")
+		Expect(err).To(MatchError("instance group proxy not found in manifest"))
+	})
+
+	It("finds the variable a certificate property references", func() {
+		Expect(helpers.CertificateVariable(manifest, "mysql", "pxc-mysql", "tls.galera")).To(Equal("galera_server_certificate"))
+
+		_, err := helpers.CertificateVariable(manifest, "mysql", "pxc-mysql", "tls.server")
+		Expect(err).To(MatchError("tls.server of job pxc-mysql does not reference a variable"))
+	})
+
+	It("reads variable options", func() {
+		options, err := helpers.VariableOptions(manifest, "galera_server_certificate")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options).To(HaveKeyWithValue("ca", "pxc_galera_ca"))
+		Expect(options).To(HaveKeyWithValue("extended_key_usage", []interface{}{"server_auth", "client_auth"}))
+
+		_, err = helpers.VariableOptions(manifest, "pxc_server_ca")
+		Expect(err).To(MatchError("variable pxc_server_ca not found in manifest"))
+	})
+})
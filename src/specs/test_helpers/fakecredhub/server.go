@@ -0,0 +1,104 @@
+// Package fakecredhub serves the part of the CredHub API that the specs use,
+// so credhub-cli clients can be exercised without UAA or a deployment.
+package fakecredhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/credhub-cli/credhub"
+)
+
+// Server answers GET /api/v1/data?name=...&current=true with the
+// credentials set on it. It requires no authentication.
+type Server struct {
+	*httptest.Server
+
+	mutex       sync.Mutex
+	credentials map[string]credential
+	nextID      int
+}
+
+type credential struct {
+	ID               string      `json:"id"`
+	Name             string      `json:"name"`
+	Type             string      `json:"type"`
+	Value            interface{} `json:"value"`
+	VersionCreatedAt string      `json:"version_created_at"`
+}
+
+func NewServer() *Server {
+	s := &Server{credentials: map[string]credential{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// SetPassword creates or replaces a password credential with the given
+// fully qualified name.
+func (s *Server) SetPassword(name, value string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextID++
+	s.credentials[name] = credential{
+		ID:               fmt.Sprintf("%d", s.nextID),
+		Name:             name,
+		Type:             "password",
+		Value:            value,
+		VersionCreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// SetCertificate creates or replaces a certificate credential with the
+// given fully qualified name.
+func (s *Server) SetCertificate(name, ca, certificate, privateKey string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextID++
+	s.credentials[name] = credential{
+		ID:   fmt.Sprintf("%d", s.nextID),
+		Name: name,
+		Type: "certificate",
+		Value: map[string]string{
+			"ca":          ca,
+			"certificate": certificate,
+			"private_key": privateKey,
+		},
+		VersionCreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Client returns a credhub-cli client for the server.
+func (s *Server) Client() (*credhub.CredHub, error) {
+	return credhub.New(s.URL, credhub.ServerVersion("1.6.0"))
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || r.URL.Path != "/api/v1/data" {
+		writeError(w, http.StatusNotFound, "The request could not be fulfilled because the resource could not be found.")
+		return
+	}
+
+	s.mutex.Lock()
+	cred, found := s.credentials[r.URL.Query().Get("name")]
+	s.mutex.Unlock()
+
+	if !found {
+		writeError(w, http.StatusNotFound, "The request could not be completed because the credential does not exist or you do not have sufficient authorization.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string][]credential{"data": {cred}})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
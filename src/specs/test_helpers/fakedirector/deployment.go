@@ -0,0 +1,292 @@
+package fakedirector
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	boshdir "github.com/cloudfoundry/bosh-cli/director"
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type Deployment struct {
+	directorfakes.FakeDeployment
+
+	name string
+
+	mutex     sync.Mutex
+	manifest  []byte
+	instances []instance
+	nextID    int
+	errands   map[string]Errand
+
+	// UpdateError, when set, fails the next Update without changing the
+	// deployment.
+	UpdateError error
+
+	updates    []Update
+	deletedVMs []string
+	errandRuns []ErrandRun
+}
+
+// Errand is the fixture result of running an errand. Without InstanceGroup,
+// the errand runs on the instances it is given, or once when given none.
+type Errand struct {
+	InstanceGroup string `yaml:"instance_group"`
+	ExitCode      int    `yaml:"exit_code"`
+	Stdout        string `yaml:"stdout"`
+	Stderr        string `yaml:"stderr"`
+	Error         string `yaml:"error"`
+}
+
+type Update struct {
+	Manifest []byte
+	Opts     boshdir.UpdateOpts
+}
+
+type ErrandRun struct {
+	Name        string
+	KeepAlive   bool
+	WhenChanged bool
+	Slugs       []boshdir.InstanceGroupOrInstanceSlug
+}
+
+type instance struct {
+	boshdir.Instance
+	index int
+}
+
+type manifestInstanceGroup struct {
+	Name      string   `yaml:"name"`
+	Instances int      `yaml:"instances"`
+	AZs       []string `yaml:"azs"`
+	Networks  []struct {
+		StaticIPs []string `yaml:"static_ips"`
+	} `yaml:"networks"`
+	Lifecycle string `yaml:"lifecycle"`
+}
+
+func (d *Deployment) Name() string {
+	return d.name
+}
+
+func (d *Deployment) Manifest() (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return string(d.manifest), nil
+}
+
+func (d *Deployment) Instances() ([]boshdir.Instance, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var instances []boshdir.Instance
+	for _, i := range d.instances {
+		instance := i.Instance
+		instance.IPs = append([]string(nil), i.IPs...)
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// Update replaces the manifest and scales each instance group to match it.
+// Surviving instances keep their IDs, VMs and IPs.
+func (d *Deployment) Update(manifest []byte, opts boshdir.UpdateOpts) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.UpdateError != nil {
+		err := d.UpdateError
+		d.UpdateError = nil
+		return err
+	}
+
+	err := d.applyLocked(manifest)
+	if err != nil {
+		return err
+	}
+
+	d.updates = append(d.updates, Update{Manifest: manifest, Opts: opts})
+	return nil
+}
+
+// DeleteVM deletes a VM, which is immediately recreated for the same
+// instance with a new CID, as the resurrector would.
+func (d *Deployment) DeleteVM(cid string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.instances {
+		if d.instances[i].VMID == cid {
+			d.deletedVMs = append(d.deletedVMs, cid)
+			d.nextID++
+			d.instances[i].VMID = fmt.Sprintf("vm-%d", d.nextID)
+			return nil
+		}
+	}
+	return fmt.Errorf("VM '%s' not found", cid)
+}
+
+func (d *Deployment) RunErrand(name string, keepAlive, whenChanged bool, slugs []boshdir.InstanceGroupOrInstanceSlug) ([]boshdir.ErrandResult, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.errandRuns = append(d.errandRuns, ErrandRun{Name: name, KeepAlive: keepAlive, WhenChanged: whenChanged, Slugs: slugs})
+
+	errand, found := d.errands[name]
+	if !found {
+		return nil, fmt.Errorf("errand '%s' doesn't exist", name)
+	}
+	if errand.Error != "" {
+		return nil, fmt.Errorf("%s", errand.Error)
+	}
+
+	targets, err := d.errandTargets(errand, slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []boshdir.ErrandResult
+	for _, target := range targets {
+		results = append(results, boshdir.ErrandResult{
+			InstanceGroup: target.Group,
+			InstanceID:    target.ID,
+			ExitCode:      errand.ExitCode,
+			Stdout:        errand.Stdout,
+			Stderr:        errand.Stderr,
+		})
+	}
+	return results, nil
+}
+
+// AddErrand defines or replaces the result of an errand.
+func (d *Deployment) AddErrand(name string, errand Errand) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.errands[name] = errand
+}
+
+func (d *Deployment) Updates() []Update {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]Update(nil), d.updates...)
+}
+
+func (d *Deployment) DeletedVMs() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]string(nil), d.deletedVMs...)
+}
+
+func (d *Deployment) ErrandRuns() []ErrandRun {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]ErrandRun(nil), d.errandRuns...)
+}
+
+func (d *Deployment) errandTargets(errand Errand, slugs []boshdir.InstanceGroupOrInstanceSlug) ([]instance, error) {
+	if len(slugs) == 0 && errand.InstanceGroup != "" {
+		slugs = []boshdir.InstanceGroupOrInstanceSlug{boshdir.NewInstanceGroupOrInstanceSlug(errand.InstanceGroup, "")}
+	}
+	if len(slugs) == 0 {
+		return []instance{{}}, nil
+	}
+
+	var targets []instance
+	for _, slug := range slugs {
+		var matched bool
+		for _, i := range d.instances {
+			if i.matches(slug) {
+				targets = append(targets, i)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no instances match '%s'", slug)
+		}
+	}
+	return targets, nil
+}
+
+func (i instance) matches(slug boshdir.InstanceGroupOrInstanceSlug) bool {
+	if i.Group != slug.Name() {
+		return false
+	}
+	indexOrID := slug.IndexOrID()
+	return indexOrID == "" || indexOrID == i.ID || indexOrID == strconv.Itoa(i.index)
+}
+
+func (d *Deployment) apply(manifest []byte) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.applyLocked(manifest)
+}
+
+func (d *Deployment) applyLocked(manifest []byte) error {
+	var parsed struct {
+		InstanceGroups []manifestInstanceGroup `yaml:"instance_groups"`
+	}
+	err := yaml.Unmarshal(manifest, &parsed)
+	if err != nil {
+		return fmt.Errorf("parsing manifest: %s", err)
+	}
+
+	existing := map[string][]instance{}
+	for _, i := range d.instances {
+		existing[i.Group] = append(existing[i.Group], i)
+	}
+
+	var instances []instance
+	for _, group := range parsed.InstanceGroups {
+		for index := 0; index < group.Instances; index++ {
+			if index < len(existing[group.Name]) {
+				instances = append(instances, existing[group.Name][index])
+				continue
+			}
+			instances = append(instances, d.newInstance(group, index))
+		}
+	}
+
+	d.manifest = manifest
+	d.instances = instances
+	return nil
+}
+
+// newInstance creates an instance with predictable identifiers. IPs come
+// from the group's static IPs when it has them.
+func (d *Deployment) newInstance(group manifestInstanceGroup, index int) instance {
+	d.nextID++
+	id := d.nextID
+
+	ip := fmt.Sprintf("10.0.%d.%d", id/250, id%250+2)
+	if len(group.Networks) > 0 && index < len(group.Networks[0].StaticIPs) {
+		ip = group.Networks[0].StaticIPs[index]
+	}
+
+	az := ""
+	if len(group.AZs) > 0 {
+		az = group.AZs[index%len(group.AZs)]
+	}
+
+	return instance{
+		Instance: boshdir.Instance{
+			AgentID:   fmt.Sprintf("agent-%d", id),
+			VMID:      fmt.Sprintf("vm-%d", id),
+			ID:        fmt.Sprintf("%08x-0000-4000-8000-%012x", id, id),
+			Group:     group.Name,
+			AZ:        az,
+			ExpectsVM: group.Lifecycle != "errand",
+			IPs:       []string{ip},
+		},
+		index: index,
+	}
+}
+
+func relativeTo(fixturePath, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(fixturePath), path)
+}
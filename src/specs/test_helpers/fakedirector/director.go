@@ -0,0 +1,135 @@
+// Package fakedirector is an in-memory BOSH director for exercising the
+// orchestration in test_helpers and the integration suites without a
+// deployed environment. Instances are derived from the deployment manifest
+// and follow it through Update; errand results come from fixtures.
+//
+// Only the calls the suites make are modelled: Info and FindDeployment on
+// the director, and Name, Manifest, Instances, Update, DeleteVM and
+// RunErrand on deployments. Other methods behave like the counterfeiter
+// fakes they are inherited from.
+package fakedirector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	boshdir "github.com/cloudfoundry/bosh-cli/director"
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type Director struct {
+	directorfakes.FakeDirector
+
+	name string
+
+	mutex       sync.Mutex
+	deployments map[string]*Deployment
+}
+
+// Fixture describes a director and its deployments. Manifests are inline or
+// read from ManifestFile, relative to the fixture.
+type Fixture struct {
+	Name        string              `yaml:"name"`
+	Deployments []DeploymentFixture `yaml:"deployments"`
+}
+
+type DeploymentFixture struct {
+	Name         string            `yaml:"name"`
+	Manifest     string            `yaml:"manifest"`
+	ManifestFile string            `yaml:"manifest_file"`
+	Errands      map[string]Errand `yaml:"errands"`
+}
+
+func NewDirector(name string) *Director {
+	return &Director{
+		name:        name,
+		deployments: map[string]*Deployment{},
+	}
+}
+
+// LoadFixture builds a director from a YAML fixture file.
+func LoadFixture(path string) (*Director, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	err = yaml.Unmarshal(data, &fixture)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	director := NewDirector(fixture.Name)
+	for _, d := range fixture.Deployments {
+		manifest := []byte(d.Manifest)
+		if d.ManifestFile != "" {
+			manifest, err = ioutil.ReadFile(relativeTo(path, d.ManifestFile))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		deployment, err := director.AddDeployment(d.Name, manifest)
+		if err != nil {
+			return nil, err
+		}
+		for name, errand := range d.Errands {
+			deployment.AddErrand(name, errand)
+		}
+	}
+
+	return director, nil
+}
+
+// AddDeployment creates a deployment whose instances match manifest.
+func (d *Director) AddDeployment(name string, manifest []byte) (*Deployment, error) {
+	deployment := &Deployment{
+		name:    name,
+		errands: map[string]Errand{},
+	}
+	err := deployment.apply(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.deployments[name] = deployment
+	return deployment, nil
+}
+
+func (d *Director) Info() (boshdir.Info, error) {
+	return boshdir.Info{Name: d.name, UUID: d.name + "-uuid", Version: "fake"}, nil
+}
+
+func (d *Director) FindDeployment(name string) (boshdir.Deployment, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	deployment, found := d.deployments[name]
+	if !found {
+		return nil, fmt.Errorf("deployment '%s' doesn't exist", name)
+	}
+	return deployment, nil
+}
+
+func (d *Director) Deployments() ([]boshdir.Deployment, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var names []string
+	for name := range d.deployments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var deployments []boshdir.Deployment
+	for _, name := range names {
+		deployments = append(deployments, d.deployments[name])
+	}
+	return deployments, nil
+}
@@ -0,0 +1,161 @@
+package fakedirector_test
+
+import (
+	"errors"
+
+	boshdir "github.com/cloudfoundry/bosh-cli/director"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	helpers "specs/test_helpers"
+	"specs/test_helpers/fakedirector"
+)
+
+var _ = Describe("Director", func() {
+	var (
+		director   *fakedirector.Director
+		deployment boshdir.Deployment
+		fake       *fakedirector.Deployment
+	)
+
+	BeforeEach(func() {
+		var err error
+		director, err = fakedirector.LoadFixture("fixtures/pxc.yml")
+		Expect(err).NotTo(HaveOccurred())
+
+		deployment, err = director.FindDeployment("pxc")
+		Expect(err).NotTo(HaveOccurred())
+		fake = deployment.(*fakedirector.Deployment)
+	})
+
+	It("describes the director", func() {
+		info, err := director.Info()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Name).To(Equal("fake-director"))
+	})
+
+	It("fails to find unknown deployments", func() {
+		_, err := director.FindDeployment("cf")
+		Expect(err).To(MatchError("deployment 'cf' doesn't exist"))
+	})
+
+	It("creates instances from the manifest", func() {
+		instances, err := deployment.Instances()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instances).To(HaveLen(5))
+
+		Expect(instances[0].Group).To(Equal("mysql"))
+		Expect(instances[0].AZ).To(Equal("z1"))
+		Expect(instances[2].AZ).To(Equal("z3"))
+		Expect(instances[3].IPs).To(Equal([]string{"10.0.9.10"}))
+
+		mysqlHosts, err := helpers.MySQLHosts(deployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mysqlHosts).To(HaveLen(3))
+
+		proxyHost, err := helpers.FirstProxyHost(deployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxyHost).To(Equal("10.0.9.10"))
+	})
+
+	Context("scaling", func() {
+		It("keeps surviving instances and adds new ones", func() {
+			before, err := deployment.Instances()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(helpers.ScaleInstanceGroup(deployment, "mysql", 1)).To(Succeed())
+			mysqlHosts, err := helpers.MySQLHosts(deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mysqlHosts).To(Equal(before[0].IPs))
+
+			Expect(helpers.ScaleInstanceGroup(deployment, "mysql", 3)).To(Succeed())
+			after, err := deployment.Instances()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(after[0]).To(Equal(before[0]))
+			Expect(after[1].ID).NotTo(Equal(before[1].ID))
+
+			Expect(fake.Updates()).To(HaveLen(2))
+			manifest, err := deployment.Manifest()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest).To(Equal(string(fake.Updates()[1].Manifest)))
+			Expect(manifest).To(ContainSubstring("innodb_buffer_pool_size_percent: 50"))
+		})
+
+		It("fails for unknown instance groups", func() {
+			err := helpers.ScaleInstanceGroup(deployment, "arbitrator", 1)
+			Expect(err).To(MatchError("instance group arbitrator not found in manifest"))
+			Expect(fake.Updates()).To(BeEmpty())
+		})
+
+		It("reports injected update failures", func() {
+			fake.UpdateError = errors.New("canary failed")
+			err := helpers.ScaleInstanceGroup(deployment, "mysql", 1)
+			Expect(err).To(MatchError("deploying: canary failed"))
+
+			mysqlHosts, err := helpers.MySQLHosts(deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mysqlHosts).To(HaveLen(3))
+		})
+	})
+
+	It("recreates deleted VMs", func() {
+		mysqlHosts, err := helpers.MySQLHosts(deployment)
+		Expect(err).NotTo(HaveOccurred())
+
+		vmcid, err := helpers.VMIDForHost(deployment, "mysql", mysqlHosts[1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deployment.DeleteVM(vmcid)).To(Succeed())
+		Expect(fake.DeletedVMs()).To(Equal([]string{vmcid}))
+
+		newVMCID, err := helpers.VMIDForHost(deployment, "mysql", mysqlHosts[1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newVMCID).NotTo(Equal(vmcid))
+
+		Expect(deployment.DeleteVM(vmcid)).To(MatchError("VM '" + vmcid + "' not found"))
+	})
+
+	Context("errands", func() {
+		It("runs on the given instances", func() {
+			slugs := []boshdir.InstanceGroupOrInstanceSlug{boshdir.NewInstanceGroupOrInstanceSlug("mysql", "0")}
+			results, err := deployment.RunErrand("bootstrap", false, false, slugs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].InstanceGroup).To(Equal("mysql"))
+			Expect(results[0].Stdout).To(Equal("Successfully bootstrapped cluster"))
+
+			Expect(fake.ErrandRuns()).To(Equal([]fakedirector.ErrandRun{{Name: "bootstrap", Slugs: slugs}}))
+		})
+
+		It("runs on every instance of the errand's instance group by default", func() {
+			results, err := deployment.RunErrand("bootstrap", false, false, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(3))
+		})
+
+		It("returns failing results", func() {
+			results, err := deployment.RunErrand("smoke-tests", true, false, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].ExitCode).To(Equal(1))
+			Expect(results[0].Stderr).To(Equal("smoke tests failed"))
+		})
+
+		It("fails for unknown errands and instances", func() {
+			_, err := deployment.RunErrand("unknown", false, false, nil)
+			Expect(err).To(MatchError("errand 'unknown' doesn't exist"))
+
+			slugs := []boshdir.InstanceGroupOrInstanceSlug{boshdir.NewInstanceGroupOrInstanceSlug("mysql", "7")}
+			_, err = deployment.RunErrand("bootstrap", false, false, slugs)
+			Expect(err).To(MatchError("no instances match 'mysql/7'"))
+		})
+	})
+
+	It("replaces the director used by the test helpers", func() {
+		helpers.UseBoshDirector(director)
+		defer helpers.UseBoshDirector(nil)
+
+		built, err := helpers.BuildBoshDirector()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(BeIdenticalTo(director))
+	})
+})
@@ -0,0 +1,13 @@
+package fakedirector_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFakedirector(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fake Director")
+}
@@ -0,0 +1,174 @@
+// Package galeraagent is a client for the galera-agent sidecar that runs
+// next to each mysql node.
+package galeraagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// StartMode selects how StartMySQL starts the node.
+type StartMode string
+
+const (
+	Bootstrap  StartMode = "bootstrap"
+	Join       StartMode = "join"
+	SingleNode StartMode = "single_node"
+)
+
+// Health is the result of the unauthenticated health check that the proxy
+// and the drain scripts use.
+type Health struct {
+	Healthy bool
+	Message string
+}
+
+// Status is the response of api/v1/status.
+type Status struct {
+	WsrepLocalState        int    `json:"wsrep_local_state"`
+	WsrepLocalStateComment string `json:"wsrep_local_state_comment"`
+	WsrepLocalIndex        int    `json:"wsrep_local_index"`
+	Healthy                bool   `json:"healthy"`
+}
+
+// StatusError is returned for unexpected HTTP statuses.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("%s returned HTTP %d: %s", e.URL, e.StatusCode, e.Body)
+}
+
+type Client struct {
+	BaseURL    string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the agent at baseURL, such as
+// http://10.0.0.5:9200.
+func NewClient(baseURL, username, password string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Username:   username,
+		Password:   password,
+		HTTPClient: httpClient,
+	}
+}
+
+// NewClientForHost returns a client for the agent on a mysql VM's default
+// galera-agent port.
+func NewClientForHost(host, username, password string, httpClient *http.Client) *Client {
+	return NewClient(fmt.Sprintf("http://%s:9200", host), username, password, httpClient)
+}
+
+// Health reports whether the node is synced. An unhealthy node is not an
+// error; failing to reach the agent is.
+func (c *Client) Health() (Health, error) {
+	status, body, err := c.do("GET", "/", false)
+	if err != nil {
+		return Health{}, err
+	}
+
+	switch status {
+	case http.StatusOK:
+		return Health{Healthy: true, Message: body}, nil
+	case http.StatusServiceUnavailable:
+		return Health{Healthy: false, Message: body}, nil
+	default:
+		return Health{}, StatusError{URL: c.BaseURL + "/", StatusCode: status, Body: body}
+	}
+}
+
+func (c *Client) Status() (Status, error) {
+	body, err := c.expectOK("GET", "/api/v1/status", false)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var status Status
+	if err := json.Unmarshal([]byte(body), &status); err != nil {
+		return Status{}, errors.Wrap(err, "failed to unmarshal galera-agent status")
+	}
+	return status, nil
+}
+
+// SequenceNumber returns the node's last committed Galera seqno; -1 means
+// the node crashed without recording one.
+func (c *Client) SequenceNumber() (int64, error) {
+	body, err := c.expectOK("GET", "/sequence_number", true)
+	if err != nil {
+		return 0, err
+	}
+
+	seqno, err := strconv.ParseInt(body, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "galera-agent returned an invalid sequence number %q", body)
+	}
+	return seqno, nil
+}
+
+// MySQLStatus returns the agent's description of the mysqld process, such
+// as "running" or "stopped".
+func (c *Client) MySQLStatus() (string, error) {
+	return c.expectOK("GET", "/mysql_status", true)
+}
+
+func (c *Client) StartMySQL(mode StartMode) error {
+	_, err := c.expectOK("POST", "/start_mysql_"+string(mode), true)
+	return err
+}
+
+func (c *Client) StopMySQL() error {
+	_, err := c.expectOK("POST", "/stop_mysql", true)
+	return err
+}
+
+func (c *Client) expectOK(method, path string, authenticate bool) (string, error) {
+	status, body, err := c.do(method, path, authenticate)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", StatusError{URL: c.BaseURL + path, StatusCode: status, Body: body}
+	}
+	return body, nil
+}
+
+func (c *Client) do(method, path string, authenticate bool) (int, string, error) {
+	requestURL := c.BaseURL + path
+	req, err := http.NewRequest(method, requestURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if authenticate {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "http request (%v) failed", requestURL)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", errors.Wrap(err, "failed to read galera-agent response")
+	}
+
+	return resp.StatusCode, strings.TrimSpace(string(body)), nil
+}
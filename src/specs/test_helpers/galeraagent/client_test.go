@@ -0,0 +1,88 @@
+package galeraagent_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"specs/test_helpers/galeraagent"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		agent  *galeraagent.FakeAgent
+		client *galeraagent.Client
+	)
+
+	BeforeEach(func() {
+		agent = galeraagent.NewFakeAgent("galera-agent", "secret")
+		client = galeraagent.NewClient(agent.URL, "galera-agent", "secret", nil)
+	})
+
+	AfterEach(func() {
+		agent.Close()
+	})
+
+	It("reports the health of a synced node", func() {
+		Expect(client.Health()).To(Equal(galeraagent.Health{Healthy: true, Message: "Galera Cluster Node is synced"}))
+
+		status, err := client.Status()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(galeraagent.Status{WsrepLocalState: 4, WsrepLocalStateComment: "Synced", Healthy: true}))
+	})
+
+	It("reports an unsynced node as unhealthy rather than failing", func() {
+		agent.SetSynced(false)
+
+		health, err := client.Health()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(health.Healthy).To(BeFalse())
+
+		status, err := client.Status()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.WsrepLocalStateComment).To(Equal("Initialized"))
+	})
+
+	It("stops mysql and reads the sequence number", func() {
+		_, err := client.SequenceNumber()
+		Expect(err).To(BeAssignableToTypeOf(galeraagent.StatusError{}))
+
+		Expect(client.StopMySQL()).To(Succeed())
+		Expect(client.MySQLStatus()).To(Equal("stopped"))
+
+		agent.SetSequenceNumber(-1)
+		Expect(client.SequenceNumber()).To(Equal(int64(-1)))
+		agent.SetSequenceNumber(1234)
+		Expect(client.SequenceNumber()).To(Equal(int64(1234)))
+	})
+
+	startsInMode := func(mode galeraagent.StartMode) {
+		Expect(client.StopMySQL()).To(Succeed())
+		Expect(client.StartMySQL(mode)).To(Succeed())
+		Expect(agent.Running()).To(BeTrue())
+		Expect(agent.Requests()).To(ContainElement("POST /start_mysql_" + string(mode)))
+	}
+
+	It("starts mysql to bootstrap a cluster", func() { startsInMode(galeraagent.Bootstrap) })
+	It("starts mysql to join a cluster", func() { startsInMode(galeraagent.Join) })
+	It("starts mysql as a single node", func() { startsInMode(galeraagent.SingleNode) })
+
+	It("returns typed errors for rejected credentials", func() {
+		client.Password = "wrong"
+
+		err := client.StopMySQL()
+		Expect(err).To(Equal(galeraagent.StatusError{
+			URL:        agent.URL + "/stop_mysql",
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized",
+		}))
+		Expect(agent.Running()).To(BeTrue())
+	})
+
+	It("fails when the agent cannot be reached", func() {
+		agent.Close()
+		_, err := client.Health()
+		Expect(err).To(MatchError(ContainSubstring("http request (" + agent.URL + "/) failed")))
+	})
+})
@@ -0,0 +1,131 @@
+package galeraagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// FakeAgent is an httptest server that behaves like galera-agent for a
+// single node. Starting and stopping mysql changes the reported state.
+type FakeAgent struct {
+	*httptest.Server
+
+	Username string
+	Password string
+
+	mutex          sync.Mutex
+	running        bool
+	synced         bool
+	sequenceNumber int64
+	requests       []string
+}
+
+// NewFakeAgent returns a fake for a running, synced node.
+func NewFakeAgent(username, password string) *FakeAgent {
+	agent := &FakeAgent{
+		Username: username,
+		Password: password,
+		running:  true,
+		synced:   true,
+	}
+	agent.Server = httptest.NewServer(http.HandlerFunc(agent.serveHTTP))
+	return agent
+}
+
+// SetSynced sets whether the running node reports itself synced.
+func (a *FakeAgent) SetSynced(synced bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.synced = synced
+}
+
+func (a *FakeAgent) SetSequenceNumber(seqno int64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.sequenceNumber = seqno
+}
+
+func (a *FakeAgent) Running() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.running
+}
+
+// Requests returns "METHOD /path" for each request received.
+func (a *FakeAgent) Requests() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]string(nil), a.requests...)
+}
+
+func (a *FakeAgent) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.requests = append(a.requests, r.Method+" "+r.URL.Path)
+
+	route := r.Method + " " + r.URL.Path
+	switch route {
+	case "GET /":
+		if !a.healthy() {
+			http.Error(w, "Galera Cluster Node status not synced", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "Galera Cluster Node is synced")
+		return
+	case "GET /api/v1/status":
+		state, comment := 4, "Synced"
+		if !a.healthy() {
+			state, comment = 0, "Initialized"
+		}
+		_ = json.NewEncoder(w).Encode(Status{
+			WsrepLocalState:        state,
+			WsrepLocalStateComment: comment,
+			Healthy:                a.healthy(),
+		})
+		return
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok || username != a.Username || password != a.Password {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case route == "GET /sequence_number":
+		if a.running {
+			http.Error(w, "can't determine sequence number when database is running", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, a.sequenceNumber)
+	case route == "GET /mysql_status":
+		if a.running {
+			fmt.Fprint(w, "running")
+		} else {
+			fmt.Fprint(w, "stopped")
+		}
+	case route == "POST /stop_mysql":
+		a.running = false
+		fmt.Fprint(w, "stop successful")
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/start_mysql_"):
+		mode := StartMode(strings.TrimPrefix(r.URL.Path, "/start_mysql_"))
+		if mode != Bootstrap && mode != Join && mode != SingleNode {
+			http.NotFound(w, r)
+			return
+		}
+		a.running = true
+		a.synced = true
+		fmt.Fprintf(w, "successfully started mysql in %s mode", mode)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *FakeAgent) healthy() bool {
+	return a.running && a.synced
+}
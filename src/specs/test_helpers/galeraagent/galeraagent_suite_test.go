@@ -0,0 +1,13 @@
+package galeraagent_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestGaleraagent(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Galera Agent")
+}
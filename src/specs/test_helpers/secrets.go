@@ -0,0 +1,123 @@
+package test_helpers
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SecretSource looks up deployment variables by their manifest name, such
+// as cf_mysql_mysql_admin_password.
+type SecretSource interface {
+	Password(name string) (string, error)
+	Certificate(name string) (Certificate, error)
+}
+
+// Certificate is the value of a certificate variable, in PEM.
+type Certificate struct {
+	CA          string `yaml:"ca"`
+	Certificate string `yaml:"certificate"`
+	PrivateKey  string `yaml:"private_key"`
+}
+
+// KeyPair returns the certificate and private key for use as a TLS client
+// certificate.
+func (c Certificate) KeyPair() (tls.Certificate, error) {
+	return tls.X509KeyPair([]byte(c.Certificate), []byte(c.PrivateKey))
+}
+
+var secretSource SecretSource
+
+// UseSecretSource makes the Get*Password helpers read from source. Pass nil
+// to go back to choosing a source from the environment.
+func UseSecretSource(source SecretSource) {
+	secretSource = source
+}
+
+// Secrets returns the source set with UseSecretSource. Otherwise it reads
+// from CredHub when CREDHUB_SERVER is set, and from the local environment
+// when it is not.
+func Secrets() (SecretSource, error) {
+	if secretSource != nil {
+		return secretSource, nil
+	}
+	if os.Getenv("CREDHUB_SERVER") == "" {
+		return &LocalSecretSource{VarsStore: os.Getenv("VARS_STORE")}, nil
+	}
+	return NewCredhubSecretSource()
+}
+
+// LocalSecretSource reads a variable from the environment variable of the
+// same name in upper case, falling back to a BOSH vars store file.
+type LocalSecretSource struct {
+	VarsStore string
+}
+
+func (s *LocalSecretSource) Password(name string) (string, error) {
+	if value := os.Getenv(strings.ToUpper(name)); value != "" {
+		return value, nil
+	}
+
+	if s.VarsStore == "" {
+		return "", fmt.Errorf("%s is not set and there is no VARS_STORE", strings.ToUpper(name))
+	}
+
+	data, err := ioutil.ReadFile(s.VarsStore)
+	if err != nil {
+		return "", err
+	}
+
+	var vars map[string]interface{}
+	err = yaml.Unmarshal(data, &vars)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s: %s", s.VarsStore, err)
+	}
+
+	value, ok := vars[name].(string)
+	if !ok {
+		return "", fmt.Errorf("%s has no password %s", s.VarsStore, name)
+	}
+	return value, nil
+}
+
+// Certificate reads the environment variables named after the certificate
+// variable in upper case with _CA, _CERTIFICATE and _PRIVATE_KEY suffixes,
+// falling back to the vars store when _CA is not set.
+func (s *LocalSecretSource) Certificate(name string) (Certificate, error) {
+	envName := strings.ToUpper(name)
+	if ca := os.Getenv(envName + "_CA"); ca != "" {
+		return Certificate{
+			CA:          ca,
+			Certificate: os.Getenv(envName + "_CERTIFICATE"),
+			PrivateKey:  os.Getenv(envName + "_PRIVATE_KEY"),
+		}, nil
+	}
+
+	if s.VarsStore == "" {
+		return Certificate{}, fmt.Errorf("%s_CA is not set and there is no VARS_STORE", envName)
+	}
+
+	data, err := ioutil.ReadFile(s.VarsStore)
+	if err != nil {
+		return Certificate{}, err
+	}
+
+	var vars map[string]interface{}
+	err = yaml.Unmarshal(data, &vars)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("parsing %s: %s", s.VarsStore, err)
+	}
+
+	value, _ := vars[name].(map[interface{}]interface{})
+	ca, ok := value["ca"].(string)
+	if !ok {
+		return Certificate{}, fmt.Errorf("%s has no certificate %s", s.VarsStore, name)
+	}
+	certificate, _ := value["certificate"].(string)
+	privateKey, _ := value["private_key"].(string)
+	return Certificate{CA: ca, Certificate: certificate, PrivateKey: privateKey}, nil
+}
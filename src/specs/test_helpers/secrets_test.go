@@ -0,0 +1,156 @@
+package test_helpers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	helpers "specs/test_helpers"
+	"specs/test_helpers/fakecredhub"
+	"specs/test_helpers/fakedirector"
+)
+
+var _ = Describe("Secrets", func() {
+	var deployment *fakedirector.Deployment
+
+	BeforeEach(func() {
+		var err error
+		deployment, err = fakedirector.NewDirector("fake").AddDeployment("pxc", []byte(`
+instance_groups:
+- name: mysql
+  jobs:
+  - name: pxc-mysql
+    properties:
+      tls:
+        galera: ((galera_server_certificate))
+        server: ((mysql_server_certificate))
+`))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		helpers.UseSecretSource(nil)
+	})
+
+	Context("from CredHub", func() {
+		var server *fakecredhub.Server
+
+		BeforeEach(func() {
+			server = fakecredhub.NewServer()
+			os.Setenv("BOSH_DEPLOYMENT", "pxc")
+			helpers.BoshCredhubPrefix = "/bosh-lite"
+
+			client, err := server.Client()
+			Expect(err).NotTo(HaveOccurred())
+			helpers.UseSecretSource(&helpers.CredhubSecretSource{Client: client})
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.Unsetenv("BOSH_DEPLOYMENT")
+			helpers.BoshCredhubPrefix = ""
+		})
+
+		It("reads passwords under the deployment's prefix", func() {
+			server.SetPassword("/bosh-lite/pxc/cf_mysql_mysql_admin_password", "admin-secret")
+			server.SetPassword("/bosh-lite/pxc/cf_mysql_mysql_galera_healthcheck_endpoint_password", "agent-secret")
+			server.SetPassword("/bosh-lite/pxc/cf_mysql_proxy_api_password", "proxy-secret")
+
+			Expect(helpers.GetMySQLAdminPassword()).To(Equal("admin-secret"))
+			Expect(helpers.GetGaleraAgentPassword()).To(Equal("agent-secret"))
+			Expect(helpers.GetProxyPassword()).To(Equal("proxy-secret"))
+		})
+
+		It("reads the CA of certificates", func() {
+			server.SetCertificate("/bosh-lite/pxc/mysql_server_certificate", "server-ca-pem", "cert-pem", "key-pem")
+			server.SetCertificate("/bosh-lite/pxc/galera_server_certificate", "galera-ca-pem", "cert-pem", "key-pem")
+
+			Expect(helpers.GetMySQLServerCA(deployment)).To(Equal("server-ca-pem"))
+			Expect(helpers.GetGaleraCA(deployment)).To(Equal("galera-ca-pem"))
+			Expect(helpers.GetGaleraCertificate(deployment)).To(Equal(helpers.Certificate{
+				CA:          "galera-ca-pem",
+				Certificate: "cert-pem",
+				PrivateKey:  "key-pem",
+			}))
+		})
+
+		It("reads the CA of the certificate the manifest currently references", func() {
+			server.SetCertificate("/bosh-lite/pxc/galera_server_certificate", "galera-ca-pem", "cert-pem", "key-pem")
+			server.SetCertificate("/bosh-lite/pxc/galera_server_certificate_rotated", "rotated-ca-pem", "cert-pem", "key-pem")
+			Expect(helpers.UpdateManifest(deployment, func(manifest map[string]interface{}) error {
+				return helpers.SetJobProperty(manifest, "mysql", "pxc-mysql", "tls.galera", "((galera_server_certificate_rotated))")
+			})).To(Succeed())
+
+			Expect(helpers.GetGaleraCA(deployment)).To(Equal("rotated-ca-pem"))
+		})
+
+		It("fails for missing credentials", func() {
+			_, err := helpers.GetMySQLAdminPassword()
+			Expect(err).To(MatchError(ContainSubstring("credential does not exist")))
+		})
+	})
+
+	Context("from the local environment", func() {
+		var varsStore string
+
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "secrets")
+			Expect(err).NotTo(HaveOccurred())
+			varsStore = filepath.Join(dir, "vars.yml")
+			Expect(ioutil.WriteFile(varsStore, []byte("cf_mysql_mysql_admin_password: from-file\ncf_mysql_proxy_api_password: proxy-file\nmysql_server_certificate: {ca: server-ca-file, certificate: cert}\n"), 0600)).To(Succeed())
+
+			helpers.UseSecretSource(&helpers.LocalSecretSource{VarsStore: varsStore})
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(filepath.Dir(varsStore))
+			os.Unsetenv("CF_MYSQL_PROXY_API_PASSWORD")
+			os.Unsetenv("GALERA_SERVER_CERTIFICATE_CA")
+			os.Unsetenv("GALERA_SERVER_CERTIFICATE_CERTIFICATE")
+			os.Unsetenv("GALERA_SERVER_CERTIFICATE_PRIVATE_KEY")
+		})
+
+		It("reads CAs from the environment or the vars store", func() {
+			os.Setenv("GALERA_SERVER_CERTIFICATE_CA", "galera-ca-env")
+
+			Expect(helpers.GetMySQLServerCA(deployment)).To(Equal("server-ca-file"))
+			Expect(helpers.GetGaleraCA(deployment)).To(Equal("galera-ca-env"))
+
+			os.Setenv("GALERA_SERVER_CERTIFICATE_CERTIFICATE", "galera-cert-env")
+			os.Setenv("GALERA_SERVER_CERTIFICATE_PRIVATE_KEY", "galera-key-env")
+			Expect(helpers.GetGaleraCertificate(deployment)).To(Equal(helpers.Certificate{
+				CA:          "galera-ca-env",
+				Certificate: "galera-cert-env",
+				PrivateKey:  "galera-key-env",
+			}))
+
+			source := &helpers.LocalSecretSource{VarsStore: varsStore}
+			_, err := source.Certificate("cf_mysql_mysql_admin_password")
+			Expect(err).To(MatchError(varsStore + " has no certificate cf_mysql_mysql_admin_password"))
+		})
+
+		It("prefers environment variables to the vars store", func() {
+			os.Setenv("CF_MYSQL_PROXY_API_PASSWORD", "proxy-env")
+
+			Expect(helpers.GetMySQLAdminPassword()).To(Equal("from-file"))
+			Expect(helpers.GetProxyPassword()).To(Equal("proxy-env"))
+		})
+
+		It("fails for variables in neither", func() {
+			_, err := helpers.GetGaleraAgentPassword()
+			Expect(err).To(MatchError(varsStore + " has no password cf_mysql_mysql_galera_healthcheck_endpoint_password"))
+		})
+	})
+
+	It("uses the local environment when CREDHUB_SERVER is not set", func() {
+		if os.Getenv("CREDHUB_SERVER") != "" {
+			Skip("CREDHUB_SERVER is set")
+		}
+		source, err := helpers.Secrets()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source).To(BeAssignableToTypeOf(&helpers.LocalSecretSource{}))
+	})
+})
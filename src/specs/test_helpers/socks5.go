@@ -18,6 +18,10 @@ import (
 
 var HttpClient = http.DefaultClient
 
+// Dial opens connections from the specs to the deployment. It goes through
+// the proxy once SetupSocks5Proxy has run.
+var Dial = net.Dial
+
 func NewSocks5Dialer(proxyURL string, logger *log.Logger) (proxy.DialFunc, error) {
 	u, err := url.Parse(proxyURL)
 	if err != nil {
@@ -63,6 +67,8 @@ func SetupSocks5Proxy() {
 		Timeout: 2 * time.Minute,
 	}
 
+	Dial = dialer
+
 	mysql.RegisterDial("tcp", func(addr string) (net.Conn, error) {
 		return dialer("tcp", addr)
 	})
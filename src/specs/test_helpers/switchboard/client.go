@@ -0,0 +1,171 @@
+// Package switchboard is a client for the proxy's HTTP API, as configured by
+// the proxy job's proxy.yml.
+package switchboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Cluster struct {
+	ActiveBackend  *Backend  `json:"activeBackend"`
+	TrafficEnabled bool      `json:"trafficEnabled"`
+	Message        string    `json:"message"`
+	LastUpdated    time.Time `json:"lastUpdated"`
+}
+
+type Backend struct {
+	Host                string `json:"host"`
+	Port                int    `json:"port"`
+	Name                string `json:"name"`
+	Healthy             bool   `json:"healthy"`
+	Active              bool   `json:"active"`
+	CurrentSessionCount int    `json:"currentSessionCount"`
+}
+
+// StatusError is returned for responses other than 200 OK.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("%s returned HTTP %d: %s", e.URL, e.StatusCode, e.Body)
+}
+
+type Client struct {
+	BaseURL  string
+	Username string
+	Password string
+	// ForwardedProto is sent as X-Forwarded-Proto. The API redirects plain
+	// HTTP requests when api_force_https is set, so it defaults to https.
+	ForwardedProto string
+	HTTPClient     *http.Client
+}
+
+// NewClient returns a client for the API at baseURL, such as
+// http://10.0.0.5:8080.
+func NewClient(baseURL, username, password string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		BaseURL:        strings.TrimSuffix(baseURL, "/"),
+		Username:       username,
+		Password:       password,
+		ForwardedProto: "https",
+		HTTPClient:     httpClient,
+	}
+}
+
+// NewClientForHost returns a client for the API on a proxy VM's default API
+// port.
+func NewClientForHost(host, username, password string, httpClient *http.Client) *Client {
+	return NewClient(fmt.Sprintf("http://%s:8080", host), username, password, httpClient)
+}
+
+// NewClientsForProxyURIs returns a client per entry of the API's ProxyURIs,
+// which name each proxy's dashboard route without a scheme.
+func NewClientsForProxyURIs(proxyURIs []string, username, password string, httpClient *http.Client) []*Client {
+	var clients []*Client
+	for _, uri := range proxyURIs {
+		if !strings.Contains(uri, "://") {
+			uri = "https://" + uri
+		}
+		clients = append(clients, NewClient(uri, username, password, httpClient))
+	}
+	return clients
+}
+
+func (c *Client) Cluster() (Cluster, error) {
+	var cluster Cluster
+	err := c.do("GET", "/v0/cluster", nil, &cluster)
+	return cluster, err
+}
+
+func (c *Client) Backends() ([]Backend, error) {
+	var backends []Backend
+	err := c.do("GET", "/v0/backends", nil, &backends)
+	return backends, err
+}
+
+// ActiveBackend returns the host of the backend receiving traffic, or ""
+// when there is none.
+func (c *Client) ActiveBackend() (string, error) {
+	cluster, err := c.Cluster()
+	if err != nil {
+		return "", err
+	}
+	if cluster.ActiveBackend == nil {
+		return "", nil
+	}
+	return cluster.ActiveBackend.Host, nil
+}
+
+// EnableTraffic lets the proxy route connections again.
+func (c *Client) EnableTraffic(message string) error {
+	return c.setTraffic(true, message)
+}
+
+// DisableTraffic stops the proxy routing connections and closes existing
+// ones. message is recorded on the cluster.
+func (c *Client) DisableTraffic(message string) error {
+	return c.setTraffic(false, message)
+}
+
+func (c *Client) setTraffic(enabled bool, message string) error {
+	query := url.Values{}
+	query.Set("trafficEnabled", strconv.FormatBool(enabled))
+	query.Set("message", message)
+	return c.do("PATCH", "/v0/cluster", query, nil)
+}
+
+func (c *Client) do(method, path string, query url.Values, result interface{}) error {
+	requestURL := c.BaseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, requestURL, nil)
+	if err != nil {
+		return err
+	}
+
+	if c.ForwardedProto != "" {
+		req.Header.Set("X-Forwarded-Proto", c.ForwardedProto)
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "http request (%v) failed", requestURL)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, `failed to read proxy response`)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return StatusError{URL: requestURL, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return errors.Wrap(err, `failed to unmarshal proxy response`)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package switchboard_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"specs/test_helpers/switchboard"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server    *httptest.Server
+		client    *switchboard.Client
+		requests  []*http.Request
+		responses []response
+	)
+
+	BeforeEach(func() {
+		requests, responses = nil, nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(responses).NotTo(BeEmpty(), "unexpected request %s %s", r.Method, r.URL)
+
+			requests = append(requests, r)
+			w.WriteHeader(responses[0].status)
+			fmt.Fprint(w, responses[0].body)
+			responses = responses[1:]
+		}))
+		client = switchboard.NewClient(server.URL, "proxy", "secret", nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	respondWith := func(status int, body string) {
+		responses = append(responses, response{status: status, body: body})
+	}
+
+	expectProxyRequest := func(r *http.Request, method, path, query string) {
+		Expect(r.Method).To(Equal(method))
+		Expect(r.URL.Path).To(Equal(path))
+		Expect(r.URL.RawQuery).To(Equal(query))
+		Expect(r.Header.Get("X-Forwarded-Proto")).To(Equal("https"))
+
+		username, password, ok := r.BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("proxy"))
+		Expect(password).To(Equal("secret"))
+	}
+
+	It("reads the cluster", func() {
+		respondWith(http.StatusOK, `{
+			"activeBackend": {"host": "10.0.0.2", "port": 3306, "name": "backend-0", "healthy": true, "active": true, "currentSessionCount": 4},
+			"trafficEnabled": true,
+			"message": "",
+			"lastUpdated": "2018-05-01T12:00:00Z"
+		}`)
+
+		cluster, err := client.Cluster()
+		Expect(err).NotTo(HaveOccurred())
+		expectProxyRequest(requests[0], "GET", "/v0/cluster", "")
+		Expect(cluster.TrafficEnabled).To(BeTrue())
+		Expect(cluster.ActiveBackend).To(Equal(&switchboard.Backend{
+			Host: "10.0.0.2", Port: 3306, Name: "backend-0", Healthy: true, Active: true, CurrentSessionCount: 4,
+		}))
+	})
+
+	It("reports no active backend", func() {
+		respondWith(http.StatusOK, `{"trafficEnabled": true}`)
+
+		Expect(client.ActiveBackend()).To(BeEmpty())
+	})
+
+	It("lists the backends", func() {
+		respondWith(http.StatusOK, `[
+			{"host": "10.0.0.2", "port": 3306, "name": "backend-0", "healthy": true, "active": true, "currentSessionCount": 4},
+			{"host": "10.0.0.3", "port": 3306, "name": "backend-1", "healthy": false, "active": false, "currentSessionCount": 0}
+		]`)
+
+		backends, err := client.Backends()
+		Expect(err).NotTo(HaveOccurred())
+		expectProxyRequest(requests[0], "GET", "/v0/backends", "")
+		Expect(backends).To(HaveLen(2))
+		Expect(backends[1].Name).To(Equal("backend-1"))
+		Expect(backends[1].Healthy).To(BeFalse())
+	})
+
+	It("disables and enables traffic", func() {
+		respondWith(http.StatusOK, `{"trafficEnabled": false}`)
+		respondWith(http.StatusOK, `{"trafficEnabled": true}`)
+
+		Expect(client.DisableTraffic("maintenance")).To(Succeed())
+		Expect(client.EnableTraffic("done")).To(Succeed())
+
+		expectProxyRequest(requests[0], "PATCH", "/v0/cluster", "message=maintenance&trafficEnabled=false")
+		expectProxyRequest(requests[1], "PATCH", "/v0/cluster", "message=done&trafficEnabled=true")
+	})
+
+	It("returns the status and body of failed requests", func() {
+		respondWith(http.StatusUnauthorized, "Unauthorized\n")
+
+		_, err := client.Cluster()
+		Expect(err).To(Equal(switchboard.StatusError{
+			URL:        server.URL + "/v0/cluster",
+			StatusCode: http.StatusUnauthorized,
+			Body:       "Unauthorized",
+		}))
+	})
+
+	It("omits X-Forwarded-Proto when ForwardedProto is empty", func() {
+		respondWith(http.StatusOK, `[]`)
+
+		client.ForwardedProto = ""
+		Expect(client.Backends()).To(BeEmpty())
+		Expect(requests[0].Header).NotTo(HaveKey("X-Forwarded-Proto"))
+	})
+
+	It("builds a client per proxy URI", func() {
+		clients := switchboard.NewClientsForProxyURIs([]string{"0-proxy.example.com", "http://1-proxy.example.com/"}, "proxy", "secret", nil)
+		Expect(clients).To(HaveLen(2))
+		Expect(clients[0].BaseURL).To(Equal("https://0-proxy.example.com"))
+		Expect(clients[1].BaseURL).To(Equal("http://1-proxy.example.com"))
+	})
+})
+
+type response struct {
+	status int
+	body   string
+}
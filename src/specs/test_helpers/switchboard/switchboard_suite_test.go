@@ -0,0 +1,13 @@
+package switchboard_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSwitchboard(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Switchboard")
+}
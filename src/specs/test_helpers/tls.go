@@ -0,0 +1,356 @@
+package test_helpers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	boshdir "github.com/cloudfoundry/bosh-cli/director"
+	"github.com/go-sql-driver/mysql"
+)
+
+// CertificateRotation is the CA rotation phase of one certificate pair on
+// an instance, as pxc-check-certs -phase prints it.
+type CertificateRotation struct {
+	Pair       string   `json:"pair"`
+	Phase      string   `json:"phase"`
+	Issuer     string   `json:"issuer"`
+	TrustedCAs []string `json:"trusted_cas"`
+}
+
+const checkCertsPhaseCommand = "/var/vcap/packages/pxc-utils/bin/pxc-check-certs -phase"
+
+// CertificateRotations returns the rotation phase of the pair on every
+// instance of the instance group, keyed by instance. Commands run with run,
+// or with BoshSSH when run is nil.
+func CertificateRotations(deployment boshdir.Deployment, instanceGroupName, pair string, run RemoteCommand) (map[string]CertificateRotation, error) {
+	if run == nil {
+		run = BoshSSH
+	}
+
+	nodes, err := ChaosNodes(deployment, instanceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	rotations := map[string]CertificateRotation{}
+	for _, node := range nodes {
+		output, err := run(node.Instance, checkCertsPhaseCommand)
+		if err != nil {
+			return nil, fmt.Errorf("checking certificates on %s: %s: %s", node.Instance, err, output)
+		}
+
+		var pairs []CertificateRotation
+		err = json.Unmarshal([]byte(strings.TrimSpace(output)), &pairs)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected output from %s: %s", node.Instance, output)
+		}
+
+		found := false
+		for _, rotation := range pairs {
+			if rotation.Pair == pair {
+				rotations[node.Instance] = rotation
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s does not check the %s certificate", node.Instance, pair)
+		}
+	}
+	return rotations, nil
+}
+
+// PinnedTLSConfig returns a TLS config that only trusts the CAs in caPEM.
+// The server's chain is verified but its hostname is not, since the
+// deployment's certificates do not name the hosts clients connect to.
+func PinnedTLSConfig(caPEM string) (*tls.Config, error) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(caPEM)) {
+		return nil, errors.New("no CA certificates in PEM")
+	}
+
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, roots)
+		},
+	}, nil
+}
+
+// RegisterPinnedTLSConfig registers a PinnedTLSConfig with go-sql-driver,
+// for use as tls=name in a DSN.
+func RegisterPinnedTLSConfig(name, caPEM string) error {
+	config, err := PinnedTLSConfig(caPEM)
+	if err != nil {
+		return err
+	}
+	return mysql.RegisterTLSConfig(name, config)
+}
+
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	var certs []*x509.Certificate
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// NewCA returns a self-signed CA in PEM that the deployment does not trust,
+// for checking that clients pinned to it are refused.
+func NewCA(commonName string) (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), nil
+}
+
+// DbConnWithTLSConfig connects like DbConnWithUser, with tls=tlsConfig: the
+// name of a registered config, or "false" for a plaintext connection.
+func DbConnWithTLSConfig(mysqlUsername, mysqlPassword, mysqlHost, tlsConfig string) (*sql.DB, error) {
+	return sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/?tls=%s&timeout=10s",
+		mysqlUsername, mysqlPassword, mysqlHost, 3306, tlsConfig))
+}
+
+// SessionTLS is the TLS a client session negotiated, as mysql reports it.
+type SessionTLS struct {
+	// Version is a protocol name such as TLSv1.2; it is empty for a
+	// plaintext session.
+	Version string
+	// Cipher is an OpenSSL cipher name such as ECDHE-RSA-AES256-GCM-SHA384.
+	Cipher string
+}
+
+// SessionTLSStatus returns the TLS of a session of db.
+func SessionTLSStatus(db *sql.DB) (SessionTLS, error) {
+	rows, err := db.Query(`SELECT variable_name, variable_value FROM performance_schema.session_status
+		WHERE variable_name IN ('Ssl_version', 'Ssl_cipher')`)
+	if err != nil {
+		return SessionTLS{}, err
+	}
+	defer rows.Close()
+
+	var status SessionTLS
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return SessionTLS{}, err
+		}
+		switch name {
+		case "Ssl_version":
+			status.Version = value
+		case "Ssl_cipher":
+			status.Cipher = value
+		}
+	}
+	return status, rows.Err()
+}
+
+// insecureTransportError is ER_SECURE_TRANSPORT_REQUIRED.
+const insecureTransportError = 3159
+
+// RequiresSecureTransport reports whether the server refuses plaintext
+// connections.
+func RequiresSecureTransport(db *sql.DB) (bool, error) {
+	var required bool
+	err := db.QueryRow("SELECT @@global.require_secure_transport").Scan(&required)
+	return required, err
+}
+
+// SetRequireSecureTransport sets require_secure_transport on the server db
+// connects to. The setting is not replicated, so it only affects that node.
+func SetRequireSecureTransport(db *sql.DB, required bool) error {
+	value := "OFF"
+	if required {
+		value = "ON"
+	}
+	_, err := db.Exec("SET GLOBAL require_secure_transport = " + value)
+	return err
+}
+
+// IsInsecureTransportError reports whether err is the server refusing a
+// plaintext connection because secure transport is required.
+func IsInsecureTransportError(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == insecureTransportError
+}
+
+// GaleraProviderOptions returns the wsrep_provider_options the server is
+// running with, such as "socket.ssl": "YES".
+func GaleraProviderOptions(db *sql.DB) (map[string]string, error) {
+	var options string
+	err := db.QueryRow("SELECT @@global.wsrep_provider_options").Scan(&options)
+	if err != nil {
+		return nil, err
+	}
+	return ParseProviderOptions(options), nil
+}
+
+// ParseProviderOptions parses wsrep_provider_options as the server reports
+// it: "key = value" pairs separated by semicolons.
+func ParseProviderOptions(options string) map[string]string {
+	parsed := map[string]string{}
+	for _, pair := range strings.Split(options, ";") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) == 2 {
+			parsed[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return parsed
+}
+
+// TLSProbe is the outcome of a TLS handshake with a port.
+type TLSProbe struct {
+	// Certificates are what the server presented; none means the port did
+	// not answer with TLS.
+	Certificates []*x509.Certificate
+	Version      uint16
+	CipherSuite  uint16
+	// HandshakeErr is set when the handshake did not complete, as when the
+	// server requires a client certificate the probe was not given.
+	HandshakeErr error
+	// VerifyErr is set when the presented chain does not verify against
+	// the CA the probe was given.
+	VerifyErr error
+}
+
+// ProbeTLS starts a TLS handshake with address and records what the server
+// presented, so that ports that are not meant for clients, such as galera's,
+// can be checked for encryption. Servers that require a client certificate,
+// such as galera, need clientCertificates to complete the handshake. Every
+// cipher suite is offered, so that the server's choice can be checked with
+// IsSecureCipherSuite.
+func ProbeTLS(address, caPEM string, clientCertificates ...tls.Certificate) TLSProbe {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(caPEM)) {
+		return TLSProbe{HandshakeErr: errors.New("no CA certificates in PEM")}
+	}
+
+	var probe TLSProbe
+	config := &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       clientCertificates,
+		CipherSuites:       allCipherSuites(),
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			for _, raw := range rawCerts {
+				if cert, err := x509.ParseCertificate(raw); err == nil {
+					probe.Certificates = append(probe.Certificates, cert)
+				}
+			}
+			probe.VerifyErr = verifyChain(rawCerts, roots)
+			return nil
+		},
+	}
+
+	conn, err := Dial("tcp", address)
+	if err != nil {
+		probe.HandshakeErr = err
+		return probe
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	client := tls.Client(conn, config)
+	probe.HandshakeErr = client.Handshake()
+	state := client.ConnectionState()
+	probe.Version, probe.CipherSuite = state.Version, state.CipherSuite
+	return probe
+}
+
+// IsSecureCipherSuite reports whether id is a cipher suite without known
+// weaknesses, as crypto/tls classifies them.
+func IsSecureCipherSuite(id uint16) bool {
+	for _, suite := range tls.CipherSuites() {
+		if suite.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func allCipherSuites() []uint16 {
+	var ids []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		ids = append(ids, suite.ID)
+	}
+	return ids
+}
+
+// SSTOptions returns the [sst] options of the rendered my.cnf on every
+// instance of the instance group, keyed by instance, as xtrabackup-v2 reads
+// them. Commands run with run, or with BoshSSH when run is nil.
+func SSTOptions(deployment boshdir.Deployment, instanceGroupName string, run RemoteCommand) (map[string]map[string]string, error) {
+	if run == nil {
+		run = BoshSSH
+	}
+
+	nodes, err := ChaosNodes(deployment, instanceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	options := map[string]map[string]string{}
+	for _, node := range nodes {
+		output, err := run(node.Instance, printSSTDefaultsCommand)
+		if err != nil {
+			return nil, fmt.Errorf("reading sst options on %s: %s: %s", node.Instance, err, output)
+		}
+
+		options[node.Instance] = map[string]string{}
+		for _, line := range strings.Split(output, "\n") {
+			parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(line), "--"), "=", 2)
+			if parts[0] == "" {
+				continue
+			}
+			if len(parts) == 1 {
+				parts = append(parts, "")
+			}
+			options[node.Instance][strings.Replace(parts[0], "_", "-", -1)] = parts[1]
+		}
+	}
+	return options, nil
+}
+
+const printSSTDefaultsCommand = "/var/vcap/packages/pxc/bin/my_print_defaults --defaults-file=/var/vcap/jobs/pxc-mysql/config/my.cnf sst"
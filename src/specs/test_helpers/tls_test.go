@@ -0,0 +1,184 @@
+package test_helpers_test
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	helpers "specs/test_helpers"
+	"specs/test_helpers/fakedirector"
+)
+
+var _ = Describe("CertificateRotations", func() {
+	var (
+		fake   *fakedirector.Deployment
+		output map[string]string
+		run    helpers.RemoteCommand
+	)
+
+	BeforeEach(func() {
+		var err error
+		fake, err = fakedirector.NewDirector("fake").AddDeployment("pxc", []byte("instance_groups: [{name: mysql, instances: 2}]"))
+		Expect(err).NotTo(HaveOccurred())
+
+		output = map[string]string{}
+		run = func(instance, command string) (string, error) {
+			Expect(command).To(Equal("/var/vcap/packages/pxc-utils/bin/pxc-check-certs -phase"))
+			if out, ok := output[instance]; ok {
+				return out, nil
+			}
+			return `[{"pair":"server","phase":"single-ca","issuer":"pxc_server_ca","trusted_cas":["pxc_server_ca"]},` +
+				`{"pair":"galera","phase":"trusting-new-ca","issuer":"pxc_galera_ca","trusted_cas":["pxc_galera_ca","pxc_galera_ca_2"]}]` + "\n", nil
+		}
+	})
+
+	It("returns the phase of the pair on every instance", func() {
+		rotations, err := helpers.CertificateRotations(fake, "mysql", "galera", run)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotations).To(HaveLen(2))
+		for _, rotation := range rotations {
+			Expect(rotation).To(Equal(helpers.CertificateRotation{
+				Pair:       "galera",
+				Phase:      "trusting-new-ca",
+				Issuer:     "pxc_galera_ca",
+				TrustedCAs: []string{"pxc_galera_ca", "pxc_galera_ca_2"},
+			}))
+		}
+	})
+
+	It("fails when an instance does not check the pair", func() {
+		nodes, err := helpers.ChaosNodes(fake, "mysql")
+		Expect(err).NotTo(HaveOccurred())
+		output[nodes[1].Instance] = `[{"pair":"server","phase":"single-ca"}]`
+
+		_, err = helpers.CertificateRotations(fake, "mysql", "galera", run)
+		Expect(err).To(MatchError(nodes[1].Instance + " does not check the galera certificate"))
+	})
+
+	It("fails when the check fails", func() {
+		_, err := helpers.CertificateRotations(fake, "mysql", "galera", func(instance, command string) (string, error) {
+			return "galera: the certificate and CA must load to detect a rotation", errors.New("exit status 1")
+		})
+		Expect(err).To(MatchError(ContainSubstring("exit status 1: galera: the certificate and CA must load")))
+	})
+})
+
+var _ = Describe("TLS probes", func() {
+	var (
+		server   *httptest.Server
+		serverCA string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.NotFoundHandler())
+		serverCA = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("verifies the server against a pinned CA without checking its hostname", func() {
+		probe := helpers.ProbeTLS(server.Listener.Addr().String(), serverCA)
+		Expect(probe.HandshakeErr).NotTo(HaveOccurred())
+		Expect(probe.VerifyErr).NotTo(HaveOccurred())
+		Expect(probe.Certificates).To(HaveLen(1))
+		Expect(probe.Version).To(BeNumerically(">=", tls.VersionTLS12))
+
+		config, err := helpers.PinnedTLSConfig(serverCA)
+		Expect(err).NotTo(HaveOccurred())
+		conn, err := tls.Dial("tcp", server.Listener.Addr().String(), config)
+		Expect(err).NotTo(HaveOccurred())
+		conn.Close()
+	})
+
+	It("records certificates that do not chain to the CA", func() {
+		otherCA, err := helpers.NewCA("untrusted")
+		Expect(err).NotTo(HaveOccurred())
+
+		probe := helpers.ProbeTLS(server.Listener.Addr().String(), otherCA)
+		Expect(probe.Certificates).To(HaveLen(1))
+		Expect(probe.VerifyErr).To(HaveOccurred())
+
+		config, err := helpers.PinnedTLSConfig(otherCA)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = tls.Dial("tcp", server.Listener.Addr().String(), config)
+		Expect(err).To(MatchError(ContainSubstring("unknown authority")))
+	})
+
+	It("finds no certificates on a plaintext port", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				conn.Write([]byte("plaintext\n"))
+				conn.Close()
+			}
+		}()
+
+		probe := helpers.ProbeTLS(listener.Addr().String(), serverCA)
+		Expect(probe.Certificates).To(BeEmpty())
+		Expect(probe.HandshakeErr).To(HaveOccurred())
+	})
+
+	It("completes the handshake with servers that require a client certificate when given one", func() {
+		server.TLS.ClientAuth = tls.RequireAnyClientCert
+
+		probe := helpers.ProbeTLS(server.Listener.Addr().String(), serverCA, server.TLS.Certificates[0])
+		Expect(probe.HandshakeErr).NotTo(HaveOccurred())
+		Expect(probe.VerifyErr).NotTo(HaveOccurred())
+		Expect(probe.Version).To(BeNumerically(">=", tls.VersionTLS12))
+		Expect(helpers.IsSecureCipherSuite(probe.CipherSuite)).To(BeTrue())
+	})
+
+	It("rejects PEM without certificates", func() {
+		_, err := helpers.PinnedTLSConfig("not a certificate")
+		Expect(err).To(MatchError("no CA certificates in PEM"))
+	})
+})
+
+var _ = Describe("IsSecureCipherSuite", func() {
+	It("follows crypto/tls", func() {
+		Expect(helpers.IsSecureCipherSuite(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384)).To(BeTrue())
+		Expect(helpers.IsSecureCipherSuite(tls.TLS_RSA_WITH_RC4_128_SHA)).To(BeFalse())
+	})
+})
+
+var _ = Describe("ParseProviderOptions", func() {
+	It("parses the options as the server reports them", func() {
+		Expect(helpers.ParseProviderOptions("base_port = 4567; socket.ssl = YES; socket.ssl_cipher = AES128-SHA256; ")).To(Equal(map[string]string{
+			"base_port":         "4567",
+			"socket.ssl":        "YES",
+			"socket.ssl_cipher": "AES128-SHA256",
+		}))
+	})
+})
+
+var _ = Describe("SSTOptions", func() {
+	It("reads the [sst] options of every instance", func() {
+		fake, err := fakedirector.NewDirector("fake").AddDeployment("pxc", []byte("instance_groups: [{name: mysql, instances: 2}]"))
+		Expect(err).NotTo(HaveOccurred())
+
+		options, err := helpers.SSTOptions(fake, "mysql", func(instance, command string) (string, error) {
+			Expect(command).To(ContainSubstring("my_print_defaults --defaults-file=/var/vcap/jobs/pxc-mysql/config/my.cnf sst"))
+			return "--encrypt=4\n--ssl_ca=/var/vcap/jobs/pxc-mysql/certificates/galera-ca.pem\n--quick\n", nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options).To(HaveLen(2))
+		for _, sst := range options {
+			Expect(sst).To(Equal(map[string]string{
+				"encrypt": "4",
+				"ssl-ca":  "/var/vcap/jobs/pxc-mysql/certificates/galera-ca.pem",
+				"quick":   "",
+			}))
+		}
+	})
+})
@@ -0,0 +1,403 @@
+package test_helpers
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorkloadStore is where a Workload writes. SQLWorkloadStore is the store
+// used against a deployment.
+type WorkloadStore interface {
+	// Setup creates an empty store, discarding writes from earlier runs.
+	Setup() error
+	// Write stores writeID and records that attempt was applied. Storing
+	// must be idempotent, so that retrying a write whose first attempt
+	// committed but reported an error does not store it twice, but every
+	// attempt that commits is recorded. An error leaves the outcome unknown.
+	Write(writeID string, attempt int) error
+	// Count returns how many rows have writeID.
+	Count(writeID string) (int, error)
+	// Counts returns how many rows each write ID has.
+	Counts() (map[string]int, error)
+	// Applied returns how many times each attempt was applied. An attempt
+	// is a single transaction, so a count above one means the cluster
+	// applied a transaction more than once.
+	Applied() (map[Attempt]int, error)
+}
+
+// Attempt identifies one try of a write.
+type Attempt struct {
+	WriteID string
+	Number  int
+}
+
+func (a Attempt) String() string {
+	return fmt.Sprintf("%s#%d", a.WriteID, a.Number)
+}
+
+// Workload runs concurrent writers and readers against a store while a test
+// disrupts the cluster, and checks afterwards that no acknowledged write was
+// lost or duplicated and that writers could read their own writes.
+type Workload struct {
+	Store   WorkloadStore
+	Writers int
+	Readers int
+	// Interval is the pause between a worker's operations.
+	Interval time.Duration
+	// Retries is how many times a writer retries a failed write with the
+	// same write ID, as a client reconnecting through the proxy would. The
+	// store's idempotent Write keeps a retry of a committed write from
+	// storing it twice.
+	Retries int
+
+	mutex      sync.Mutex
+	writes     []WriteRecord
+	acked      []string
+	violations []string
+	staleReads []string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// WriteRecord is the outcome of one logical write.
+type WriteRecord struct {
+	ID           string
+	Writer       int
+	Attempts     int
+	Acknowledged bool
+	Err          string
+	At           time.Time
+}
+
+// WorkloadReport summarises a workload run.
+type WorkloadReport struct {
+	Attempted    int
+	Acknowledged int
+	Failed       int
+	// Lost are acknowledged writes missing from the store.
+	Lost []string
+	// Duplicated are attempts the store applied more than once, and how
+	// many times.
+	Duplicated map[Attempt]int
+	// ReadYourWritesViolations are acknowledged writes their writer could
+	// not read back immediately.
+	ReadYourWritesViolations []string
+	// StaleReads are acknowledged writes a reader did not see. They are
+	// reported but do not make the run inconsistent.
+	StaleReads []string
+	// Unexpected are rows for writes that were never attempted.
+	Unexpected []string
+}
+
+// Start creates the store and starts the workers.
+func (w *Workload) Start() error {
+	err := w.Store.Setup()
+	if err != nil {
+		return err
+	}
+
+	w.stop = make(chan struct{})
+	for i := 0; i < w.Writers; i++ {
+		w.wg.Add(1)
+		go w.write(i)
+	}
+	for i := 0; i < w.Readers; i++ {
+		w.wg.Add(1)
+		go w.read(int64(i))
+	}
+	return nil
+}
+
+// Stop stops the workers and waits for in-flight operations to finish. It
+// may be called more than once.
+func (w *Workload) Stop() {
+	w.stopOnce.Do(func() {
+		if w.stop != nil {
+			close(w.stop)
+		}
+	})
+	w.wg.Wait()
+}
+
+// Check compares the recorded outcomes with the store's final contents. Call
+// it after Stop, once the cluster is healthy again.
+func (w *Workload) Check() (WorkloadReport, error) {
+	counts, err := w.Store.Counts()
+	if err != nil {
+		return WorkloadReport{}, err
+	}
+
+	applied, err := w.Store.Applied()
+	if err != nil {
+		return WorkloadReport{}, err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	report := WorkloadReport{
+		Attempted:                len(w.writes),
+		Duplicated:               map[Attempt]int{},
+		ReadYourWritesViolations: append([]string(nil), w.violations...),
+		StaleReads:               append([]string(nil), w.staleReads...),
+	}
+
+	attempted := map[string]bool{}
+	for _, write := range w.writes {
+		attempted[write.ID] = true
+		if write.Acknowledged {
+			report.Acknowledged++
+			if counts[write.ID] == 0 {
+				report.Lost = append(report.Lost, write.ID)
+			}
+		} else {
+			report.Failed++
+		}
+	}
+
+	for id := range counts {
+		if !attempted[id] {
+			report.Unexpected = append(report.Unexpected, id)
+		}
+	}
+	for attempt, count := range applied {
+		if count > 1 {
+			report.Duplicated[attempt] = count
+		}
+	}
+
+	sort.Strings(report.Lost)
+	sort.Strings(report.Unexpected)
+	return report, nil
+}
+
+// Writes returns the outcome of every write so far.
+func (w *Workload) Writes() []WriteRecord {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]WriteRecord(nil), w.writes...)
+}
+
+func (w *Workload) write(writer int) {
+	defer w.wg.Done()
+
+	for seq := 0; !w.stopped(); seq++ {
+		record := WriteRecord{
+			ID:     fmt.Sprintf("w%d-%d", writer, seq),
+			Writer: writer,
+			At:     time.Now(),
+		}
+
+		var err error
+		for record.Attempts <= w.Retries {
+			record.Attempts++
+			if err = w.Store.Write(record.ID, record.Attempts); err == nil {
+				break
+			}
+		}
+
+		if err != nil {
+			record.Err = err.Error()
+		} else {
+			record.Acknowledged = true
+			if count, err := w.Store.Count(record.ID); err != nil || count == 0 {
+				w.recordViolation(record.ID)
+			}
+		}
+
+		w.record(record)
+		w.pause()
+	}
+}
+
+func (w *Workload) read(seed int64) {
+	defer w.wg.Done()
+
+	random := rand.New(rand.NewSource(seed))
+	for !w.stopped() {
+		if id := w.randomAcked(random); id != "" {
+			count, err := w.Store.Count(id)
+			if err == nil && count == 0 {
+				w.mutex.Lock()
+				w.staleReads = append(w.staleReads, id)
+				w.mutex.Unlock()
+			}
+		}
+		w.pause()
+	}
+}
+
+func (w *Workload) record(record WriteRecord) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.writes = append(w.writes, record)
+	if record.Acknowledged {
+		w.acked = append(w.acked, record.ID)
+	}
+}
+
+func (w *Workload) recordViolation(id string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.violations = append(w.violations, id)
+}
+
+func (w *Workload) randomAcked(random *rand.Rand) string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.acked) == 0 {
+		return ""
+	}
+	return w.acked[random.Intn(len(w.acked))]
+}
+
+func (w *Workload) stopped() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *Workload) pause() {
+	select {
+	case <-w.stop:
+	case <-time.After(w.Interval):
+	}
+}
+
+// Consistent is true when no acknowledged write was lost, no write was
+// duplicated, every writer read its own writes and there are no unexpected
+// rows.
+func (r WorkloadReport) Consistent() bool {
+	return len(r.Lost) == 0 && len(r.Duplicated) == 0 && len(r.ReadYourWritesViolations) == 0 && len(r.Unexpected) == 0
+}
+
+func (r WorkloadReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d writes attempted, %d acknowledged, %d failed\n", r.Attempted, r.Acknowledged, r.Failed)
+	fmt.Fprintf(&b, "lost acknowledged writes: %d %v\n", len(r.Lost), r.Lost)
+	fmt.Fprintf(&b, "duplicated writes: %d %v\n", len(r.Duplicated), r.Duplicated)
+	fmt.Fprintf(&b, "read-your-writes violations: %d %v\n", len(r.ReadYourWritesViolations), r.ReadYourWritesViolations)
+	fmt.Fprintf(&b, "unexpected rows: %d %v\n", len(r.Unexpected), r.Unexpected)
+	fmt.Fprintf(&b, "stale reads: %d\n", len(r.StaleReads))
+	return b.String()
+}
+
+// SQLWorkloadStore keeps writes in a table in pxc_release_test_db. The write
+// ID is unique there, so a retried write is ignored once the first attempt
+// has committed. Each attempt first logs itself, in the same transaction, to
+// a second table without a unique key, so an attempt the cluster applied
+// more than once is still visible.
+type SQLWorkloadStore struct {
+	DB    *sql.DB
+	Table string
+}
+
+func (s *SQLWorkloadStore) Setup() error {
+	_, err := s.DB.Exec(`CREATE DATABASE IF NOT EXISTS pxc_release_test_db`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s, %s", s.table(), s.log()))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.Exec(fmt.Sprintf(`CREATE TABLE %s (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		write_id VARCHAR(64) NOT NULL,
+		UNIQUE KEY (write_id)
+	)`, s.table()))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.Exec(fmt.Sprintf(`CREATE TABLE %s (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		write_id VARCHAR(64) NOT NULL,
+		attempt INT NOT NULL,
+		KEY (write_id, attempt)
+	)`, s.log()))
+	return err
+}
+
+func (s *SQLWorkloadStore) Write(writeID string, attempt int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (write_id, attempt) VALUES (?, ?)", s.log()), writeID, attempt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("INSERT IGNORE INTO %s (write_id) VALUES (?)", s.table()), writeID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLWorkloadStore) Count(writeID string) (int, error) {
+	var count int
+	err := s.DB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE write_id = ?", s.table()), writeID).Scan(&count)
+	return count, err
+}
+
+func (s *SQLWorkloadStore) Counts() (map[string]int, error) {
+	rows, err := s.DB.Query(fmt.Sprintf("SELECT write_id, COUNT(*) FROM %s GROUP BY write_id", s.table()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var id string
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
+
+func (s *SQLWorkloadStore) Applied() (map[Attempt]int, error) {
+	rows, err := s.DB.Query(fmt.Sprintf("SELECT write_id, attempt, COUNT(*) FROM %s GROUP BY write_id, attempt", s.log()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[Attempt]int{}
+	for rows.Next() {
+		var attempt Attempt
+		var count int
+		if err := rows.Scan(&attempt.WriteID, &attempt.Number, &count); err != nil {
+			return nil, err
+		}
+		applied[attempt] = count
+	}
+	return applied, rows.Err()
+}
+
+func (s *SQLWorkloadStore) table() string {
+	return "pxc_release_test_db." + s.Table
+}
+
+func (s *SQLWorkloadStore) log() string {
+	return "pxc_release_test_db." + s.Table + "_applied"
+}
@@ -0,0 +1,257 @@
+package test_helpers_test
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	helpers "specs/test_helpers"
+)
+
+// memoryStore is a WorkloadStore whose faults are chosen per write ID.
+type memoryStore struct {
+	mutex   sync.Mutex
+	rows    map[string]int
+	applied map[helpers.Attempt]int
+
+	// lose acknowledges the write without storing it.
+	lose map[string]bool
+	// commitThenFail stores the write but reports an error, once.
+	commitThenFail map[string]bool
+	// applyTwice applies an attempt twice, as a cluster that replayed a
+	// transaction would.
+	applyTwice map[string]bool
+	// hideFromReads makes Count miss a stored write.
+	hideFromReads map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		rows:           map[string]int{},
+		applied:        map[helpers.Attempt]int{},
+		lose:           map[string]bool{},
+		commitThenFail: map[string]bool{},
+		applyTwice:     map[string]bool{},
+		hideFromReads:  map[string]bool{},
+	}
+}
+
+func (s *memoryStore) Setup() error { return nil }
+
+func (s *memoryStore) Write(id string, attempt int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.lose[id] {
+		return nil
+	}
+	s.rows[id] = 1
+	s.applied[helpers.Attempt{WriteID: id, Number: attempt}]++
+	if s.applyTwice[id] {
+		s.applied[helpers.Attempt{WriteID: id, Number: attempt}]++
+	}
+	if s.commitThenFail[id] {
+		delete(s.commitThenFail, id)
+		return errors.New("invalid connection")
+	}
+	return nil
+}
+
+func (s *memoryStore) Count(id string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.hideFromReads[id] {
+		return 0, nil
+	}
+	return s.rows[id], nil
+}
+
+func (s *memoryStore) Counts() (map[string]int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	counts := map[string]int{}
+	for id, count := range s.rows {
+		counts[id] = count
+	}
+	return counts, nil
+}
+
+func (s *memoryStore) Applied() (map[helpers.Attempt]int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	applied := map[helpers.Attempt]int{}
+	for attempt, count := range s.applied {
+		applied[attempt] = count
+	}
+	return applied, nil
+}
+
+var _ = Describe("Workload", func() {
+	var (
+		store    *memoryStore
+		workload *helpers.Workload
+	)
+
+	BeforeEach(func() {
+		store = newMemoryStore()
+		workload = &helpers.Workload{
+			Store:    store,
+			Writers:  2,
+			Readers:  2,
+			Interval: time.Millisecond,
+			Retries:  1,
+		}
+	})
+
+	run := func() helpers.WorkloadReport {
+		Expect(workload.Start()).To(Succeed())
+		Eventually(func() int { return len(workload.Writes()) }).Should(BeNumerically(">=", 10))
+		workload.Stop()
+
+		report, err := workload.Check()
+		Expect(err).NotTo(HaveOccurred())
+		return report
+	}
+
+	It("reports a clean run as consistent", func() {
+		report := run()
+		Expect(report.Consistent()).To(BeTrue(), report.String())
+		Expect(report.Acknowledged).To(Equal(report.Attempted))
+		Expect(report.Failed).To(BeZero())
+	})
+
+	It("finds lost acknowledged writes", func() {
+		store.lose["w0-1"] = true
+
+		report := run()
+		Expect(report.Consistent()).To(BeFalse())
+		Expect(report.Lost).To(Equal([]string{"w0-1"}))
+		Expect(report.ReadYourWritesViolations).To(ContainElement("w0-1"))
+	})
+
+	It("does not report a retried write that had committed as duplicated", func() {
+		store.commitThenFail["w1-2"] = true
+
+		report := run()
+		Expect(report.Consistent()).To(BeTrue(), report.String())
+		Expect(report.Duplicated).To(BeEmpty())
+
+		for _, write := range workload.Writes() {
+			if write.ID == "w1-2" {
+				Expect(write.Attempts).To(Equal(2))
+				Expect(write.Acknowledged).To(BeTrue())
+			}
+		}
+	})
+
+	It("finds writes the store applied more than once", func() {
+		store.applyTwice["w1-2"] = true
+
+		report := run()
+		Expect(report.Consistent()).To(BeFalse())
+		Expect(report.Duplicated).To(Equal(map[helpers.Attempt]int{{WriteID: "w1-2", Number: 1}: 2}))
+	})
+
+	It("records failed writes without counting them as lost", func() {
+		workload.Retries = 0
+		store.commitThenFail["w0-0"] = true
+
+		report := run()
+		Expect(report.Failed).To(Equal(1))
+		Expect(report.Lost).To(BeEmpty())
+		Expect(report.Consistent()).To(BeTrue(), report.String())
+	})
+
+	It("finds read-your-writes violations", func() {
+		store.hideFromReads["w0-0"] = true
+
+		report := run()
+		Expect(report.Consistent()).To(BeFalse())
+		Expect(report.ReadYourWritesViolations).To(Equal([]string{"w0-0"}))
+		Expect(report.Lost).To(BeEmpty())
+		Expect(report.String()).To(ContainSubstring("read-your-writes violations: 1 [w0-0]"))
+	})
+})
+
+var _ = Describe("SQLWorkloadStore", func() {
+	var (
+		mock  sqlmock.Sqlmock
+		store *helpers.SQLWorkloadStore
+	)
+
+	BeforeEach(func() {
+		db, m, err := sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+		mock = m
+		store = &helpers.SQLWorkloadStore{DB: db, Table: "workload"}
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+
+	It("logs every attempt before the idempotent insert, in one transaction", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO pxc_release_test_db.workload_applied (write_id, attempt) VALUES (?, ?)")).
+			WithArgs("w0-1", 2).
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO pxc_release_test_db.workload (write_id) VALUES (?)")).
+			WithArgs("w0-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		Expect(store.Write("w0-1", 2)).To(Succeed())
+	})
+
+	It("rolls back the log entry when the insert fails", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO pxc_release_test_db.workload_applied").
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		mock.ExpectExec("INSERT IGNORE INTO pxc_release_test_db.workload").
+			WillReturnError(errors.New("WSREP has not yet prepared node for application use"))
+		mock.ExpectRollback()
+
+		Expect(store.Write("w0-1", 1)).To(MatchError(ContainSubstring("WSREP")))
+	})
+
+	It("counts applications of each attempt from the log", func() {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT write_id, attempt, COUNT(*) FROM pxc_release_test_db.workload_applied GROUP BY write_id, attempt")).
+			WillReturnRows(sqlmock.NewRows([]string{"write_id", "attempt", "COUNT(*)"}).
+				AddRow("w0-1", 1, 1).
+				AddRow("w0-1", 2, 1).
+				AddRow("w1-0", 1, 2))
+
+		Expect(store.Applied()).To(Equal(map[helpers.Attempt]int{
+			{WriteID: "w0-1", Number: 1}: 1,
+			{WriteID: "w0-1", Number: 2}: 1,
+			{WriteID: "w1-0", Number: 1}: 2,
+		}))
+	})
+
+	It("counts rows for each write from the data table", func() {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT write_id, COUNT(*) FROM pxc_release_test_db.workload GROUP BY write_id")).
+			WillReturnRows(sqlmock.NewRows([]string{"write_id", "COUNT(*)"}).AddRow("w0-1", 1))
+
+		Expect(store.Counts()).To(Equal(map[string]int{"w0-1": 1}))
+	})
+
+	It("reports an attempt applied twice as duplicated, but not a retry", func() {
+		mock.ExpectQuery("SELECT write_id, COUNT").
+			WillReturnRows(sqlmock.NewRows([]string{"write_id", "COUNT(*)"}))
+		mock.ExpectQuery("SELECT write_id, attempt, COUNT").
+			WillReturnRows(sqlmock.NewRows([]string{"write_id", "attempt", "COUNT(*)"}).
+				AddRow("w0-1", 1, 1).
+				AddRow("w0-1", 2, 1).
+				AddRow("w1-0", 1, 2))
+
+		workload := &helpers.Workload{Store: store}
+		report, err := workload.Check()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Duplicated).To(Equal(map[helpers.Attempt]int{{WriteID: "w1-0", Number: 1}: 2}))
+	})
+})
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"thermostat"
+	"thermostat/localmysql"
+)
+
+// runLocal starts a local mysqld configured from the thermostat config, runs
+// the release suite (or the command after --) against it and stops the
+// server. The exit status is the command's.
+func runLocal(args []string) {
+	flags := flag.NewFlagSet("local", flag.ExitOnError)
+	configPath := flags.String("config", os.Getenv("CONFIG"),
+		"Thermostat config with the properties the server is configured from")
+	mysqld := flags.String("mysqld", "mysqld",
+		"MySQL-compatible server binary; looked up on the PATH")
+	myCnfPath := flags.String("my-cnf", "",
+		"Option file, such as a rendered my.cnf, to include in the generated defaults file")
+	dir := flags.String("dir", "",
+		"Directory for the server's data, socket and logs; kept after the run. Defaults to a temporary directory")
+	flags.Parse(args)
+
+	command := flags.Args()
+	if len(command) == 0 {
+		command = []string{"go", "test", "thermostat/release"}
+	}
+
+	status, err := local(*configPath, *mysqld, *myCnfPath, *dir, command)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "thermostat local failed: %s\n", err)
+		os.Exit(2)
+	}
+	os.Exit(status)
+}
+
+func local(configPath, mysqld, myCnfPath, dir string, command []string) (int, error) {
+	if configPath == "" {
+		return 0, fmt.Errorf("-config or the CONFIG environment variable must be set")
+	}
+
+	config, err := thermostat.LoadConfigFromFile(configPath)
+	if err != nil {
+		return 0, err
+	}
+
+	server := &localmysql.Server{
+		Binary: mysqld,
+		Dir:    dir,
+		Output: os.Stderr,
+	}
+	if myCnfPath != "" {
+		server.Includes = []string{myCnfPath}
+	}
+
+	localConfig, err := server.Start(config)
+	if err != nil {
+		return 0, err
+	}
+	defer server.Stop()
+
+	data, err := yaml.Marshal(localConfig)
+	if err != nil {
+		return 0, err
+	}
+
+	localConfigPath := filepath.Join(server.Dir, "thermostat.yml")
+	err = ioutil.WriteFile(localConfigPath, data, 0600)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "CONFIG="+localConfigPath)
+
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"thermostat"
+)
+
+const usage = `Usage:
+  thermostat check [-config path] [-my-cnf path] [-section name] [-format text|json]
+  thermostat local [-config path] [-mysqld path] [-my-cnf path] [-dir path] [-- command...]`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "check":
+		runCheck(os.Args[2:])
+	case "local":
+		runLocal(os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runCheck(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	configPath := flags.String("config", os.Getenv("CONFIG"),
+		"Thermostat config with the properties used to connect to the node")
+	myCnfPath := flags.String("my-cnf", "/var/vcap/jobs/pxc-mysql/config/my.cnf",
+		"Rendered option file to compare with the running node; !include directives are followed")
+	section := flags.String("section", "mysqld",
+		"Option file section to compare")
+	format := flags.String("format", "text",
+		"Report format, text or json")
+	flags.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "thermostat check failed: unknown -format %q, must be text or json\n", *format)
+		os.Exit(2)
+	}
+
+	report, err := check(*configPath, *myCnfPath, *section)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "thermostat check failed: %s\n", err)
+		os.Exit(2)
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "thermostat check failed: %s\n", err)
+			os.Exit(2)
+		}
+	case "text":
+		fmt.Print(report.String())
+	}
+
+	if report.HasDrift() {
+		os.Exit(1)
+	}
+}
+
+func check(configPath, myCnfPath, section string) (thermostat.DriftReport, error) {
+	if configPath == "" {
+		return thermostat.DriftReport{}, fmt.Errorf("-config or the CONFIG environment variable must be set")
+	}
+
+	config, err := thermostat.LoadConfigFromFile(configPath)
+	if err != nil {
+		return thermostat.DriftReport{}, err
+	}
+
+	options, err := thermostat.ParseOptionFile(myCnfPath)
+	if err != nil {
+		return thermostat.DriftReport{}, err
+	}
+
+	db, err := thermostat.Db(config)
+	if err != nil {
+		return thermostat.DriftReport{}, err
+	}
+	defer db.Close()
+
+	variables, err := thermostat.DbGlobalVariables(db)
+	if err != nil {
+		return thermostat.DriftReport{}, err
+	}
+
+	plugins, err := thermostat.DbPluginStatuses(db)
+	if err != nil {
+		return thermostat.DriftReport{}, err
+	}
+
+	return thermostat.CompareOptions(options.Section(section), variables, plugins), nil
+}
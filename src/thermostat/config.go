@@ -2,16 +2,21 @@ package thermostat
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
 type Config struct {
 	Properties `yaml:"properties"`
-	IP         string `yaml:"ip"`
+	IP         string           `yaml:"ip"`
+	Connection ConnectionConfig `yaml:"connection"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -20,6 +25,10 @@ func LoadConfig() (*Config, error) {
 		return nil, errors.New("CONFIG environment variable must be set")
 	}
 
+	return LoadConfigFromFile(path)
+}
+
+func LoadConfigFromFile(path string) (*Config, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -36,28 +45,59 @@ func LoadConfig() (*Config, error) {
 
 type Properties map[interface{}]interface{}
 
+// NotFoundError is returned when a lens does not resolve to a value. Segment
+// is the part of the lens that could not be found.
+type NotFoundError struct {
+	Lens    string
+	Segment string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("value not found: no %q in %s", e.Segment, e.Lens)
+}
+
+// IsNotFound reports whether err means the value is absent, as opposed to
+// present with an unexpected type.
+func IsNotFound(err error) bool {
+	_, ok := err.(NotFoundError)
+	return ok
+}
+
+// Find resolves a dot separated lens such as "audit_log.enabled". Maps may be
+// Properties or the raw maps produced by yaml.v2, and numeric segments index
+// into lists, as in "instances.0.address".
 func (p Properties) Find(lens string) (val interface{}, err error) {
-	matchers := strings.Split(lens, ".")
+	var node interface{} = p
 
-	if len(matchers) == 1 {
-		val, found := p[matchers[0]]
-		if !found {
-			return nil, errors.New("value not found")
-		}
-		return val, nil
-	}
+	for _, segment := range strings.Split(lens, ".") {
+		var found bool
 
-	m := matchers[0]
+		switch n := node.(type) {
+		case Properties:
+			node, found = n[segment]
+		case map[interface{}]interface{}:
+			node, found = n[segment]
+		case map[string]interface{}:
+			node, found = n[segment]
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil {
+				return nil, fmt.Errorf("cannot look up %q in %s: value is a list and %q is not an index", segment, lens, segment)
+			}
+			found = i >= 0 && i < len(n)
+			if found {
+				node = n[i]
+			}
+		default:
+			return nil, fmt.Errorf("cannot look up %q in %s: value is %T, not a map or list", segment, lens, node)
+		}
 
-	if next, present := p[m]; present {
-		n, ok := next.(Properties)
-		if !ok {
-			panic("type conversion failed")
+		if !found {
+			return nil, NotFoundError{Lens: lens, Segment: segment}
 		}
-		return n.Find(strings.Join(matchers[1:], "."))
-	} else {
-		return nil, errors.New("value not found")
 	}
+
+	return node, nil
 }
 
 func (p Properties) FindString(lens string) (val string, err error) {
@@ -68,7 +108,7 @@ func (p Properties) FindString(lens string) (val string, err error) {
 
 	val, ok := s.(string)
 	if !ok {
-		return "", errors.New("value not a string")
+		return "", typeMismatch(lens, "a string", s)
 	}
 
 	return val, nil
@@ -82,8 +122,245 @@ func (p Properties) FindBool(lens string) (val bool, err error) {
 
 	val, ok := b.(bool)
 	if !ok {
-		return false, errors.New("value not a boolean")
+		return false, typeMismatch(lens, "a boolean", b)
 	}
 
 	return val, nil
 }
+
+func (p Properties) FindInt(lens string) (val int, err error) {
+	i, err := p.Find(lens)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := i.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+
+	return 0, typeMismatch(lens, "an integer", i)
+}
+
+func (p Properties) FindFloat(lens string) (val float64, err error) {
+	f, err := p.Find(lens)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := f.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	}
+
+	return 0, typeMismatch(lens, "a number", f)
+}
+
+// FindDuration parses a Go duration string such as "30s" or "1m30s".
+func (p Properties) FindDuration(lens string) (val time.Duration, err error) {
+	s, err := p.Find(lens)
+	if err != nil {
+		return 0, err
+	}
+
+	str, ok := s.(string)
+	if !ok {
+		return 0, typeMismatch(lens, "a duration", s)
+	}
+
+	val, err = time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("value at %s is not a duration: %s", lens, err)
+	}
+
+	return val, nil
+}
+
+// FindByteSize accepts a plain number of bytes or a MySQL option file style
+// size with a K, M, G or T suffix, such as 256M or 1G.
+func (p Properties) FindByteSize(lens string) (val int64, err error) {
+	b, err := p.Find(lens)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := b.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint64:
+		return int64(v), nil
+	case string:
+		val, err = ParseByteSize(v)
+		if err != nil {
+			return 0, fmt.Errorf("value at %s is not a byte size: %s", lens, err)
+		}
+		return val, nil
+	}
+
+	return 0, typeMismatch(lens, "a byte size", b)
+}
+
+func (p Properties) FindStringSlice(lens string) (val []string, err error) {
+	s, err := p.Find(lens)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := s.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		val = make([]string, 0, len(v))
+		for i, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, typeMismatch(fmt.Sprintf("%s.%d", lens, i), "a string", item)
+			}
+			val = append(val, str)
+		}
+		return val, nil
+	}
+
+	return nil, typeMismatch(lens, "a list of strings", s)
+}
+
+func (p Properties) FindMap(lens string) (val Properties, err error) {
+	m, err := p.Find(lens)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := m.(type) {
+	case Properties:
+		return v, nil
+	case map[interface{}]interface{}:
+		return Properties(v), nil
+	case map[string]interface{}:
+		val = Properties{}
+		for key, item := range v {
+			val[key] = item
+		}
+		return val, nil
+	}
+
+	return nil, typeMismatch(lens, "a map", m)
+}
+
+func (p Properties) FindStringOrDefault(lens string, def string) (string, error) {
+	val, err := p.FindString(lens)
+	if IsNotFound(err) {
+		return def, nil
+	}
+	return val, err
+}
+
+func (p Properties) FindBoolOrDefault(lens string, def bool) (bool, error) {
+	val, err := p.FindBool(lens)
+	if IsNotFound(err) {
+		return def, nil
+	}
+	return val, err
+}
+
+func (p Properties) FindIntOrDefault(lens string, def int) (int, error) {
+	val, err := p.FindInt(lens)
+	if IsNotFound(err) {
+		return def, nil
+	}
+	return val, err
+}
+
+func (p Properties) FindFloatOrDefault(lens string, def float64) (float64, error) {
+	val, err := p.FindFloat(lens)
+	if IsNotFound(err) {
+		return def, nil
+	}
+	return val, err
+}
+
+func (p Properties) FindDurationOrDefault(lens string, def time.Duration) (time.Duration, error) {
+	val, err := p.FindDuration(lens)
+	if IsNotFound(err) {
+		return def, nil
+	}
+	return val, err
+}
+
+func (p Properties) FindByteSizeOrDefault(lens string, def int64) (int64, error) {
+	val, err := p.FindByteSize(lens)
+	if IsNotFound(err) {
+		return def, nil
+	}
+	return val, err
+}
+
+func (p Properties) FindStringSliceOrDefault(lens string, def []string) ([]string, error) {
+	val, err := p.FindStringSlice(lens)
+	if IsNotFound(err) {
+		return def, nil
+	}
+	return val, err
+}
+
+func (p Properties) FindMapOrDefault(lens string, def Properties) (Properties, error) {
+	val, err := p.FindMap(lens)
+	if IsNotFound(err) {
+		return def, nil
+	}
+	return val, err
+}
+
+// ParseByteSize converts a size such as 256M, 1G or 1024MB into bytes.
+// Suffixes are case insensitive and use powers of 1024, as in MySQL option
+// files.
+func ParseByteSize(size string) (int64, error) {
+	s := strings.TrimSpace(size)
+	if len(s) > 2 && strings.ContainsAny(s[len(s)-2:len(s)-1], "KMGTkmgt") && strings.ContainsAny(s[len(s)-1:], "Bb") {
+		s = s[:len(s)-1]
+	}
+	if s == "" {
+		return 0, errors.New("empty byte size")
+	}
+
+	multiplier := int64(1)
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	case "T":
+		multiplier = 1 << 40
+	}
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid byte size %q", size)
+	}
+
+	return n * multiplier, nil
+}
+
+func typeMismatch(lens, expected string, actual interface{}) error {
+	return fmt.Errorf("value at %s is not %s: got %T (%v)", lens, expected, actual, actual)
+}
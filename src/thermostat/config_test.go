@@ -0,0 +1,208 @@
+package thermostat_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	yaml "gopkg.in/yaml.v2"
+
+	. "thermostat"
+)
+
+var _ = Describe("Properties", func() {
+	var properties Properties
+
+	BeforeEach(func() {
+		properties = Properties{
+			"admin_password": "secret",
+			"userstat":       Properties{"enabled": true},
+			"leader_follower": Properties{
+				"semi_sync_ack_timeout_in_ms": 10000,
+			},
+			"buffer_pool_percent":  42.5,
+			"probe_interval":       "1m30s",
+			"max_allowed_packet":   "256M",
+			"innodb_log_file_size": 1073741824,
+			"excluded_accounts":    []interface{}{"monitoring", "bot"},
+			"engine_config":        map[interface{}]interface{}{"event_scheduler": true},
+		}
+	})
+
+	Describe("FindInt", func() {
+		It("finds integers", func() {
+			Expect(properties.FindInt("leader_follower.semi_sync_ack_timeout_in_ms")).To(Equal(10000))
+		})
+
+		It("returns an error naming the lens on a type mismatch", func() {
+			_, err := properties.FindInt("admin_password")
+			Expect(err).To(MatchError(ContainSubstring("value at admin_password is not an integer")))
+		})
+	})
+
+	Describe("FindFloat", func() {
+		It("finds floats and integers", func() {
+			Expect(properties.FindFloat("buffer_pool_percent")).To(Equal(42.5))
+			Expect(properties.FindFloat("innodb_log_file_size")).To(Equal(float64(1073741824)))
+		})
+
+		It("returns an error naming the lens on a type mismatch", func() {
+			_, err := properties.FindFloat("userstat.enabled")
+			Expect(err).To(MatchError(ContainSubstring("value at userstat.enabled is not a number")))
+		})
+	})
+
+	Describe("FindDuration", func() {
+		It("parses duration strings", func() {
+			Expect(properties.FindDuration("probe_interval")).To(Equal(90 * time.Second))
+		})
+
+		It("returns an error naming the lens when the value does not parse", func() {
+			_, err := properties.FindDuration("admin_password")
+			Expect(err).To(MatchError(ContainSubstring("value at admin_password is not a duration")))
+		})
+	})
+
+	Describe("FindByteSize", func() {
+		It("accepts suffixed sizes and plain byte counts", func() {
+			Expect(properties.FindByteSize("max_allowed_packet")).To(Equal(int64(268435456)))
+			Expect(properties.FindByteSize("innodb_log_file_size")).To(Equal(int64(1073741824)))
+		})
+
+		It("returns an error naming the lens when the value does not parse", func() {
+			_, err := properties.FindByteSize("admin_password")
+			Expect(err).To(MatchError(ContainSubstring("value at admin_password is not a byte size")))
+		})
+	})
+
+	Describe("ParseByteSize", func() {
+		It("uses powers of 1024 with case insensitive suffixes", func() {
+			Expect(ParseByteSize("32k")).To(Equal(int64(32768)))
+			Expect(ParseByteSize("256M")).To(Equal(int64(268435456)))
+			Expect(ParseByteSize("1G")).To(Equal(int64(1073741824)))
+			Expect(ParseByteSize("2T")).To(Equal(int64(2199023255552)))
+			Expect(ParseByteSize("512")).To(Equal(int64(512)))
+			Expect(ParseByteSize("1024MB")).To(Equal(int64(1073741824)))
+		})
+
+		It("rejects malformed sizes", func() {
+			_, err := ParseByteSize("1.5G")
+			Expect(err).To(HaveOccurred())
+			_, err = ParseByteSize("")
+			Expect(err).To(HaveOccurred())
+			_, err = ParseByteSize("9000000000T")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("FindStringSlice", func() {
+		It("finds lists of strings", func() {
+			Expect(properties.FindStringSlice("excluded_accounts")).To(Equal([]string{"monitoring", "bot"}))
+		})
+
+		It("returns an error naming the offending element", func() {
+			properties["excluded_accounts"] = []interface{}{"monitoring", 7}
+			_, err := properties.FindStringSlice("excluded_accounts")
+			Expect(err).To(MatchError(ContainSubstring("value at excluded_accounts.1 is not a string")))
+		})
+	})
+
+	Describe("FindMap", func() {
+		It("finds Properties and raw YAML maps", func() {
+			Expect(properties.FindMap("userstat")).To(Equal(Properties{"enabled": true}))
+			Expect(properties.FindMap("engine_config")).To(Equal(Properties{"event_scheduler": true}))
+		})
+
+		It("returns an error naming the lens on a type mismatch", func() {
+			_, err := properties.FindMap("admin_password")
+			Expect(err).To(MatchError(ContainSubstring("value at admin_password is not a map")))
+		})
+	})
+
+	Describe("OrDefault variants", func() {
+		It("return the default when the value is not found", func() {
+			Expect(properties.FindStringOrDefault("workload", "mixed")).To(Equal("mixed"))
+			Expect(properties.FindBoolOrDefault("local_infile", true)).To(BeTrue())
+			Expect(properties.FindIntOrDefault("max_connections", 1500)).To(Equal(1500))
+			Expect(properties.FindFloatOrDefault("ratio", 0.5)).To(Equal(0.5))
+			Expect(properties.FindDurationOrDefault("timeout", time.Second)).To(Equal(time.Second))
+			Expect(properties.FindByteSizeOrDefault("tmp_table_size", 1024)).To(Equal(int64(1024)))
+			Expect(properties.FindStringSliceOrDefault("users", []string{"a"})).To(Equal([]string{"a"}))
+			Expect(properties.FindMapOrDefault("galera", Properties{})).To(Equal(Properties{}))
+		})
+
+		It("return the value when it is found", func() {
+			Expect(properties.FindStringOrDefault("admin_password", "other")).To(Equal("secret"))
+			Expect(properties.FindIntOrDefault("leader_follower.semi_sync_ack_timeout_in_ms", 1)).To(Equal(10000))
+		})
+
+		It("still return an error on a type mismatch", func() {
+			_, err := properties.FindIntOrDefault("admin_password", 1)
+			Expect(err).To(MatchError(ContainSubstring("value at admin_password is not an integer")))
+		})
+	})
+
+	Describe("Find", func() {
+		BeforeEach(func() {
+			properties["audit_log"] = map[interface{}]interface{}{
+				"enabled": true,
+				"exclude": map[interface{}]interface{}{"accounts": []interface{}{"bot"}},
+			}
+			properties["instances"] = []interface{}{
+				map[interface{}]interface{}{"address": "10.0.0.1"},
+				map[interface{}]interface{}{"address": "10.0.0.2"},
+			}
+		})
+
+		It("descends into raw YAML maps", func() {
+			Expect(properties.Find("audit_log.enabled")).To(BeTrue())
+			Expect(properties.FindStringSlice("audit_log.exclude.accounts")).To(Equal([]string{"bot"}))
+		})
+
+		It("indexes into lists", func() {
+			Expect(properties.FindString("instances.1.address")).To(Equal("10.0.0.2"))
+		})
+
+		It("works on configuration loaded from YAML", func() {
+			var config Config
+			Expect(yaml.Unmarshal([]byte(`
+properties:
+  leader_follower:
+    enabled: true
+    replication_mode: semi-sync
+  instances:
+  - address: 10.0.0.1
+`), &config)).To(Succeed())
+
+			Expect(config.Properties.FindString("leader_follower.replication_mode")).To(Equal("semi-sync"))
+			Expect(config.Properties.FindString("instances.0.address")).To(Equal("10.0.0.1"))
+		})
+
+		It("returns a not found error naming the missing segment", func() {
+			_, err := properties.Find("audit_log.missing.enabled")
+			Expect(IsNotFound(err)).To(BeTrue())
+			Expect(err).To(MatchError(`value not found: no "missing" in audit_log.missing.enabled`))
+
+			_, err = properties.Find("instances.2.address")
+			Expect(IsNotFound(err)).To(BeTrue())
+			Expect(err).To(MatchError(`value not found: no "2" in instances.2.address`))
+		})
+
+		It("returns an error instead of panicking when descending into a scalar", func() {
+			_, err := properties.Find("admin_password.length")
+			Expect(IsNotFound(err)).To(BeFalse())
+			Expect(err).To(MatchError(`cannot look up "length" in admin_password.length: value is string, not a map or list`))
+		})
+
+		It("returns an error when indexing a list with a non-numeric segment", func() {
+			_, err := properties.Find("instances.first.address")
+			Expect(err).To(MatchError(ContainSubstring(`"first" is not an index`)))
+		})
+	})
+
+	It("returns a not found error for missing values", func() {
+		_, err := properties.FindString("missing")
+		Expect(IsNotFound(err)).To(BeTrue())
+	})
+})
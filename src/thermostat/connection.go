@@ -0,0 +1,156 @@
+package thermostat
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	DefaultSocket   = "/tmp/mysql.sock"
+	DefaultUsername = "admin"
+	DefaultPort     = 3306
+)
+
+// ConnectionConfig describes how to reach the server under test. Host takes
+// precedence over Socket; with neither set, thermostat connects to
+// DefaultSocket. Password defaults to the admin_password property.
+type ConnectionConfig struct {
+	Socket        string        `yaml:"socket"`
+	Host          string        `yaml:"host"`
+	Port          int           `yaml:"port"`
+	Username      string        `yaml:"username"`
+	Password      string        `yaml:"password"`
+	TLS           *TLSConfig    `yaml:"tls"`
+	Timeout       time.Duration `yaml:"timeout"`
+	Attempts      int           `yaml:"attempts"`
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+// TLSConfig verifies the server certificate against CA. ServerName defaults
+// to Host.
+type TLSConfig struct {
+	CA         string `yaml:"ca"`
+	ServerName string `yaml:"server_name"`
+}
+
+// MySQLConfig returns the driver configuration for c.
+func (c ConnectionConfig) MySQLConfig(properties Properties) (*mysql.Config, error) {
+	password := c.Password
+	if password == "" {
+		var err error
+		password, err = properties.FindString("admin_password")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := &mysql.Config{
+		User:    c.Username,
+		Passwd:  password,
+		Net:     "unix",
+		Addr:    c.Socket,
+		Timeout: c.Timeout,
+	}
+
+	if cfg.User == "" {
+		cfg.User = DefaultUsername
+	}
+
+	switch {
+	case c.Host != "":
+		port := c.Port
+		if port == 0 {
+			port = DefaultPort
+		}
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(port))
+	case c.Socket == "":
+		cfg.Addr = DefaultSocket
+	}
+
+	if c.TLS != nil {
+		key, err := c.registerTLS()
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLSConfig = key
+	}
+
+	return cfg, nil
+}
+
+// registerTLS registers the TLS settings with the driver, which refers to
+// them by name in the DSN.
+func (c ConnectionConfig) registerTLS() (string, error) {
+	caPEM, err := ioutil.ReadFile(c.TLS.CA)
+	if err != nil {
+		return "", err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return "", fmt.Errorf("no certificates found in %s", c.TLS.CA)
+	}
+
+	serverName := c.TLS.ServerName
+	if serverName == "" {
+		serverName = c.Host
+	}
+
+	key := "thermostat-" + c.TLS.CA + "-" + serverName
+	err = mysql.RegisterTLSConfig(key, &tls.Config{
+		RootCAs:    pool,
+		ServerName: serverName,
+	})
+	return key, err
+}
+
+// Db opens a connection to the server described by config and waits until
+// it answers, retrying up to Connection.Attempts times.
+func Db(config *Config) (*sql.DB, error) {
+	cfg, err := config.Connection.MySQLConfig(config.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	err = pingWithRetry(db, config.Connection.Attempts, config.Connection.RetryInterval)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("connecting to %s(%s): %s", cfg.Net, cfg.Addr, err)
+	}
+
+	return db, nil
+}
+
+func pingWithRetry(db *sql.DB, attempts int, interval time.Duration) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = db.Ping(); err == nil {
+			return nil
+		}
+		if attempt < attempts {
+			time.Sleep(interval)
+		}
+	}
+	return err
+}
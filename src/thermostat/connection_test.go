@@ -0,0 +1,111 @@
+package thermostat_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "thermostat"
+)
+
+var _ = Describe("Connection", func() {
+	var properties Properties
+
+	BeforeEach(func() {
+		properties = Properties{"admin_password": "secret"}
+	})
+
+	It("defaults to the admin user over the local socket", func() {
+		cfg, err := ConnectionConfig{}.MySQLConfig(properties)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.FormatDSN()).To(Equal("admin:secret@unix(/tmp/mysql.sock)/"))
+	})
+
+	It("connects to the configured socket", func() {
+		connection := ConnectionConfig{
+			Socket:   "/var/vcap/sys/run/pxc-mysql/mysqld.sock",
+			Username: "monitor",
+			Password: "other",
+		}
+		cfg, err := connection.MySQLConfig(properties)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.FormatDSN()).To(Equal("monitor:other@unix(/var/vcap/sys/run/pxc-mysql/mysqld.sock)/"))
+	})
+
+	It("prefers TCP when a host is configured", func() {
+		connection := ConnectionConfig{
+			Socket:  "/tmp/mysql.sock",
+			Host:    "10.0.0.5",
+			Timeout: 5 * time.Second,
+		}
+		cfg, err := connection.MySQLConfig(properties)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.FormatDSN()).To(Equal("admin:secret@tcp(10.0.0.5:3306)/?timeout=5s"))
+	})
+
+	It("requires admin_password when no password is configured", func() {
+		_, err := ConnectionConfig{}.MySQLConfig(Properties{})
+		Expect(IsNotFound(err)).To(BeTrue())
+	})
+
+	It("rejects a CA file without certificates", func() {
+		dir, err := ioutil.TempDir("", "thermostat-connection")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		ca := filepath.Join(dir, "ca.pem")
+		Expect(ioutil.WriteFile(ca, []byte("not a certificate"), 0600)).To(Succeed())
+
+		connection := ConnectionConfig{Host: "proxy.example.com", TLS: &TLSConfig{CA: ca}}
+		_, err = connection.MySQLConfig(properties)
+		Expect(err).To(MatchError("no certificates found in " + ca))
+	})
+
+	It("loads connection settings from the config file", func() {
+		dir, err := ioutil.TempDir("", "thermostat-connection")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "config.yml")
+		Expect(ioutil.WriteFile(path, []byte(`
+connection:
+  host: proxy.example.com
+  port: 6033
+  tls:
+    ca: /tmp/ca.pem
+    server_name: mysql.example.com
+  timeout: 10s
+  attempts: 3
+  retry_interval: 500ms
+`), 0600)).To(Succeed())
+
+		config, err := LoadConfigFromFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Connection).To(Equal(ConnectionConfig{
+			Host:          "proxy.example.com",
+			Port:          6033,
+			TLS:           &TLSConfig{CA: "/tmp/ca.pem", ServerName: "mysql.example.com"},
+			Timeout:       10 * time.Second,
+			Attempts:      3,
+			RetryInterval: 500 * time.Millisecond,
+		}))
+	})
+
+	It("gives up after the configured number of attempts", func() {
+		config := &Config{
+			Properties: properties,
+			Connection: ConnectionConfig{
+				Host:          "127.0.0.1",
+				Port:          1,
+				Attempts:      2,
+				RetryInterval: time.Millisecond,
+			},
+		}
+		_, err := Db(config)
+		Expect(err).To(MatchError(ContainSubstring("connecting to tcp(127.0.0.1:1)")))
+	})
+})
@@ -2,20 +2,8 @@ package thermostat
 
 import (
 	"database/sql"
-	"fmt"
-
-	. "github.com/onsi/gomega"
 )
 
-func Db(config *Config) (*sql.DB, error) {
-	password, err := config.Properties.FindString("admin_password")
-	if err != nil {
-		return nil, err
-	}
-	dsn := fmt.Sprintf("admin:%s@unix(/tmp/mysql.sock)/", password)
-	return sql.Open("mysql", dsn)
-}
-
 func DbPluginActive(db *sql.DB, plugin string) (bool, error) {
 	var status string
 
@@ -69,26 +57,23 @@ func DbExecuteQuery(db *sql.DB, query string, dest interface{}) error {
 	return db.QueryRow(query).Scan(dest)
 }
 
-func DbSchemaExists(db *sql.DB, schemaName string) bool {
+func DbSchemaExists(db *sql.DB, schemaName string) (bool, error) {
 	sql := `SELECT COUNT(*) = 1 FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = ?`
 	var result bool
 	err := db.QueryRow(sql, schemaName).Scan(&result)
-	ExpectWithOffset(1, err).ToNot(HaveOccurred())
-	return result
+	return result, err
 }
 
-func DbTableExists(db *sql.DB, schemaName, tableName string) bool {
+func DbTableExists(db *sql.DB, schemaName, tableName string) (bool, error) {
 	sql := `SELECT COUNT(*) = 1 FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`
 	var result bool
 	err := db.QueryRow(sql, schemaName, tableName).Scan(&result)
-	ExpectWithOffset(1, err).ToNot(HaveOccurred())
-	return result
+	return result, err
 }
 
-func DbEventExists(db *sql.DB, schemaName, eventName string) bool {
+func DbEventExists(db *sql.DB, schemaName, eventName string) (bool, error) {
 	sql := `SELECT COUNT(*) = 1 FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA = ? AND EVENT_NAME = ?`
 	var result bool
 	err := db.QueryRow(sql, schemaName, eventName).Scan(&result)
-	ExpectWithOffset(1, err).ToNot(HaveOccurred())
-	return result
+	return result, err
 }
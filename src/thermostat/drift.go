@@ -0,0 +1,264 @@
+package thermostat
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Drift is a declared option whose running value differs from the option
+// file.
+type Drift struct {
+	Option   string `json:"option"`
+	Variable string `json:"variable"`
+	Declared string `json:"declared"`
+	Actual   string `json:"actual"`
+}
+
+// DriftReport is the result of comparing an option file section with a
+// running server.
+type DriftReport struct {
+	Checked   []string `json:"checked"`
+	Unchecked []string `json:"unchecked"`
+	Drifts    []Drift  `json:"drifts"`
+}
+
+func (r DriftReport) HasDrift() bool {
+	return len(r.Drifts) > 0
+}
+
+func (r DriftReport) String() string {
+	var b strings.Builder
+	for _, d := range r.Drifts {
+		fmt.Fprintf(&b, "DRIFT %s: declared %q, running %q\n", d.Variable, d.Declared, d.Actual)
+	}
+	fmt.Fprintf(&b, "%d options checked, %d drifted, %d not comparable", len(r.Checked), len(r.Drifts), len(r.Unchecked))
+	if len(r.Unchecked) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(r.Unchecked, ", "))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// uncheckedOptions are options with no matching variable, or whose variable
+// does not show the declared value.
+var uncheckedOptions = map[string]bool{
+	"user":           true,
+	"language":       true,
+	"wsrep_sst_auth": true,
+	"symbolic_links": true,
+	"skip_symlink":   true,
+}
+
+// pathVariables are variables the server reports as directories, with a
+// trailing slash whether or not the option file has one.
+var pathVariables = map[string]bool{
+	"basedir":          true,
+	"datadir":          true,
+	"tmpdir":           true,
+	"secure_file_priv": true,
+}
+
+// CompareOptions compares the options declared in an option file section
+// with the server's global variables and plugin statuses, as returned by
+// DbGlobalVariables and DbPluginStatuses.
+func CompareOptions(declared, variables, plugins map[string]string) DriftReport {
+	report := DriftReport{}
+
+	var names []string
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, option := range names {
+		value := declared[option]
+
+		if option == "plugin_load" || option == "plugin_load_add" {
+			report.comparePlugins(option, value, plugins)
+			continue
+		}
+
+		variable, expected := option, value
+		if option == "log_bin" {
+			// log_bin declares the binlog basename; the variable only says
+			// whether binary logging is enabled.
+			expected = "ON"
+		}
+		if option == "innodb_buffer_pool_size" {
+			expected = roundBufferPoolSize(value, variables)
+		}
+
+		actual, found := variables[variable]
+		if !found || uncheckedOptions[option] {
+			report.Unchecked = append(report.Unchecked, option)
+			continue
+		}
+
+		report.Checked = append(report.Checked, option)
+		if !valuesMatch(variable, expected, actual) {
+			report.Drifts = append(report.Drifts, Drift{Option: option, Variable: variable, Declared: value, Actual: actual})
+		}
+	}
+
+	return report
+}
+
+func (r *DriftReport) comparePlugins(option, value string, plugins map[string]string) {
+	for _, plugin := range strings.Split(value, ";") {
+		name := strings.TrimSpace(strings.SplitN(plugin, "=", 2)[0])
+		if name == "" {
+			continue
+		}
+
+		r.Checked = append(r.Checked, option+":"+name)
+		if status := plugins[strings.ToLower(name)]; status != "ACTIVE" {
+			r.Drifts = append(r.Drifts, Drift{Option: option, Variable: "plugin " + name, Declared: "ACTIVE", Actual: status})
+		}
+	}
+}
+
+// valuesMatch compares a declared option value with the value the server
+// reports, treating equivalent spellings of booleans, sizes and lists as
+// equal.
+func valuesMatch(variable, declared, actual string) bool {
+	if strings.EqualFold(declared, actual) {
+		return true
+	}
+
+	if declaredBool, ok := parseOptionBool(declared); ok {
+		if actualBool, ok := parseOptionBool(actual); ok {
+			return declaredBool == actualBool
+		}
+	}
+
+	if declaredSize, err := ParseByteSize(declared); err == nil {
+		if actualSize, err := ParseByteSize(actual); err == nil {
+			return declaredSize == actualSize
+		}
+	}
+
+	if pathVariables[variable] {
+		return strings.TrimRight(declared, "/") == strings.TrimRight(actual, "/")
+	}
+
+	if variable == "wsrep_provider_options" {
+		return providerOptionsMatch(declared, actual)
+	}
+
+	if strings.Contains(declared, ",") || strings.Contains(actual, ",") {
+		return listsMatch(declared, actual)
+	}
+
+	return false
+}
+
+// roundBufferPoolSize rounds a declared buffer pool size up to a multiple of
+// innodb_buffer_pool_chunk_size * innodb_buffer_pool_instances, as the
+// server does at startup. The declared value is returned unchanged when it
+// or the chunk variables cannot be parsed.
+func roundBufferPoolSize(declared string, variables map[string]string) string {
+	size, err := ParseByteSize(declared)
+	if err != nil {
+		return declared
+	}
+	chunkSize, err := strconv.ParseInt(variables["innodb_buffer_pool_chunk_size"], 10, 64)
+	if err != nil || chunkSize <= 0 {
+		return declared
+	}
+	instances, err := strconv.ParseInt(variables["innodb_buffer_pool_instances"], 10, 64)
+	if err != nil || instances <= 0 {
+		return declared
+	}
+
+	multiple := chunkSize * instances
+	if remainder := size % multiple; remainder != 0 {
+		size += multiple - remainder
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+func parseOptionBool(value string) (bool, bool) {
+	switch strings.ToUpper(value) {
+	case "ON", "TRUE", "1", "YES":
+		return true, true
+	case "OFF", "FALSE", "0", "NO":
+		return false, true
+	}
+	return false, false
+}
+
+func listsMatch(declared, actual string) bool {
+	declaredItems := listItems(declared, ",")
+	actualItems := listItems(actual, ",")
+	if len(declaredItems) != len(actualItems) {
+		return false
+	}
+	for item := range declaredItems {
+		if _, found := actualItems[item]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// providerOptionsMatch checks that every declared provider option has the
+// declared value. The server reports every provider option, so additional
+// options in actual are expected.
+func providerOptionsMatch(declared, actual string) bool {
+	actualItems := listItems(actual, ";")
+	for key, value := range listItems(declared, ";") {
+		actualValue, found := actualItems[key]
+		if !found || !valuesMatch(key, value, actualValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func listItems(list, separator string) map[string]string {
+	items := map[string]string{}
+	for _, item := range strings.Split(list, separator) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		items[key] = ""
+		if len(parts) == 2 {
+			items[key] = strings.TrimSpace(parts[1])
+		}
+	}
+	return items
+}
+
+func DbGlobalVariables(db *sql.DB) (map[string]string, error) {
+	return dbNameValues(db, "SHOW GLOBAL VARIABLES")
+}
+
+func DbPluginStatuses(db *sql.DB) (map[string]string, error) {
+	return dbNameValues(db, "SELECT plugin_name, plugin_status FROM information_schema.plugins")
+}
+
+func dbNameValues(db *sql.DB, query string) (map[string]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	values := map[string]string{}
+	for rows.Next() {
+		var name string
+		var value sql.NullString
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		values[strings.ToLower(name)] = value.String
+	}
+
+	return values, rows.Err()
+}
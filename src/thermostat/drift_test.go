@@ -0,0 +1,117 @@
+package thermostat_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "thermostat"
+)
+
+var _ = Describe("CompareOptions", func() {
+	var (
+		declared  map[string]string
+		variables map[string]string
+		plugins   map[string]string
+	)
+
+	BeforeEach(func() {
+		declared = map[string]string{
+			"max_allowed_packet":     "256M",
+			"innodb_log_file_size":   "1024MB",
+			"slow_query_log":         "1",
+			"skip_external_locking":  "TRUE",
+			"log_bin":                "mysql-bin",
+			"sql_mode":               "NO_AUTO_CREATE_USER,NO_ENGINE_SUBSTITUTION,STRICT_ALL_TABLES",
+			"wsrep_provider_options": "gcache.size=512M;pc.recovery=FALSE",
+			"plugin_load":            "audit_log=audit_log.so",
+			"user":                   "vcap",
+			"language":               "/var/vcap/packages/pxc/share",
+			"max_connections":        "1500",
+		}
+		variables = map[string]string{
+			"max_allowed_packet":     "268435456",
+			"innodb_log_file_size":   "1073741824",
+			"slow_query_log":         "ON",
+			"skip_external_locking":  "ON",
+			"log_bin":                "ON",
+			"sql_mode":               "STRICT_ALL_TABLES,NO_AUTO_CREATE_USER,NO_ENGINE_SUBSTITUTION",
+			"wsrep_provider_options": "base_dir = /var/vcap/store/pxc-mysql/; gcache.size = 512M; pc.recovery = false",
+			"max_connections":        "1500",
+		}
+		plugins = map[string]string{"audit_log": "ACTIVE"}
+	})
+
+	It("normalizes units, booleans and lists", func() {
+		report := CompareOptions(declared, variables, plugins)
+
+		Expect(report.HasDrift()).To(BeFalse(), report.String())
+		Expect(report.Checked).To(ContainElement("plugin_load:audit_log"))
+		Expect(report.Unchecked).To(ConsistOf("language", "user"))
+	})
+
+	It("reports drifted variables and inactive plugins", func() {
+		variables["max_connections"] = "750"
+		variables["wsrep_provider_options"] = "gcache.size = 128M; pc.recovery = false"
+		plugins["audit_log"] = "DISABLED"
+
+		report := CompareOptions(declared, variables, plugins)
+
+		Expect(report.Drifts).To(ConsistOf(
+			Drift{Option: "max_connections", Variable: "max_connections", Declared: "1500", Actual: "750"},
+			Drift{Option: "wsrep_provider_options", Variable: "wsrep_provider_options",
+				Declared: "gcache.size=512M;pc.recovery=FALSE", Actual: "gcache.size = 128M; pc.recovery = false"},
+			Drift{Option: "plugin_load", Variable: "plugin audit_log", Declared: "ACTIVE", Actual: "DISABLED"},
+		))
+		Expect(report.String()).To(ContainSubstring(`DRIFT max_connections: declared "1500", running "750"`))
+	})
+
+	It("reports binary logging that is disabled", func() {
+		variables["log_bin"] = "OFF"
+
+		report := CompareOptions(declared, variables, plugins)
+		Expect(report.Drifts).To(ConsistOf(
+			Drift{Option: "log_bin", Variable: "log_bin", Declared: "mysql-bin", Actual: "OFF"},
+		))
+	})
+
+	It("ignores the trailing slash the server adds to directories", func() {
+		declared["datadir"] = "/var/vcap/store/pxc-mysql"
+		declared["tmpdir"] = "/var/vcap/data/pxc-mysql/tmp/"
+		variables["datadir"] = "/var/vcap/store/pxc-mysql/"
+		variables["tmpdir"] = "/var/vcap/data/pxc-mysql/tmp/"
+
+		report := CompareOptions(declared, variables, plugins)
+		Expect(report.HasDrift()).To(BeFalse(), report.String())
+		Expect(report.Checked).To(ContainElement("datadir"))
+
+		variables["datadir"] = "/var/vcap/store/mysql/"
+		report = CompareOptions(declared, variables, plugins)
+		Expect(report.Drifts).To(ConsistOf(
+			Drift{Option: "datadir", Variable: "datadir", Declared: "/var/vcap/store/pxc-mysql", Actual: "/var/vcap/store/mysql/"},
+		))
+	})
+
+	Context("innodb_buffer_pool_size", func() {
+		BeforeEach(func() {
+			declared["innodb_buffer_pool_size"] = "1000M"
+			variables["innodb_buffer_pool_chunk_size"] = "134217728"
+			variables["innodb_buffer_pool_instances"] = "8"
+		})
+
+		It("rounds the declared size up to a multiple of the chunk size and instances", func() {
+			variables["innodb_buffer_pool_size"] = "1073741824"
+
+			report := CompareOptions(declared, variables, plugins)
+			Expect(report.HasDrift()).To(BeFalse(), report.String())
+		})
+
+		It("reports a size that differs from the rounded size", func() {
+			variables["innodb_buffer_pool_size"] = "1048576000"
+
+			report := CompareOptions(declared, variables, plugins)
+			Expect(report.Drifts).To(ConsistOf(
+				Drift{Option: "innodb_buffer_pool_size", Variable: "innodb_buffer_pool_size", Declared: "1000M", Actual: "1048576000"},
+			))
+		})
+	})
+})
@@ -0,0 +1,263 @@
+package thermostat
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Expectations describes the server configuration a deployment should have.
+// It is loaded from a YAML or JSON file so that expectations can change
+// alongside the job templates without changes to the suite.
+type Expectations struct {
+	Variables []VariableExpectation `yaml:"variables"`
+	Plugins   []PluginExpectation   `yaml:"plugins"`
+	Schemas   []SchemaExpectation   `yaml:"schemas"`
+}
+
+// Condition limits an expectation to deployments whose Properties match.
+// Without Equals or NotEquals, the property must be the boolean true.
+type Condition struct {
+	Property  string      `yaml:"property"`
+	Equals    interface{} `yaml:"equals"`
+	NotEquals interface{} `yaml:"not_equals"`
+}
+
+// Expectation is a single entry of any kind, so that each can be reported
+// on its own.
+type Expectation interface {
+	String() string
+	Check(db *sql.DB, properties Properties) CheckResult
+}
+
+type VariableExpectation struct {
+	Name              string     `yaml:"name"`
+	Value             string     `yaml:"value"`
+	ValueFromProperty string     `yaml:"value_from_property"`
+	Description       string     `yaml:"description"`
+	When              *Condition `yaml:"when"`
+}
+
+type PluginExpectation struct {
+	Name        string     `yaml:"name"`
+	Active      bool       `yaml:"active"`
+	Description string     `yaml:"description"`
+	When        *Condition `yaml:"when"`
+}
+
+// SchemaExpectation checks for a schema, or a table or event within it when
+// Table or Event is set. Exists defaults to true.
+type SchemaExpectation struct {
+	Schema      string     `yaml:"schema"`
+	Table       string     `yaml:"table"`
+	Event       string     `yaml:"event"`
+	Exists      *bool      `yaml:"exists"`
+	Description string     `yaml:"description"`
+	When        *Condition `yaml:"when"`
+}
+
+// CheckResult is the outcome of a single expectation.
+type CheckResult struct {
+	Kind        string
+	Name        string
+	Description string
+	Expected    string
+	Actual      string
+	Skipped     bool
+	Err         error
+}
+
+func (r CheckResult) Failed() bool {
+	return !r.Skipped && (r.Err != nil || r.Expected != r.Actual)
+}
+
+func (r CheckResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s %s: %s", r.Kind, r.Name, r.Err)
+	}
+	return fmt.Sprintf("%s %s: expected %q, got %q", r.Kind, r.Name, r.Expected, r.Actual)
+}
+
+func LoadExpectations(path string) (*Expectations, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var expectations Expectations
+	err = yaml.Unmarshal(data, &expectations)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return &expectations, nil
+}
+
+// ExpectationsPath returns the file named by the EXPECTATIONS environment
+// variable, falling back to defaultPath.
+func ExpectationsPath(defaultPath string) string {
+	if path := os.Getenv("EXPECTATIONS"); path != "" {
+		return path
+	}
+	return defaultPath
+}
+
+// All returns every expectation: variables, then plugins, then schemas.
+func (e Expectations) All() []Expectation {
+	var all []Expectation
+
+	for _, v := range e.Variables {
+		all = append(all, v)
+	}
+	for _, p := range e.Plugins {
+		all = append(all, p)
+	}
+	for _, s := range e.Schemas {
+		all = append(all, s)
+	}
+
+	return all
+}
+
+// Check runs every expectation against db. Expectations whose condition does
+// not hold for properties are reported as skipped, and those whose condition
+// names a missing property as failed.
+func (e Expectations) Check(db *sql.DB, properties Properties) []CheckResult {
+	var results []CheckResult
+	for _, expectation := range e.All() {
+		results = append(results, expectation.Check(db, properties))
+	}
+	return results
+}
+
+func (v VariableExpectation) String() string {
+	return describe("variable "+v.Name, v.Description)
+}
+
+func (v VariableExpectation) Check(db *sql.DB, properties Properties) CheckResult {
+	result := CheckResult{Kind: "variable", Name: v.Name, Description: v.Description}
+
+	result.Skipped, result.Err = v.When.skips(properties)
+	if result.Skipped || result.Err != nil {
+		return result
+	}
+
+	result.Expected = v.Value
+	if v.ValueFromProperty != "" {
+		expected, err := properties.Find(v.ValueFromProperty)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Expected = fmt.Sprint(expected)
+	}
+
+	result.Actual, result.Err = DbVariableValue(db, v.Name)
+	return result
+}
+
+func (p PluginExpectation) String() string {
+	return describe("plugin "+p.Name, p.Description)
+}
+
+func (p PluginExpectation) Check(db *sql.DB, properties Properties) CheckResult {
+	result := CheckResult{Kind: "plugin", Name: p.Name, Description: p.Description}
+
+	result.Skipped, result.Err = p.When.skips(properties)
+	if result.Skipped || result.Err != nil {
+		return result
+	}
+
+	active, err := DbPluginActive(db, p.Name)
+	result.Expected = activeString(p.Active)
+	result.Actual = activeString(active)
+	result.Err = err
+	return result
+}
+
+func (s SchemaExpectation) String() string {
+	name := "schema " + s.Schema
+	switch {
+	case s.Table != "":
+		name = "table " + s.Schema + "." + s.Table
+	case s.Event != "":
+		name = "event " + s.Schema + "." + s.Event
+	}
+	return describe(name, s.Description)
+}
+
+func (s SchemaExpectation) Check(db *sql.DB, properties Properties) CheckResult {
+	result := CheckResult{Kind: "schema", Name: s.Schema, Description: s.Description}
+	switch {
+	case s.Table != "":
+		result.Kind, result.Name = "table", s.Schema+"."+s.Table
+	case s.Event != "":
+		result.Kind, result.Name = "event", s.Schema+"."+s.Event
+	}
+
+	result.Skipped, result.Err = s.When.skips(properties)
+	if result.Skipped || result.Err != nil {
+		return result
+	}
+
+	var exists bool
+	switch {
+	case s.Table != "":
+		exists, result.Err = DbTableExists(db, s.Schema, s.Table)
+	case s.Event != "":
+		exists, result.Err = DbEventExists(db, s.Schema, s.Event)
+	default:
+		exists, result.Err = DbSchemaExists(db, s.Schema)
+	}
+
+	result.Expected = existsString(s.Exists == nil || *s.Exists)
+	result.Actual = existsString(exists)
+	return result
+}
+
+// skips reports whether the condition does not hold for properties. A
+// property that cannot be found is an error rather than a skip, so that a
+// misspelled lens does not silently skip the expectation.
+func (c *Condition) skips(properties Properties) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+
+	value, err := properties.Find(c.Property)
+	if err != nil {
+		return false, fmt.Errorf("when.property: %s", err)
+	}
+
+	switch {
+	case c.Equals != nil:
+		return fmt.Sprint(value) != fmt.Sprint(c.Equals), nil
+	case c.NotEquals != nil:
+		return fmt.Sprint(value) == fmt.Sprint(c.NotEquals), nil
+	default:
+		return value != true, nil
+	}
+}
+
+func describe(name, description string) string {
+	if description == "" {
+		return name
+	}
+	return name + ": " + description
+}
+
+func activeString(active bool) string {
+	if active {
+		return "ACTIVE"
+	}
+	return "INACTIVE"
+}
+
+func existsString(exists bool) string {
+	if exists {
+		return "exists"
+	}
+	return "absent"
+}
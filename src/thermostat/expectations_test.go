@@ -0,0 +1,184 @@
+package thermostat_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "thermostat"
+)
+
+var _ = Describe("Expectations", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "expectations")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeFile := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	Describe("LoadExpectations", func() {
+		It("loads YAML", func() {
+			path := writeFile("expectations.yml", `
+variables:
+- name: character_set_server
+  value_from_property: default_char_set
+plugins:
+- name: audit_log
+  active: true
+  when:
+    property: audit_log.enabled
+schemas:
+- schema: cf_metadata
+  event: purge
+  exists: false
+`)
+			expectations, err := LoadExpectations(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(expectations.Variables).To(Equal([]VariableExpectation{
+				{Name: "character_set_server", ValueFromProperty: "default_char_set"},
+			}))
+			Expect(expectations.Plugins).To(Equal([]PluginExpectation{
+				{Name: "audit_log", Active: true, When: &Condition{Property: "audit_log.enabled"}},
+			}))
+			Expect(expectations.Schemas).To(HaveLen(1))
+			Expect(expectations.Schemas[0].Event).To(Equal("purge"))
+			Expect(*expectations.Schemas[0].Exists).To(BeFalse())
+		})
+
+		It("loads JSON", func() {
+			path := writeFile("expectations.json", `{
+  "variables": [
+    {"name": "max_connections", "value": "750",
+     "when": {"property": "workload", "equals": "mixed"}}
+  ]
+}`)
+			expectations, err := LoadExpectations(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expectations.Variables).To(Equal([]VariableExpectation{
+				{Name: "max_connections", Value: "750", When: &Condition{Property: "workload", Equals: "mixed"}},
+			}))
+		})
+
+		It("returns an error naming the file when it does not parse", func() {
+			path := writeFile("bad.yml", "variables: {")
+			_, err := LoadExpectations(path)
+			Expect(err).To(MatchError(ContainSubstring(path)))
+		})
+
+		It("loads the expectations shipped with the suite", func() {
+			expectations, err := LoadExpectations("expectations.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expectations.Variables).NotTo(BeEmpty())
+			Expect(expectations.Plugins).NotTo(BeEmpty())
+			Expect(expectations.Schemas).NotTo(BeEmpty())
+
+			var conditional []string
+			for _, plugin := range expectations.Plugins {
+				if plugin.When != nil {
+					conditional = append(conditional, plugin.Name)
+				}
+			}
+			Expect(conditional).To(ContainElement("rpl_semi_sync_master"))
+		})
+	})
+
+	Describe("All", func() {
+		It("lists each expectation once, described by kind and name", func() {
+			expectations := Expectations{
+				Variables: []VariableExpectation{{Name: "max_connections", Value: "750"}},
+				Plugins:   []PluginExpectation{{Name: "audit_log", Active: true, Description: "audits connections"}},
+				Schemas: []SchemaExpectation{
+					{Schema: "sys"},
+					{Schema: "sys", Table: "sys_config"},
+					{Schema: "cf_metadata", Event: "purge"},
+				},
+			}
+
+			var names []string
+			for _, expectation := range expectations.All() {
+				names = append(names, expectation.String())
+			}
+			Expect(names).To(Equal([]string{
+				"variable max_connections",
+				"plugin audit_log: audits connections",
+				"schema sys",
+				"table sys.sys_config",
+				"event cf_metadata.purge",
+			}))
+		})
+	})
+
+	Describe("Check", func() {
+		It("skips expectations whose condition does not hold", func() {
+			expectations := Expectations{
+				Variables: []VariableExpectation{
+					{Name: "rpl_semi_sync_slave_enabled", Value: "ON",
+						When: &Condition{Property: "leader_follower.replication_mode", Equals: "semi-sync"}},
+				},
+				Plugins: []PluginExpectation{
+					{Name: "audit_log", Active: true, When: &Condition{Property: "audit_log.enabled"}},
+				},
+			}
+			properties := Properties{
+				"leader_follower": map[interface{}]interface{}{"replication_mode": "async"},
+				"audit_log":       map[interface{}]interface{}{"enabled": false},
+			}
+
+			results := expectations.Check(nil, properties)
+			Expect(results).To(HaveLen(2))
+			for _, result := range results {
+				Expect(result.Skipped).To(BeTrue(), result.Name)
+				Expect(result.Failed()).To(BeFalse())
+			}
+		})
+
+		It("skips expectations whose property equals not_equals", func() {
+			expectations := Expectations{
+				Plugins: []PluginExpectation{
+					{Name: "rpl_semi_sync_master", Active: false,
+						When: &Condition{Property: "leader_follower.replication_mode", NotEquals: "semi-sync"}},
+				},
+			}
+			properties := Properties{
+				"leader_follower": map[interface{}]interface{}{"replication_mode": "semi-sync"},
+			}
+
+			results := expectations.Check(nil, properties)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Skipped).To(BeTrue())
+		})
+
+		It("fails expectations whose condition names a missing property", func() {
+			expectations := Expectations{
+				Schemas: []SchemaExpectation{
+					{Schema: "cf_metadata", Table: "settings", When: &Condition{Property: "leader_follower.replication_mod"}},
+				},
+			}
+			properties := Properties{
+				"leader_follower": map[interface{}]interface{}{"replication_mode": "async"},
+			}
+
+			results := expectations.Check(nil, properties)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Skipped).To(BeFalse())
+			Expect(results[0].Failed()).To(BeTrue())
+			Expect(results[0].Err).To(MatchError(ContainSubstring("when.property")))
+			Expect(results[0].String()).To(HavePrefix("table cf_metadata.settings: "))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package localmysql_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestLocalmysql(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Localmysql")
+}
@@ -0,0 +1,295 @@
+// Package localmysql runs a throwaway mysqld for the thermostat suite, so
+// that configuration expectations can be checked without a BOSH deployment.
+package localmysql
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"thermostat"
+)
+
+// Server is a mysqld started from a defaults file generated from a
+// thermostat Config. The binary must support --initialize-insecure, as
+// MySQL and Percona Server 5.7 and later do.
+type Server struct {
+	// Binary is the mysqld to run; "mysqld" is looked up on the PATH.
+	Binary string
+	// Dir holds the defaults file, data directory, socket and logs. When it
+	// is empty, Start creates a temporary directory that Stop removes.
+	Dir string
+	// Includes are option files, such as a rendered my.cnf, included before
+	// the generated options.
+	Includes []string
+	// Options are extra [mysqld] options; they override the options derived
+	// from the Config.
+	Options map[string]string
+	// Output receives the server's stdout and stderr.
+	Output io.Writer
+	// StartTimeout bounds how long Start waits for the server to accept
+	// connections.
+	StartTimeout time.Duration
+
+	ownsDir bool
+	cmd     *exec.Cmd
+	exited  chan error
+}
+
+// propertyOptions maps boolean Properties to the server options the job
+// templates derive from them. loose_ options are ignored by servers that do
+// not know them.
+var propertyOptions = []struct {
+	lens   string
+	option string
+	on     string
+	off    string
+}{
+	{"enable_lower_case_table_names", "lower_case_table_names", "1", "0"},
+	{"local_infile", "local_infile", "ON", "OFF"},
+	{"userstat.enabled", "loose_userstat", "ON", "OFF"},
+}
+
+// ServerOptions returns the [mysqld] options for a server in dir.
+func ServerOptions(config *thermostat.Config, dir string) map[string]string {
+	options := map[string]string{
+		"datadir":         filepath.Join(dir, "data"),
+		"socket":          filepath.Join(dir, "mysql.sock"),
+		"pid_file":        filepath.Join(dir, "mysql.pid"),
+		"log_error":       filepath.Join(dir, "mysql.err"),
+		"skip_networking": "ON",
+		"loose_mysqlx":    "OFF",
+		"loose_wsrep_on":  "OFF",
+	}
+
+	for _, p := range propertyOptions {
+		enabled, err := config.Properties.FindBool(p.lens)
+		if err != nil {
+			continue
+		}
+		options[p.option] = p.off
+		if enabled {
+			options[p.option] = p.on
+		}
+	}
+
+	return options
+}
+
+// DefaultsFile renders the generated option file.
+func (s *Server) DefaultsFile(config *thermostat.Config) []byte {
+	options := ServerOptions(config, s.Dir)
+	for name, value := range s.Options {
+		options[thermostat.NormalizeOptionName(name)] = value
+	}
+
+	var names []string
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, include := range s.Includes {
+		fmt.Fprintf(&b, "!include %s\n", include)
+	}
+	b.WriteString("[mysqld]\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s = %s\n", name, options[name])
+	}
+	return b.Bytes()
+}
+
+// Start initializes a data directory when there is none, starts mysqld and
+// creates the admin user and default schema described by config. It returns
+// a copy of config that connects to the local server.
+func (s *Server) Start(config *thermostat.Config) (*thermostat.Config, error) {
+	binary, err := exec.LookPath(s.binary())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Dir == "" {
+		s.Dir, err = ioutil.TempDir("", "thermostat-mysql")
+		if err != nil {
+			return nil, err
+		}
+		s.ownsDir = true
+	}
+
+	defaultsFile := filepath.Join(s.Dir, "my.cnf")
+	err = ioutil.WriteFile(defaultsFile, s.DefaultsFile(config), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	dataDir := filepath.Join(s.Dir, "data")
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		err = s.run(binary, "--defaults-file="+defaultsFile, "--initialize-insecure")
+		if err != nil {
+			return nil, fmt.Errorf("initializing %s: %s", dataDir, err)
+		}
+	}
+
+	s.cmd = exec.Command(binary, "--defaults-file="+defaultsFile)
+	s.cmd.Stdout = s.Output
+	s.cmd.Stderr = s.Output
+	err = s.cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	s.exited = make(chan error, 1)
+	go func() { s.exited <- s.cmd.Wait() }()
+
+	root, err := s.waitForRoot()
+	if err != nil {
+		_ = s.Stop()
+		return nil, err
+	}
+	defer root.Close()
+
+	local, err := s.localConfig(config)
+	if err != nil {
+		_ = s.Stop()
+		return nil, err
+	}
+
+	err = seed(root, local)
+	if err != nil {
+		_ = s.Stop()
+		return nil, err
+	}
+
+	return local, nil
+}
+
+// Stop shuts mysqld down, killing it if it has not exited within a minute,
+// and removes the directory Start created.
+func (s *Server) Stop() error {
+	var err error
+	if s.cmd != nil && s.cmd.Process != nil {
+		_ = s.cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-s.exited:
+		case <-time.After(time.Minute):
+			err = s.cmd.Process.Kill()
+			<-s.exited
+		}
+		s.cmd = nil
+	}
+
+	if s.ownsDir {
+		if removeErr := os.RemoveAll(s.Dir); err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
+
+func (s *Server) binary() string {
+	if s.Binary == "" {
+		return "mysqld"
+	}
+	return s.Binary
+}
+
+func (s *Server) run(binary string, args ...string) error {
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout = s.Output
+	cmd.Stderr = s.Output
+	return cmd.Run()
+}
+
+// waitForRoot connects as the passwordless root user that
+// --initialize-insecure creates.
+func (s *Server) waitForRoot() (*sql.DB, error) {
+	cfg := &mysql.Config{User: "root", Net: "unix", Addr: filepath.Join(s.Dir, "mysql.sock")}
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := s.StartTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case exitErr := <-s.exited:
+			s.exited <- exitErr
+			_ = db.Close()
+			return nil, fmt.Errorf("mysqld exited before accepting connections (%v); see %s", exitErr, filepath.Join(s.Dir, "mysql.err"))
+		default:
+		}
+
+		if err = db.Ping(); err == nil {
+			return db, nil
+		}
+		if time.Now().After(deadline) {
+			_ = db.Close()
+			return nil, fmt.Errorf("mysqld did not accept connections within %s: %s", timeout, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func (s *Server) localConfig(config *thermostat.Config) (*thermostat.Config, error) {
+	connection := config.Connection
+	connection.TLS = nil
+	cfg, err := connection.MySQLConfig(config.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	local := *config
+	local.IP = "127.0.0.1"
+	local.Connection = thermostat.ConnectionConfig{
+		Socket:   filepath.Join(s.Dir, "mysql.sock"),
+		Username: cfg.User,
+		Password: cfg.Passwd,
+	}
+	return &local, nil
+}
+
+func seed(root *sql.DB, config *thermostat.Config) error {
+	user := quoteString(config.Connection.Username)
+	statements := []string{
+		fmt.Sprintf("CREATE USER IF NOT EXISTS %s@'localhost' IDENTIFIED BY %s", user, quoteString(config.Connection.Password)),
+		fmt.Sprintf("GRANT ALL PRIVILEGES ON *.* TO %s@'localhost' WITH GRANT OPTION", user),
+	}
+
+	schema, err := config.Properties.FindString("default_schema")
+	if err == nil && schema != "" {
+		statements = append(statements, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", quoteIdentifier(schema)))
+	}
+
+	for _, statement := range statements {
+		if _, err := root.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func quoteIdentifier(s string) string {
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
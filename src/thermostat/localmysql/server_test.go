@@ -0,0 +1,60 @@
+package localmysql_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"thermostat"
+	. "thermostat/localmysql"
+)
+
+var _ = Describe("Server", func() {
+	var config *thermostat.Config
+
+	BeforeEach(func() {
+		config = &thermostat.Config{
+			Properties: thermostat.Properties{
+				"admin_password":                "secret",
+				"enable_lower_case_table_names": true,
+				"local_infile":                  false,
+			},
+		}
+	})
+
+	It("derives server options from the properties", func() {
+		options := ServerOptions(config, "/tmp/local")
+		Expect(options).To(HaveKeyWithValue("datadir", "/tmp/local/data"))
+		Expect(options).To(HaveKeyWithValue("socket", "/tmp/local/mysql.sock"))
+		Expect(options).To(HaveKeyWithValue("lower_case_table_names", "1"))
+		Expect(options).To(HaveKeyWithValue("local_infile", "OFF"))
+		Expect(options).ToNot(HaveKey("loose_userstat"))
+	})
+
+	It("renders includes before the generated options, which the extra options override", func() {
+		server := &Server{
+			Dir:      "/tmp/local",
+			Includes: []string{"/var/vcap/jobs/pxc-mysql/config/my.cnf"},
+			Options:  map[string]string{"local-infile": "ON", "max_connections": "750"},
+		}
+
+		Expect(string(server.DefaultsFile(config))).To(Equal(`!include /var/vcap/jobs/pxc-mysql/config/my.cnf
+[mysqld]
+datadir = /tmp/local/data
+local_infile = ON
+log_error = /tmp/local/mysql.err
+loose_mysqlx = OFF
+loose_wsrep_on = OFF
+lower_case_table_names = 1
+max_connections = 750
+pid_file = /tmp/local/mysql.pid
+skip_networking = ON
+socket = /tmp/local/mysql.sock
+`))
+	})
+
+	It("fails to start when the binary cannot be found", func() {
+		server := &Server{Binary: "/nonexistent/mysqld"}
+		_, err := server.Start(config)
+		Expect(err).To(HaveOccurred())
+	})
+})
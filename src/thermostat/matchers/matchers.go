@@ -0,0 +1,105 @@
+// Package matchers provides Gomega matchers for the thermostat database
+// helpers, for use as Expect(db).To(HaveVariable("event_scheduler", "ON")).
+package matchers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/onsi/gomega/types"
+
+	"thermostat"
+)
+
+// HaveVariable succeeds when the server variable has the expected value.
+func HaveVariable(name, expected string) types.GomegaMatcher {
+	return &haveVariableMatcher{name: name, expected: expected}
+}
+
+// HavePluginActive succeeds when the plugin is loaded and ACTIVE.
+func HavePluginActive(plugin string) types.GomegaMatcher {
+	return &havePluginActiveMatcher{plugin: plugin}
+}
+
+// HaveSchema succeeds when the schema exists.
+func HaveSchema(schema string) types.GomegaMatcher {
+	return &haveSchemaMatcher{schema: schema}
+}
+
+type haveVariableMatcher struct {
+	name     string
+	expected string
+	actual   string
+}
+
+func (m *haveVariableMatcher) Match(actual interface{}) (bool, error) {
+	db, err := toDB("HaveVariable", actual)
+	if err != nil {
+		return false, err
+	}
+
+	m.actual, err = thermostat.DbVariableValue(db, m.name)
+	if err != nil {
+		return false, err
+	}
+
+	return m.actual == m.expected, nil
+}
+
+func (m *haveVariableMatcher) FailureMessage(interface{}) string {
+	return fmt.Sprintf("Expected variable %s to be %q, but it was %q", m.name, m.expected, m.actual)
+}
+
+func (m *haveVariableMatcher) NegatedFailureMessage(interface{}) string {
+	return fmt.Sprintf("Expected variable %s not to be %q", m.name, m.expected)
+}
+
+type havePluginActiveMatcher struct {
+	plugin string
+}
+
+func (m *havePluginActiveMatcher) Match(actual interface{}) (bool, error) {
+	db, err := toDB("HavePluginActive", actual)
+	if err != nil {
+		return false, err
+	}
+
+	return thermostat.DbPluginActive(db, m.plugin)
+}
+
+func (m *havePluginActiveMatcher) FailureMessage(interface{}) string {
+	return fmt.Sprintf("Expected plugin %s to be active", m.plugin)
+}
+
+func (m *havePluginActiveMatcher) NegatedFailureMessage(interface{}) string {
+	return fmt.Sprintf("Expected plugin %s not to be active", m.plugin)
+}
+
+type haveSchemaMatcher struct {
+	schema string
+}
+
+func (m *haveSchemaMatcher) Match(actual interface{}) (bool, error) {
+	db, err := toDB("HaveSchema", actual)
+	if err != nil {
+		return false, err
+	}
+
+	return thermostat.DbSchemaExists(db, m.schema)
+}
+
+func (m *haveSchemaMatcher) FailureMessage(interface{}) string {
+	return fmt.Sprintf("Expected schema %s to exist", m.schema)
+}
+
+func (m *haveSchemaMatcher) NegatedFailureMessage(interface{}) string {
+	return fmt.Sprintf("Expected schema %s not to exist", m.schema)
+}
+
+func toDB(matcher string, actual interface{}) (*sql.DB, error) {
+	db, ok := actual.(*sql.DB)
+	if !ok || db == nil {
+		return nil, fmt.Errorf("%s matcher expects a *sql.DB, got %T", matcher, actual)
+	}
+	return db, nil
+}
@@ -0,0 +1,100 @@
+package matchers_test
+
+import (
+	"database/sql"
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	. "thermostat/matchers"
+)
+
+var _ = Describe("Matchers", func() {
+	DescribeTable("rejecting values that are not a *sql.DB",
+		func(matcher types.GomegaMatcher, name string) {
+			_, err := matcher.Match("not a db")
+			Expect(err).To(MatchError(name + " matcher expects a *sql.DB, got string"))
+		},
+		Entry("HaveVariable", HaveVariable("read_only", "ON"), "HaveVariable"),
+		Entry("HavePluginActive", HavePluginActive("audit_log"), "HavePluginActive"),
+		Entry("HaveSchema", HaveSchema("mysql"), "HaveSchema"),
+	)
+
+	It("describes the running value when a variable does not match", func() {
+		matcher := HaveVariable("read_only", "ON")
+		Expect(matcher.FailureMessage(nil)).To(Equal(`Expected variable read_only to be "ON", but it was ""`))
+		Expect(matcher.NegatedFailureMessage(nil)).To(Equal(`Expected variable read_only not to be "ON"`))
+	})
+
+	Context("against a database", func() {
+		var (
+			db   *sql.DB
+			mock sqlmock.Sqlmock
+		)
+
+		BeforeEach(func() {
+			var err error
+			db, mock, err = sqlmock.New()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		expectVariable := func(name, value string) {
+			mock.ExpectPrepare(regexp.QuoteMeta("SHOW VARIABLES WHERE Variable_name = ?")).
+				ExpectQuery().WithArgs(name).
+				WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow(name, value))
+		}
+
+		expectPluginStatus := func(plugin, status string) {
+			mock.ExpectPrepare(regexp.QuoteMeta("SELECT plugin_status FROM information_schema.plugins WHERE plugin_name = ?")).
+				ExpectQuery().WithArgs(plugin).
+				WillReturnRows(sqlmock.NewRows([]string{"plugin_status"}).AddRow(status))
+		}
+
+		expectSchema := func(schema string, exists bool) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = ?")).
+				WithArgs(schema).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+		}
+
+		It("matches a variable with the expected value", func() {
+			expectVariable("event_scheduler", "ON")
+			Expect(db).To(HaveVariable("event_scheduler", "ON"))
+		})
+
+		It("does not match a variable with another value and reports it", func() {
+			expectVariable("event_scheduler", "OFF")
+
+			matcher := HaveVariable("event_scheduler", "ON")
+			Expect(matcher.Match(db)).To(BeFalse())
+			Expect(matcher.FailureMessage(db)).To(Equal(`Expected variable event_scheduler to be "ON", but it was "OFF"`))
+		})
+
+		It("matches an active plugin", func() {
+			expectPluginStatus("audit_log", "ACTIVE")
+			Expect(db).To(HavePluginActive("audit_log"))
+		})
+
+		It("does not match a disabled plugin", func() {
+			expectPluginStatus("audit_log", "DISABLED")
+			Expect(db).NotTo(HavePluginActive("audit_log"))
+		})
+
+		It("matches an existing schema", func() {
+			expectSchema("cf_metadata", true)
+			Expect(db).To(HaveSchema("cf_metadata"))
+		})
+
+		It("does not match a missing schema", func() {
+			expectSchema("test", false)
+			Expect(db).NotTo(HaveSchema("test"))
+		})
+	})
+})
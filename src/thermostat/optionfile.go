@@ -0,0 +1,150 @@
+package thermostat
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OptionFile holds the options of a MySQL option file, keyed by section and
+// then by normalized option name. Later occurrences of an option override
+// earlier ones, as they do for mysqld.
+type OptionFile map[string]map[string]string
+
+// Section returns the options of a section, or an empty set when the section
+// is absent.
+func (o OptionFile) Section(name string) map[string]string {
+	if section, ok := o[name]; ok {
+		return section
+	}
+	return map[string]string{}
+}
+
+// ParseOptionFile reads a MySQL option file, following !include and
+// !includedir directives.
+func ParseOptionFile(path string) (OptionFile, error) {
+	options := OptionFile{}
+	err := options.parse(path, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// NormalizeOptionName converts an option name to its system variable form,
+// so that "pid-file" and "pid_file" are the same option.
+func NormalizeOptionName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.Replace(name, "-", "_", -1)
+	return strings.TrimPrefix(name, "loose_")
+}
+
+func (o OptionFile) parse(path string, visited map[string]bool) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if visited[absPath] {
+		return fmt.Errorf("%s includes itself", path)
+	}
+	visited[absPath] = true
+	defer delete(visited, absPath)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "!includedir"):
+			dir := includePath(path, strings.TrimSpace(strings.TrimPrefix(line, "!includedir")))
+			if err := o.parseDir(dir, visited); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "!include"):
+			included := includePath(path, strings.TrimSpace(strings.TrimPrefix(line, "!include")))
+			if err := o.parse(included, visited); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("%s:%d: malformed section header %q", path, lineNumber, line)
+			}
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+		default:
+			if section == "" {
+				return fmt.Errorf("%s:%d: option %q appears before any section", path, lineNumber, line)
+			}
+			name, value := splitOption(line)
+			if o[section] == nil {
+				o[section] = map[string]string{}
+			}
+			o[section][NormalizeOptionName(name)] = value
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (o OptionFile) parseDir(dir string, visited map[string]bool) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".cnf") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := o.parse(filepath.Join(dir, name), visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func includePath(includingFile, included string) string {
+	if filepath.IsAbs(included) {
+		return included
+	}
+	return filepath.Join(filepath.Dir(includingFile), included)
+}
+
+// splitOption splits "name = value" into its parts. Options without a value,
+// such as "quick", are boolean options and get the value "ON".
+func splitOption(line string) (name, value string) {
+	parts := strings.SplitN(line, "=", 2)
+	name = strings.TrimSpace(parts[0])
+	if len(parts) == 1 {
+		return name, "ON"
+	}
+
+	value = strings.TrimSpace(parts[1])
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return name, value[1 : end+1]
+		}
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return name, value
+}
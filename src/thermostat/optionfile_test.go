@@ -0,0 +1,97 @@
+package thermostat_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "thermostat"
+)
+
+var _ = Describe("ParseOptionFile", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "optionfile")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeFile := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	It("parses sections, normalizes names and follows includes", func() {
+		writeFile("auto-tune.cnf", `
+[mysqld]
+innodb_buffer_pool_size = 4294967296
+`)
+		myCnf := writeFile("my.cnf", `
+[client]
+port                            = 3306
+
+!include                        `+filepath.Join(dir, "auto-tune.cnf")+`
+
+[mysqld]
+# GALERA options:
+wsrep_provider_options          = "gcache.size=512M;pc.recovery=FALSE"
+pid-file                        = /var/vcap/store/pxc-mysql/mysql.pid
+sql-mode                        = NO_AUTO_CREATE_USER,STRICT_ALL_TABLES
+max_connections                 = 1500 # inline comment
+innodb_buffer_pool_size         = 1G
+
+[mysqldump]
+quick
+`)
+
+		options, err := ParseOptionFile(myCnf)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(options.Section("client")).To(Equal(map[string]string{"port": "3306"}))
+		Expect(options.Section("mysqld")).To(Equal(map[string]string{
+			"wsrep_provider_options":  "gcache.size=512M;pc.recovery=FALSE",
+			"pid_file":                "/var/vcap/store/pxc-mysql/mysql.pid",
+			"sql_mode":                "NO_AUTO_CREATE_USER,STRICT_ALL_TABLES",
+			"max_connections":         "1500",
+			"innodb_buffer_pool_size": "1G",
+		}))
+		Expect(options.Section("mysqldump")).To(Equal(map[string]string{"quick": "ON"}))
+		Expect(options.Section("missing")).To(BeEmpty())
+	})
+
+	It("follows relative includes and includedir", func() {
+		Expect(os.Mkdir(filepath.Join(dir, "conf.d"), 0755)).To(Succeed())
+		writeFile("conf.d/b.cnf", "[mysqld]\nmax_connections = 20\n")
+		writeFile("conf.d/a.cnf", "[mysqld]\nmax_connections = 10\nevent_scheduler = ON\n")
+		writeFile("conf.d/ignored.txt", "[mysqld]\nevent_scheduler = OFF\n")
+		myCnf := writeFile("my.cnf", "!includedir conf.d\n")
+
+		options, err := ParseOptionFile(myCnf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.Section("mysqld")).To(Equal(map[string]string{
+			"max_connections": "20",
+			"event_scheduler": "ON",
+		}))
+	})
+
+	It("returns an error for include cycles", func() {
+		myCnf := writeFile("my.cnf", "!include my.cnf\n")
+		_, err := ParseOptionFile(myCnf)
+		Expect(err).To(MatchError(ContainSubstring("includes itself")))
+	})
+
+	It("returns an error naming the line for options outside a section", func() {
+		myCnf := writeFile("my.cnf", "\nmax_connections = 10\n")
+		_, err := ParseOptionFile(myCnf)
+		Expect(err).To(MatchError(myCnf + `:2: option "max_connections = 10" appears before any section`))
+	})
+})
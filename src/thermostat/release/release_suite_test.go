@@ -1,4 +1,4 @@
-package thermostat_test
+package release_test
 
 import (
 	. "github.com/onsi/ginkgo"
@@ -9,5 +9,5 @@ import (
 
 func TestHotsqlRelease(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "Thermostat")
+	RunSpecs(t, "Thermostat Release")
 }
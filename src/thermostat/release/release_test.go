@@ -1,4 +1,4 @@
-package thermostat_test
+package release_test
 
 import (
 	"database/sql"
@@ -7,12 +7,12 @@ import (
 	"github.com/cloudfoundry/gosigar"
 	_ "github.com/go-sql-driver/mysql"
 	. "github.com/onsi/ginkgo"
-	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	"fmt"
 	"os"
 	. "thermostat"
+	. "thermostat/matchers"
 )
 
 var _ = Describe("Dedicated MySQL", func() {
@@ -56,18 +56,27 @@ var _ = Describe("Dedicated MySQL", func() {
 		})
 	})
 
-	DescribeTable("general options",
-		func(variable, expected string) {
-			value, err := DbVariableValue(db, variable)
-			Expect(err).ToNot(HaveOccurred())
-			Expect(value).To(Equal(expected))
+	Describe("expected configuration", func() {
+		expectations, err := LoadExpectations(ExpectationsPath("../expectations.yml"))
+		if err != nil {
+			It("loads the expectations manifest", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+			return
+		}
 
-		},
-		Entry("turns on the event scheduler", "event_scheduler", "ON"),
-		Entry("table definition cache is set to 8192", "table_definition_cache", "8192"),
-		Entry("max connections is set to 750", "max_connections", "750"),
-		Entry("sets myisam recovery option to force and backup", "myisam_recover_options", "BACKUP,FORCE"),
-	)
+		for _, expectation := range expectations.All() {
+			expectation := expectation
+			It(expectation.String(), func() {
+				result := expectation.Check(db, config.Properties)
+				if result.Skipped {
+					Skip("condition does not hold")
+				}
+				Expect(result.Err).ToNot(HaveOccurred())
+				Expect(result.Actual).To(Equal(result.Expected), result.String())
+			})
+		}
+	})
 
 	Describe("lower_case_table_names", func() {
 		var lowerCaseTableNames bool
@@ -106,14 +115,10 @@ var _ = Describe("Dedicated MySQL", func() {
 					}
 				})
 				It("loads the audit logging plugin", func() {
-					auditPluginActive, err := DbPluginActive(db, "audit_log")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(auditPluginActive).To(Equal(auditLoggingRequested))
+					Expect(db).To(HavePluginActive("audit_log"))
 				})
 				It("does not log to syslog", func() {
-					handler, err := DbVariableValue(db, "audit_log_handler")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(handler).ToNot(Equal("SYSLOG"))
+					Expect(db).ToNot(HaveVariable("audit_log_handler", "SYSLOG"))
 				})
 				It("logs to the expected path", func() {
 					path, err := DbVariableValue(db, "audit_log_file")
@@ -205,99 +210,6 @@ var _ = Describe("Dedicated MySQL", func() {
 			Expect(DbVariableValue(db, "read_only")).To(Equal(expectedReadOnlyState))
 			Expect(DbVariableValue(db, "super_read_only")).To(Equal(expectedReadOnlyState))
 		})
-
-		Context("When semi-synchronous replication is enabled", func() {
-			BeforeEach(func() {
-				mode, err := config.Properties.FindString("leader_follower.replication_mode")
-				Expect(err).NotTo(HaveOccurred())
-
-				if mode != "semi-sync" {
-					Skip("Replication mode is not semi-sync")
-				}
-			})
-
-			It("Loads the semi-synchronous replication plugins", func() {
-				semisyncMasterEnabled, err := DbPluginActive(db, "rpl_semi_sync_master")
-				Expect(err).ToNot(HaveOccurred())
-				Expect(semisyncMasterEnabled).To(BeTrue())
-
-				semisyncSlaveEnabled, err := DbPluginActive(db, "rpl_semi_sync_slave")
-				Expect(err).ToNot(HaveOccurred())
-				Expect(semisyncSlaveEnabled).To(BeTrue())
-			})
-
-			It("Has rpl_semi_sync_slave_enabled set to ON", func() {
-				value, err := DbVariableValue(db, "rpl_semi_sync_slave_enabled")
-				Expect(err).ToNot(HaveOccurred())
-				Expect(value).To(Equal("ON"))
-			})
-
-			It("sets the semi sync timeout to the specified value", func() {
-				dbVal, err := DbVariableValue(db, "rpl_semi_sync_master_timeout")
-				Expect(err).ToNot(HaveOccurred())
-
-				actualTimeout, err := strconv.ParseInt(dbVal, 10, 64)
-				Expect(err).ToNot(HaveOccurred())
-
-				configuredTimeout, err := config.Properties.Find("leader_follower.semi_sync_ack_timeout_in_ms")
-				Expect(err).ToNot(HaveOccurred())
-
-				Expect(actualTimeout).To(BeEquivalentTo(configuredTimeout))
-			})
-		})
-
-		Context("When semi-synchronous replication is disabled", func() {
-			BeforeEach(func() {
-				mode, err := config.Properties.FindString("leader_follower.replication_mode")
-				Expect(err).NotTo(HaveOccurred())
-
-				if mode == "semi-sync" {
-					Skip("Replication mode is semi-sync")
-				}
-			})
-
-			It("Does not load the semi-synchronous replication plugins", func() {
-				semisyncMasterEnabled, err := DbPluginActive(db, "rpl_semi_sync_master")
-				Expect(err).ToNot(HaveOccurred())
-				Expect(semisyncMasterEnabled).To(BeFalse())
-
-				semisyncSlaveEnabled, err := DbPluginActive(db, "rpl_semi_sync_slave")
-				Expect(err).ToNot(HaveOccurred())
-				Expect(semisyncSlaveEnabled).To(BeFalse())
-			})
-		})
-
-		DescribeTable("replication options",
-			func(variable, expected string) {
-				value, err := DbVariableValue(db, variable)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(value).To(Equal(expected))
-			},
-
-			Entry("abort server when mysql cannot write to binlog", "binlog_error_action", "ABORT_SERVER"),
-			Entry("bin log format set to row", "binlog_format", "ROW"),
-			Entry("bin log has no delay in syncing group commits", "binlog_group_commit_sync_delay", "0"),
-			Entry("binlog-row-image is set to minimal", "binlog_row_image", "MINIMAL"),
-			Entry("binlog-rows-query-log-events is enabled", "binlog_rows_query_log_events", "ON"),
-			Entry("binlog-stmt-cache-size is set to 4M", "binlog_stmt_cache_size", "4194304"),
-			Entry("enforce-gtid-consistency is enabled", "enforce_gtid_consistency", "ON"),
-			Entry("expire_logs_days is set to 3 days", "expire_logs_days", "3"),
-			Entry("gtid-mode is enabled", "gtid_mode", "ON"),
-			Entry("binary logs are enabled", "log_bin", "ON"),
-			Entry("binary log basename is reasonable", "log_bin_basename", "/var/vcap/store/pxc-mysql/mysql-bin"),
-			Entry("log-slave-updates is enabled", "log_slave_updates", "ON"),
-			Entry("trust users to create stored functions in a non strict mode", "log_bin_trust_function_creators", "ON"),
-			Entry("master-info-repository is set to TABLE", "master_info_repository", "TABLE"),
-			Entry("master-verify-checksum is enabled", "master_verify_checksum", "ON"),
-			Entry("bin log max cache size is set to 2G", "max_binlog_cache_size", "2147483648"),
-			Entry("bin log max size set to 512M", "max_binlog_size", "536870912"),
-			Entry("bin log statement max cache size is set to 2G", "max_binlog_stmt_cache_size", "2147483648"),
-			Entry("relay-log is set with a reasonable basename", "relay_log", "mysql-relay"),
-			Entry("relay-log-info-repository is set to TABLE", "relay_log_info_repository", "TABLE"),
-			Entry("slave-sql-verify-checksum is enabled", "slave_sql_verify_checksum", "ON"),
-			Entry("bin log sync transactions before being committed", "sync_binlog", "1"),
-			Entry("Recover relay logs when follower dies", "relay_log_recovery", "ON"),
-		)
 	})
 
 	Describe("innodb", func() {
@@ -319,10 +231,8 @@ var _ = Describe("Dedicated MySQL", func() {
 			var workload string
 			BeforeEach(func() {
 				var err error
-				workload, err = config.Properties.FindString("workload")
-				if err != nil {
-					workload = "mixed"
-				}
+				workload, err = config.Properties.FindStringOrDefault("workload", "mixed")
+				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("innodb_log_buffer_size greater than the default, 32M", func() {
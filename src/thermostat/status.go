@@ -0,0 +1,213 @@
+package thermostat
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GaleraCluster describes the cluster as seen by the node it was read from.
+type GaleraCluster struct {
+	ClusterSize       int
+	ClusterStatus     string
+	LocalStateComment string
+	Ready             bool
+	Connected         bool
+	Members           []GaleraMember
+}
+
+// GaleraMember is a row of performance_schema.pxc_cluster_view.
+type GaleraMember struct {
+	HostName   string
+	UUID       string
+	Status     string
+	LocalIndex int
+	Segment    int
+}
+
+// ReplicaState is the subset of SHOW SLAVE STATUS that suites assert on.
+// Configured is false when the node is not a replica.
+type ReplicaState struct {
+	Configured          bool
+	MasterHost          string
+	MasterPort          int
+	IORunning           bool
+	SQLRunning          bool
+	SecondsBehindMaster *int
+	LastIOError         string
+	LastSQLError        string
+	RetrievedGtidSet    string
+	ExecutedGtidSet     string
+}
+
+// SemiSyncState combines the semi-synchronous replication variables and
+// status counters. Everything is false or zero when the plugins are not
+// loaded.
+type SemiSyncState struct {
+	MasterEnabled   bool
+	MasterActive    bool
+	MasterClients   int
+	MasterTimeoutMs int
+	SlaveEnabled    bool
+	SlaveActive     bool
+}
+
+func DbStatusValue(db *sql.DB, name string) (string, error) {
+	var key, value string
+
+	stmtOut, err := db.Prepare("SHOW GLOBAL STATUS WHERE Variable_name = ?")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = stmtOut.Close() }()
+
+	err = stmtOut.QueryRow(name).Scan(&key, &value)
+
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func DbGlobalStatus(db *sql.DB) (map[string]string, error) {
+	return dbNameValues(db, "SHOW GLOBAL STATUS")
+}
+
+func GaleraClusterView(db *sql.DB) (GaleraCluster, error) {
+	status, err := DbGlobalStatus(db)
+	if err != nil {
+		return GaleraCluster{}, err
+	}
+
+	clusterSize, err := parseInt("wsrep_cluster_size", status["wsrep_cluster_size"])
+	if err != nil {
+		return GaleraCluster{}, err
+	}
+
+	cluster := GaleraCluster{
+		ClusterSize:       clusterSize,
+		ClusterStatus:     status["wsrep_cluster_status"],
+		LocalStateComment: status["wsrep_local_state_comment"],
+		Ready:             status["wsrep_ready"] == "ON",
+		Connected:         status["wsrep_connected"] == "ON",
+	}
+
+	rows, err := db.Query("SELECT host_name, uuid, status, local_index, segment FROM performance_schema.pxc_cluster_view ORDER BY local_index")
+	if err != nil {
+		return GaleraCluster{}, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var member GaleraMember
+		err := rows.Scan(&member.HostName, &member.UUID, &member.Status, &member.LocalIndex, &member.Segment)
+		if err != nil {
+			return GaleraCluster{}, err
+		}
+		cluster.Members = append(cluster.Members, member)
+	}
+
+	return cluster, rows.Err()
+}
+
+func ReplicaStatus(db *sql.DB) (ReplicaState, error) {
+	rows, err := db.Query("SHOW SLAVE STATUS")
+	if err != nil {
+		return ReplicaState{}, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return ReplicaState{}, err
+	}
+
+	if !rows.Next() {
+		return ReplicaState{}, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return ReplicaState{}, err
+	}
+
+	status := map[string]sql.NullString{}
+	for i, column := range columns {
+		status[strings.ToLower(column)] = values[i]
+	}
+
+	masterPort, err := parseInt("Master_Port", status["master_port"].String)
+	if err != nil {
+		return ReplicaState{}, err
+	}
+
+	state := ReplicaState{
+		Configured:       true,
+		MasterHost:       status["master_host"].String,
+		MasterPort:       masterPort,
+		IORunning:        status["slave_io_running"].String == "Yes",
+		SQLRunning:       status["slave_sql_running"].String == "Yes",
+		LastIOError:      status["last_io_error"].String,
+		LastSQLError:     status["last_sql_error"].String,
+		RetrievedGtidSet: status["retrieved_gtid_set"].String,
+		ExecutedGtidSet:  status["executed_gtid_set"].String,
+	}
+
+	if lag := status["seconds_behind_master"]; lag.Valid {
+		seconds, err := parseInt("Seconds_Behind_Master", lag.String)
+		if err != nil {
+			return ReplicaState{}, err
+		}
+		state.SecondsBehindMaster = &seconds
+	}
+
+	return state, nil
+}
+
+func SemiSyncStatus(db *sql.DB) (SemiSyncState, error) {
+	variables, err := DbGlobalVariables(db)
+	if err != nil {
+		return SemiSyncState{}, err
+	}
+
+	status, err := DbGlobalStatus(db)
+	if err != nil {
+		return SemiSyncState{}, err
+	}
+
+	clients, err := parseInt("rpl_semi_sync_master_clients", status["rpl_semi_sync_master_clients"])
+	if err != nil {
+		return SemiSyncState{}, err
+	}
+	timeout, err := parseInt("rpl_semi_sync_master_timeout", variables["rpl_semi_sync_master_timeout"])
+	if err != nil {
+		return SemiSyncState{}, err
+	}
+
+	return SemiSyncState{
+		MasterEnabled:   variables["rpl_semi_sync_master_enabled"] == "ON",
+		MasterActive:    status["rpl_semi_sync_master_status"] == "ON",
+		MasterClients:   clients,
+		MasterTimeoutMs: timeout,
+		SlaveEnabled:    variables["rpl_semi_sync_slave_enabled"] == "ON",
+		SlaveActive:     status["rpl_semi_sync_slave_status"] == "ON",
+	}, nil
+}
+
+// parseInt parses the value of a status column or variable. An empty value,
+// as when the variable does not exist, is zero.
+func parseInt(name, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not an integer: %q", name, value)
+	}
+	return i, nil
+}
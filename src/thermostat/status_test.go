@@ -0,0 +1,190 @@
+package thermostat_test
+
+import (
+	"database/sql"
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	. "thermostat"
+)
+
+var _ = Describe("Status", func() {
+	var (
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+	)
+
+	BeforeEach(func() {
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+
+	expectNameValues := func(query string, values map[string]string) {
+		rows := sqlmock.NewRows([]string{"Variable_name", "Value"})
+		for name, value := range values {
+			rows.AddRow(name, value)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(rows)
+	}
+
+	Describe("DbStatusValue", func() {
+		query := regexp.QuoteMeta("SHOW GLOBAL STATUS WHERE Variable_name = ?")
+
+		It("returns the value of the status variable", func() {
+			mock.ExpectPrepare(query).ExpectQuery().WithArgs("wsrep_ready").
+				WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("wsrep_ready", "ON"))
+
+			Expect(DbStatusValue(db, "wsrep_ready")).To(Equal("ON"))
+		})
+
+		It("returns ErrNoRows for unknown variables", func() {
+			mock.ExpectPrepare(query).ExpectQuery().WithArgs("wsrep_missing").
+				WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}))
+
+			_, err := DbStatusValue(db, "wsrep_missing")
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+	})
+
+	Describe("GaleraClusterView", func() {
+		members := regexp.QuoteMeta("SELECT host_name, uuid, status, local_index, segment FROM performance_schema.pxc_cluster_view ORDER BY local_index")
+		memberColumns := []string{"HOST_NAME", "UUID", "STATUS", "LOCAL_INDEX", "SEGMENT"}
+
+		It("combines the wsrep status with the members of pxc_cluster_view", func() {
+			expectNameValues("SHOW GLOBAL STATUS", map[string]string{
+				"WSREP_CLUSTER_SIZE":        "2",
+				"wsrep_cluster_status":      "Primary",
+				"wsrep_local_state_comment": "Synced",
+				"wsrep_ready":               "ON",
+				"wsrep_connected":           "OFF",
+			})
+			mock.ExpectQuery(members).WillReturnRows(sqlmock.NewRows(memberColumns).
+				AddRow("mysql-0", "1d2c", "SYNCED", "0", "0").
+				AddRow("mysql-1", "5e6f", "DONOR", "1", "1"))
+
+			Expect(GaleraClusterView(db)).To(Equal(GaleraCluster{
+				ClusterSize:       2,
+				ClusterStatus:     "Primary",
+				LocalStateComment: "Synced",
+				Ready:             true,
+				Connected:         false,
+				Members: []GaleraMember{
+					{HostName: "mysql-0", UUID: "1d2c", Status: "SYNCED", LocalIndex: 0, Segment: 0},
+					{HostName: "mysql-1", UUID: "5e6f", Status: "DONOR", LocalIndex: 1, Segment: 1},
+				},
+			}))
+		})
+
+		It("returns an error for a cluster size that is not a number", func() {
+			expectNameValues("SHOW GLOBAL STATUS", map[string]string{"wsrep_cluster_size": "two"})
+
+			_, err := GaleraClusterView(db)
+			Expect(err).To(MatchError(`wsrep_cluster_size is not an integer: "two"`))
+		})
+
+		It("returns an error for members that cannot be read", func() {
+			expectNameValues("SHOW GLOBAL STATUS", map[string]string{"wsrep_cluster_size": "1"})
+			mock.ExpectQuery(members).WillReturnRows(sqlmock.NewRows(memberColumns).
+				AddRow("mysql-0", "1d2c", "SYNCED", "first", "0"))
+
+			_, err := GaleraClusterView(db)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ReplicaStatus", func() {
+		columns := []string{"Slave_IO_State", "Master_Host", "Master_Port", "Slave_IO_Running", "Slave_SQL_Running",
+			"Seconds_Behind_Master", "Last_IO_Error", "Last_SQL_Error", "Retrieved_Gtid_Set", "Executed_Gtid_Set"}
+
+		It("is not configured when the node is not a replica", func() {
+			mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(columns))
+
+			Expect(ReplicaStatus(db)).To(Equal(ReplicaState{}))
+		})
+
+		It("maps the columns of SHOW SLAVE STATUS", func() {
+			mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(columns).AddRow(
+				"Waiting for master to send event", "10.0.0.1", "3306", "Yes", "No",
+				"12", "", "Error 'Duplicate entry'", "uuid:1-10", "uuid:1-9",
+			))
+
+			lag := 12
+			Expect(ReplicaStatus(db)).To(Equal(ReplicaState{
+				Configured:          true,
+				MasterHost:          "10.0.0.1",
+				MasterPort:          3306,
+				IORunning:           true,
+				SQLRunning:          false,
+				SecondsBehindMaster: &lag,
+				LastSQLError:        "Error 'Duplicate entry'",
+				RetrievedGtidSet:    "uuid:1-10",
+				ExecutedGtidSet:     "uuid:1-9",
+			}))
+		})
+
+		It("leaves the lag unknown when Seconds_Behind_Master is NULL", func() {
+			mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(columns).AddRow(
+				"", "10.0.0.1", "3306", "No", "No", nil, "error connecting to master", "", "", "",
+			))
+
+			state, err := ReplicaStatus(db)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.SecondsBehindMaster).To(BeNil())
+			Expect(state.LastIOError).To(Equal("error connecting to master"))
+		})
+
+		It("returns an error for a port that is not a number", func() {
+			mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(columns).AddRow(
+				"", "10.0.0.1", "mysql", "No", "No", nil, "", "", "", "",
+			))
+
+			_, err := ReplicaStatus(db)
+			Expect(err).To(MatchError(`Master_Port is not an integer: "mysql"`))
+		})
+	})
+
+	Describe("SemiSyncStatus", func() {
+		It("is zero when the semi-sync plugins are not loaded", func() {
+			expectNameValues("SHOW GLOBAL VARIABLES", map[string]string{"max_connections": "1500"})
+			expectNameValues("SHOW GLOBAL STATUS", map[string]string{"uptime": "10"})
+
+			Expect(SemiSyncStatus(db)).To(Equal(SemiSyncState{}))
+		})
+
+		It("combines the semi-sync variables and status counters", func() {
+			expectNameValues("SHOW GLOBAL VARIABLES", map[string]string{
+				"rpl_semi_sync_master_enabled": "ON",
+				"rpl_semi_sync_master_timeout": "10000",
+				"rpl_semi_sync_slave_enabled":  "OFF",
+			})
+			expectNameValues("SHOW GLOBAL STATUS", map[string]string{
+				"Rpl_semi_sync_master_status":  "ON",
+				"Rpl_semi_sync_master_clients": "1",
+				"Rpl_semi_sync_slave_status":   "OFF",
+			})
+
+			Expect(SemiSyncStatus(db)).To(Equal(SemiSyncState{
+				MasterEnabled:   true,
+				MasterActive:    true,
+				MasterClients:   1,
+				MasterTimeoutMs: 10000,
+			}))
+		})
+
+		It("returns an error for a timeout that is not a number", func() {
+			expectNameValues("SHOW GLOBAL VARIABLES", map[string]string{"rpl_semi_sync_master_timeout": "10s"})
+			expectNameValues("SHOW GLOBAL STATUS", map[string]string{})
+
+			_, err := SemiSyncStatus(db)
+			Expect(err).To(MatchError(`rpl_semi_sync_master_timeout is not an integer: "10s"`))
+		})
+	})
+})
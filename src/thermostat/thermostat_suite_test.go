@@ -0,0 +1,15 @@
+package thermostat_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+// The specs here need no database; the specs that check a running server
+// are in the release package.
+func TestThermostat(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Thermostat")
+}